@@ -16,7 +16,11 @@ var ExecCmd = &cobra.Command{
 
 func init() {
 	ExecCmd.PersistentFlags().StringVarP(&urlICQ, UrlFlagName, "u", "http://localhost:9999", "server url")
+	resubmitFailedTx.Flags().String(ConnectionIDFlagName, "", "connection of the query (the primary connection if empty)")
 	ExecCmd.AddCommand(resubmitFailedTx)
+	ExecCmd.AddCommand(releaseQuery)
+	backfillQuery.Flags().String(ConnectionIDFlagName, "", "connection of the query (the primary connection if empty)")
+	ExecCmd.AddCommand(backfillQuery)
 	rootCmd.AddCommand(ExecCmd)
 }
 
@@ -37,14 +41,20 @@ var resubmitFailedTx = &cobra.Command{
 		}
 		hash := args[1]
 
+		connectionID, err := cmd.Flags().GetString(ConnectionIDFlagName)
+		if err != nil {
+			return err
+		}
+
 		queryID, err := strconv.Atoi(args[0])
 		if err != nil {
 			return fmt.Errorf("failed to parse queryID: %w", err)
 		}
 
 		req := icqhttp.ResubmitRequest{Txs: []icqhttp.ResubmitTx{{
-			QueryID: uint64(queryID),
-			Hash:    hash,
+			ConnectionID: connectionID,
+			QueryID:      uint64(queryID),
+			Hash:         hash,
 		}}}
 
 		err = client.ResubmitTxs(req)
@@ -56,3 +66,83 @@ var resubmitFailedTx = &cobra.Command{
 		return nil
 	},
 }
+
+// releaseQuery represents the release-query command
+var releaseQuery = &cobra.Command{
+	Use:   "release-query <queryID>",
+	Args:  cobra.ExactArgs(1),
+	Short: "Release a quarantined or backed off query so it is processed again and clear its critical tx error",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		queryID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse queryID: %w", err)
+		}
+
+		err = client.ReleaseQueries(icqhttp.ReleaseQueriesRequest{QueryIDs: []uint64{queryID}})
+		if err != nil {
+			return fmt.Errorf("failed to release query: %w", err)
+		}
+
+		fmt.Printf("Query queryID=%d released successfully", queryID)
+		return nil
+	},
+}
+
+// backfillQuery represents the backfill command
+var backfillQuery = &cobra.Command{
+	Use:   "backfill <queryID> <fromHeight> <toHeight>",
+	Args:  cobra.ExactArgs(3),
+	Short: "Relay transactions of a TX query over an explicit height range",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		connectionID, err := cmd.Flags().GetString(ConnectionIDFlagName)
+		if err != nil {
+			return err
+		}
+
+		queryID, err := strconv.ParseUint(args[0], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse queryID: %w", err)
+		}
+		fromHeight, err := strconv.ParseUint(args[1], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse fromHeight: %w", err)
+		}
+		toHeight, err := strconv.ParseUint(args[2], 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse toHeight: %w", err)
+		}
+
+		req := icqhttp.BackfillRequest{ConnectionID: connectionID}
+		req.QueryID = queryID
+		req.FromHeight = fromHeight
+		req.ToHeight = toHeight
+
+		info, err := client.Backfill(req)
+		if err != nil {
+			return fmt.Errorf("failed to start backfill: %w", err)
+		}
+
+		fmt.Printf("Backfill of queryID=%d over heights [%d, %d] is %s", info.QueryID, info.FromHeight, info.ToHeight, info.Status)
+		return nil
+	},
+}
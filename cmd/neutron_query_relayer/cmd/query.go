@@ -13,7 +13,8 @@ import (
 var urlICQ string
 
 const (
-	UrlFlagName = "url"
+	UrlFlagName          = "url"
+	ConnectionIDFlagName = "connection-id"
 )
 
 // QueryCmd represents the query command
@@ -24,6 +25,12 @@ var QueryCmd = &cobra.Command{
 func init() {
 	QueryCmd.PersistentFlags().StringVarP(&urlICQ, UrlFlagName, "u", "http://localhost:9999", "server url")
 	QueryCmd.AddCommand(UnsuccessfulTxs)
+	QueryCmd.AddCommand(TasksQueue)
+	QueryCmd.AddCommand(QuarantinedQueries)
+	QueryCmd.AddCommand(CriticalTxErrors)
+	QueryCmd.AddCommand(DryRunResults)
+	QueryCmd.AddCommand(Backfills)
+	QueryCmd.AddCommand(ClientHealth)
 	rootCmd.AddCommand(QueryCmd)
 }
 
@@ -60,3 +67,207 @@ var UnsuccessfulTxs = &cobra.Command{
 		return nil
 	},
 }
+
+// TasksQueue represents the tasks-queue command
+var TasksQueue = &cobra.Command{
+	Use:   "tasks-queue",
+	Short: "Query tasks waiting in the relayer queue in the order they are going to be processed",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		tasks, err := client.GetTasksQueue()
+		if err != nil {
+			return fmt.Errorf("failed to get tasks queue: %w", err)
+		}
+
+		var response bytes.Buffer
+		encoder := json.NewEncoder(&response)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(tasks)
+		if err != nil {
+			return fmt.Errorf("failed to encode tasks queue: %w", err)
+		}
+
+		fmt.Printf("Tasks queue:\n%s\n", response.String())
+
+		return nil
+	},
+}
+
+// QuarantinedQueries represents the quarantined-queries command
+var QuarantinedQueries = &cobra.Command{
+	Use:   "quarantined-queries",
+	Short: "Query queries which are not processed anymore because of too many consecutive failures",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		queries, err := client.GetQuarantinedQueries()
+		if err != nil {
+			return fmt.Errorf("failed to get quarantined queries: %w", err)
+		}
+
+		var response bytes.Buffer
+		encoder := json.NewEncoder(&response)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(queries)
+		if err != nil {
+			return fmt.Errorf("failed to encode quarantined queries: %w", err)
+		}
+
+		fmt.Printf("Quarantined queries:\n%s\n", response.String())
+
+		return nil
+	},
+}
+
+// CriticalTxErrors represents the critical-tx-errors command
+var CriticalTxErrors = &cobra.Command{
+	Use:   "critical-tx-errors",
+	Short: "Query the latest tx submission errors not matching the ignored errors regex",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		errs, err := client.GetCriticalTxErrors()
+		if err != nil {
+			return fmt.Errorf("failed to get critical tx errors: %w", err)
+		}
+
+		var response bytes.Buffer
+		encoder := json.NewEncoder(&response)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(errs)
+		if err != nil {
+			return fmt.Errorf("failed to encode critical tx errors: %w", err)
+		}
+
+		fmt.Printf("Critical tx errors:\n%s\n", response.String())
+
+		return nil
+	},
+}
+
+// DryRunResults represents the dry-run-results command
+var DryRunResults = &cobra.Command{
+	Use:   "dry-run-results",
+	Short: "Query the latest transactions built but not broadcast in the dry run mode",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		results, err := client.GetDryRunResults()
+		if err != nil {
+			return fmt.Errorf("failed to get dry run results: %w", err)
+		}
+
+		var response bytes.Buffer
+		encoder := json.NewEncoder(&response)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(results)
+		if err != nil {
+			return fmt.Errorf("failed to encode dry run results: %w", err)
+		}
+
+		fmt.Printf("Dry run results:\n%s\n", response.String())
+
+		return nil
+	},
+}
+
+// Backfills represents the backfills command
+var Backfills = &cobra.Command{
+	Use:   "backfills",
+	Short: "Query the states of the recent backfill jobs",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		jobs, err := client.GetBackfills()
+		if err != nil {
+			return fmt.Errorf("failed to get backfill jobs: %w", err)
+		}
+
+		var response bytes.Buffer
+		encoder := json.NewEncoder(&response)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(jobs)
+		if err != nil {
+			return fmt.Errorf("failed to encode backfill jobs: %w", err)
+		}
+
+		fmt.Printf("Backfill jobs:\n%s\n", response.String())
+
+		return nil
+	},
+}
+
+// ClientHealth represents the client-health command
+var ClientHealth = &cobra.Command{
+	Use:   "client-health",
+	Short: "Query the health of Neutron's light clients of the target chains",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		url, err := cmd.Flags().GetString(UrlFlagName)
+		if err != nil {
+			return err
+		}
+
+		client, err := icqhttp.NewICQClient(url)
+		if err != nil {
+			return fmt.Errorf("failed to get new icq client: %w", err)
+		}
+
+		clients, err := client.GetClientHealth()
+		if err != nil {
+			return fmt.Errorf("failed to get client health: %w", err)
+		}
+
+		var response bytes.Buffer
+		encoder := json.NewEncoder(&response)
+		encoder.SetIndent("", "  ")
+		err = encoder.Encode(clients)
+		if err != nil {
+			return fmt.Errorf("failed to encode client health: %w", err)
+		}
+
+		fmt.Printf("Client health:\n%s\n", response.String())
+
+		return nil
+	},
+}
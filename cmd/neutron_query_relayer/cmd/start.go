@@ -2,12 +2,14 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	relaysubscriber "github.com/neutron-org/neutron-query-relayer/internal/subscriber"
 	"log"
 	"os"
 	"os/signal"
 	"sync"
 	"syscall"
+	"time"
 
 	neutronapp "github.com/neutron-org/neutron/app"
 
@@ -20,8 +22,14 @@ import (
 
 	nlogger "github.com/neutron-org/neutron-logger"
 	"github.com/neutron-org/neutron-query-relayer/internal/app"
+	"github.com/neutron-org/neutron-query-relayer/internal/backfill"
+	"github.com/neutron-org/neutron-query-relayer/internal/clienthealth"
 	"github.com/neutron-org/neutron-query-relayer/internal/config"
-	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+	"github.com/neutron-org/neutron-query-relayer/internal/connection"
+	"github.com/neutron-org/neutron-query-relayer/internal/dryrun"
+	"github.com/neutron-org/neutron-query-relayer/internal/revision"
+	"github.com/neutron-org/neutron-query-relayer/internal/scheduler"
+	"github.com/neutron-org/neutron-query-relayer/internal/submit"
 )
 
 const (
@@ -61,6 +69,12 @@ func startRelayer() {
 		app.TxSubmitCheckerContext,
 		app.TrustedHeadersFetcherContext,
 		app.KVProcessorContext,
+		app.SubmitterContext,
+		app.BackfillerContext,
+		app.ClientHealthContext,
+		app.RevisionTrackerContext,
+		app.ConnectionWatcherContext,
+		app.TxQuerierContext,
 		icqhttp.MonitoringLoggerContext,
 	)
 	if err != nil {
@@ -74,8 +88,16 @@ func startRelayer() {
 		logger.Fatal("cannot initialize relayer config", zap.Error(err))
 	}
 
+	// ctx bounds the lifetime of all the relayer components, while acceptCtx is done as soon as the
+	// relayer stops accepting new work on shutdown.
 	ctx, cancel := context.WithCancel(context.Background())
-	wg := &sync.WaitGroup{}
+	acceptCtx, stopAccepting := context.WithCancel(ctx)
+	var (
+		// wg tracks the components serving the processing: the web server and the tx submit checkers
+		wg = &sync.WaitGroup{}
+		// processingWg tracks the subscribers and relayers, which are drained on shutdown
+		processingWg = &sync.WaitGroup{}
+	)
 
 	// The storage has to be shared because of the LevelDB single process restriction.
 	storage, err := app.NewDefaultStorage(cfg, logger)
@@ -88,38 +110,170 @@ func startRelayer() {
 		}
 	}(storage)
 
+	var dryRunRecorder relay.DryRunRecorder
+	if cfg.DryRun {
+		logger.Warn("the relayer runs in the dry run mode, transactions are not going to be broadcast")
+		dryRunRecorder = dryrun.NewRecorder(cfg.DryRunResultsCapacity)
+	}
+
+	// The tx sender is shared by all the connections to keep the Neutron account sequence consistent.
+	txSender, err := app.NewDefaultTxSender(ctx, cfg, logRegistry, dryRunRecorder)
+	if err != nil {
+		logger.Fatal("failed to create NewDefaultTxSender", zap.Error(err))
+	}
+
 	var (
-		queriesTasksQueue      = make(chan neutrontypes.RegisteredQuery, cfg.QueriesTaskQueueCapacity)
-		submittedTxsTasksQueue = make(chan relay.PendingSubmittedTxInfo)
+		pipelines      []*connectionPipeline
+		apiConnections []icqhttp.Connection
 	)
+	for _, conn := range cfg.AllConnections() {
+		pipeline, err := newConnectionPipeline(ctx, cfg.ForConnection(conn), logRegistry, app.NewConnectionStorage(cfg, storage, conn.ConnectionID), txSender)
+		if err != nil {
+			logger.Fatal("failed to initialize connection pipeline", zap.String("connection_id", conn.ConnectionID), zap.Error(err))
+		}
+
+		pipelines = append(pipelines, pipeline)
+		apiConnections = append(apiConnections, pipeline.apiConnection())
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		err := icqhttp.Run(ctx, logRegistry, apiConnections, dryRunRecorder, acceptCtx.Done(), cfg.ListenAddr)
+		if err != nil {
+			logger.Error("WebServer exited with an error", zap.Error(err))
+			cancel()
+		}
+	}()
+
+	for _, pipeline := range pipelines {
+		pipeline.run(ctx, acceptCtx, wg, processingWg, cancel, logger.With(zap.String("connection_id", pipeline.connectionID)))
+	}
 
+	go func() {
+		// Once all the relayers are drained, there is no work left for the rest of the components.
+		// The status checks of the txs submitted last are not waited for: the txs are stored as pending
+		// before they are queued for the TxSubmitChecker, which checks the pending txs left in the storage
+		// on the next start.
+		processingWg.Wait()
+		cancel()
+	}()
+
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+
+		s := <-sigs
+		logger.Info("Received termination signal, draining...",
+			zap.String("signal", s.String()), zap.Duration("grace_period", cfg.ShutdownGracePeriod))
+		stopAccepting()
+
+		select {
+		case <-time.After(cfg.ShutdownGracePeriod):
+			logger.Warn("shutdown grace period is over, abandoning the work in progress")
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	processingWg.Wait()
+	wg.Wait()
+	logger.Info("neutron-query-relayer stopped")
+}
+
+// connectionPipeline contains the components relaying the queries of a single connection.
+type connectionPipeline struct {
+	connectionID           string
+	storage                relay.Storage
+	subscriber             relay.Subscriber
+	relayer                *relay.Relayer
+	txProcessor            relay.TXProcessor
+	trustedHeaderFetcher   relay.TrustedHeaderFetcher
+	clientHealthMonitor    *clienthealth.Monitor
+	clientKeepAlive        *clienthealth.KeepAlive
+	revisionTracker        *revision.Tracker
+	connectionWatcher      *connection.Watcher
+	txSubmitChecker        relay.TxSubmitChecker
+	backfiller             *backfill.Backfiller
+	queriesTasksQueue      *scheduler.Scheduler
+	submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo
+}
+
+func newConnectionPipeline(
+	ctx context.Context,
+	cfg config.NeutronQueryRelayerConfig,
+	logRegistry *nlogger.Registry,
+	storage relay.Storage,
+	txSender *submit.TxSender,
+) (*connectionPipeline, error) {
 	subscriber, err := relaysubscriber.NewDefaultSubscriber(cfg, logRegistry)
 	if err != nil {
-		logger.Fatal("Failed to get NewDefaultSubscriber", zap.Error(err))
+		return nil, fmt.Errorf("failed to get NewDefaultSubscriber: %w", err)
 	}
 
-	deps, err := app.NewDefaultDependencyContainer(ctx, cfg, logRegistry, storage)
+	deps, err := app.NewDefaultDependencyContainer(ctx, cfg, logRegistry, storage, txSender)
 	if err != nil {
-		logger.Fatal("failed to initialize dependency container", zap.Error(err))
+		return nil, fmt.Errorf("failed to initialize dependency container: %w", err)
 	}
 
 	relayer, err := app.NewDefaultRelayer(cfg, logRegistry, storage, deps)
 	if err != nil {
-		logger.Fatal("Failed to get NewDefaultRelayer", zap.Error(err))
+		return nil, fmt.Errorf("failed to get NewDefaultRelayer: %w", err)
 	}
 
 	txSubmitChecker, err := app.NewDefaultTxSubmitChecker(cfg, logRegistry, storage)
 	if err != nil {
-		logger.Fatal("Failed to get NewDefaultTxSubmitChecker", zap.Error(err))
+		return nil, fmt.Errorf("failed to get NewDefaultTxSubmitChecker: %w", err)
+	}
+
+	return &connectionPipeline{
+		connectionID:           cfg.NeutronChain.ConnectionID,
+		storage:                storage,
+		subscriber:             subscriber,
+		relayer:                relayer,
+		txProcessor:            deps.GetTxProcessor(),
+		trustedHeaderFetcher:   deps.GetTrustedHeaderFetcher(),
+		clientHealthMonitor:    deps.GetClientHealthMonitor(),
+		clientKeepAlive:        deps.GetClientKeepAlive(),
+		revisionTracker:        deps.GetRevisionTracker(),
+		connectionWatcher:      deps.GetConnectionWatcher(),
+		txSubmitChecker:        txSubmitChecker,
+		backfiller:             app.NewDefaultBackfiller(logRegistry, storage, deps, subscriber),
+		queriesTasksQueue:      scheduler.NewScheduler(cfg.NeutronChain.ConnectionID, cfg.QueriesTaskQueueCapacity, cfg.OwnerWeights),
+		submittedTxsTasksQueue: make(chan relay.PendingSubmittedTxInfo),
+	}, nil
+}
+
+func (p *connectionPipeline) apiConnection() icqhttp.Connection {
+	return icqhttp.Connection{
+		ID:                     p.connectionID,
+		Storage:                p.storage,
+		TxProcessor:            p.txProcessor,
+		SubmittedTxsTasksQueue: p.submittedTxsTasksQueue,
+		TasksQueue:             p.queriesTasksQueue,
+		Backfiller:             p.backfiller,
+		ClientHealth:           p.clientHealthMonitor,
 	}
+}
 
+// run starts the pipeline components. An error in any of them stops the whole relayer. The subscriber
+// and the relayer stop accepting new work once acceptCtx is done, and are tracked by processingWg.
+func (p *connectionPipeline) run(
+	ctx context.Context,
+	acceptCtx context.Context,
+	wg *sync.WaitGroup,
+	processingWg *sync.WaitGroup,
+	cancel context.CancelFunc,
+	logger *zap.Logger,
+) {
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
-		err := icqhttp.Run(ctx, logRegistry, storage, deps.GetTxProcessor(), submittedTxsTasksQueue, cfg.ListenAddr)
+		err := p.txSubmitChecker.Run(ctx, p.submittedTxsTasksQueue)
 		if err != nil {
-			logger.Error("WebServer exited with an error", zap.Error(err))
+			logger.Error("TxSubmitChecker exited with an error", zap.Error(err))
 			cancel()
 		}
 	}()
@@ -128,44 +282,67 @@ func startRelayer() {
 	go func() {
 		defer wg.Done()
 
-		err := txSubmitChecker.Run(ctx, submittedTxsTasksQueue)
-		if err != nil {
-			logger.Error("TxSubmitChecker exited with an error", zap.Error(err))
-			cancel()
-		}
+		p.trustedHeaderFetcher.Run(ctx)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
-		// The subscriber writes to the tasks queue.
-		if err := subscriber.Subscribe(ctx, queriesTasksQueue); err != nil {
-			logger.Error("Subscriber exited with an error", zap.Error(err))
-			cancel()
-		}
+		p.clientHealthMonitor.Run(ctx)
 	}()
 
 	wg.Add(1)
 	go func() {
 		defer wg.Done()
 
-		// The relayer reads from the tasks queue.
-		if err := relayer.Run(ctx, queriesTasksQueue, submittedTxsTasksQueue); err != nil {
-			logger.Error("Relayer exited with an error", zap.Error(err))
+		p.revisionTracker.Run(ctx)
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		p.connectionWatcher.Run(ctx)
+	}()
+
+	if p.clientKeepAlive != nil {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			p.clientKeepAlive.Run(ctx)
+		}()
+	}
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+
+		// The backfill jobs are not drained: they don't move the queries' last processed heights, so
+		// it's safe to start them over.
+		p.backfiller.Run(acceptCtx, p.submittedTxsTasksQueue)
+	}()
+
+	processingWg.Add(1)
+	go func() {
+		defer processingWg.Done()
+
+		// The subscriber writes to the tasks queue.
+		if err := p.subscriber.Subscribe(acceptCtx, p.queriesTasksQueue); err != nil {
+			logger.Error("Subscriber exited with an error", zap.Error(err))
 			cancel()
 		}
 	}()
 
+	processingWg.Add(1)
 	go func() {
-		sigs := make(chan os.Signal, 1)
-		signal.Notify(sigs, syscall.SIGINT, syscall.SIGTERM)
+		defer processingWg.Done()
 
-		s := <-sigs
-		logger.Info("Received termination signal, gracefully shutting down...",
-			zap.String("signal", s.String()))
-		cancel()
+		// The relayer reads from the tasks queue.
+		if err := p.relayer.Run(ctx, acceptCtx, p.queriesTasksQueue, p.submittedTxsTasksQueue); err != nil {
+			logger.Error("Relayer exited with an error", zap.Error(err))
+			cancel()
+		}
 	}()
-
-	wg.Wait()
 }
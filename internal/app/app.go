@@ -4,8 +4,7 @@ import (
 	"context"
 	"fmt"
 
-	"github.com/neutron-org/neutron-query-relayer/internal/kvprocessor"
-	"github.com/neutron-org/neutron-query-relayer/internal/txprocessor"
+	"github.com/neutron-org/neutron-query-relayer/internal/backfill"
 
 	"github.com/avast/retry-go/v4"
 	cosmosrelayer "github.com/cosmos/relayer/v2/relayer"
@@ -19,9 +18,9 @@ import (
 
 	nlogger "github.com/neutron-org/neutron-logger"
 	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	"github.com/neutron-org/neutron-query-relayer/internal/connection"
 	"github.com/neutron-org/neutron-query-relayer/internal/raw"
 	"github.com/neutron-org/neutron-query-relayer/internal/relay"
-	"github.com/neutron-org/neutron-query-relayer/internal/subscriber/querier/client/query"
 	"github.com/neutron-org/neutron-query-relayer/internal/txsubmitchecker"
 )
 
@@ -43,6 +42,12 @@ const (
 	TxSubmitCheckerContext       = "tx_submit_checker"
 	TrustedHeadersFetcherContext = "trusted_headers_fetcher"
 	KVProcessorContext           = "kv_processor"
+	SubmitterContext             = "submitter"
+	BackfillerContext            = "backfiller"
+	ClientHealthContext          = "client_health"
+	RevisionTrackerContext       = "revision_tracker"
+	ConnectionWatcherContext     = "connection_watcher"
+	TxQuerierContext             = "tx_querier"
 )
 
 // retries configuration for fetching connection info
@@ -60,52 +65,68 @@ func NewDefaultTxSubmitChecker(cfg config.NeutronQueryRelayerConfig, logRegistry
 	}
 
 	return txsubmitchecker.NewTxSubmitChecker(
+		cfg.NeutronChain.ConnectionID,
 		storage,
 		neutronClient,
 		logRegistry.Get(TxSubmitCheckerContext),
 	), nil
 }
 
-// NewDefaultRelayer returns a relayer built with cfg.
+// NewDefaultBackfiller returns a backfiller relaying the transactions with the same dependencies as the relayer.
+func NewDefaultBackfiller(
+	logRegistry *nlogger.Registry,
+	storage relay.Storage,
+	deps *DependencyContainer,
+	queryFetcher relay.QueryFetcher,
+) *backfill.Backfiller {
+	return backfill.NewBackfiller(
+		queryFetcher,
+		deps.GetTxQuerier(),
+		deps.GetTxProcessor(),
+		storage,
+		deps.GetClientHealthMonitor(),
+		logRegistry.Get(BackfillerContext),
+	)
+}
+
+// NewDefaultRelayer returns a relayer built with cfg. The relayer shares the tx and KV processors of deps with
+// the rest of the connection's components, e.g. the client keep-alive, so the recently submitted client
+// updates are known to all of them.
 func NewDefaultRelayer(
 	cfg config.NeutronQueryRelayerConfig,
 	logRegistry *nlogger.Registry,
 	storage relay.Storage,
 	deps *DependencyContainer,
 ) (*relay.Relayer, error) {
-	var (
-		txProcessor = txprocessor.NewTxProcessor(
-			deps.GetTrustedHeaderFetcher(), storage, deps.GetProofSubmitter(), logRegistry.Get(TxProcessorContext), cfg.CheckSubmittedTxStatusDelay, cfg.IgnoreErrorsRegex)
-		kvProcessor = kvprocessor.NewKVProcessor(
-			deps.GetTrustedHeaderFetcher(),
-			deps.GetTargetQuerier(),
-			cfg.MinKvUpdatePeriod,
-			logRegistry.Get(KVProcessorContext),
-			deps.GetProofSubmitter(),
-			storage,
-			deps.GetTargetChain(),
-			deps.GetNeutronChain(),
-		)
-		relayer = relay.NewRelayer(
-			cfg,
-			deps.GetTxQuerier(),
-			storage,
-			txProcessor,
-			kvProcessor,
-			deps.GetTargetChain(),
-			logRegistry.Get(RelayerContext),
-		)
+	relayer := relay.NewRelayer(
+		cfg,
+		deps.GetTxQuerier(),
+		storage,
+		deps.GetTxProcessor(),
+		deps.GetKvProcessor(),
+		deps.GetTargetChain(),
+		deps.GetClientHealthMonitor(),
+		logRegistry.Get(RelayerContext),
 	)
 	return relayer, nil
 }
 
-func NewDefaultStorage(cfg config.NeutronQueryRelayerConfig, logger *zap.Logger) (relay.Storage, error) {
-	var (
-		err            error
-		leveldbStorage relay.Storage
-	)
+// NewDefaultStorage returns the LevelDB storage at the configured storage path.
+//
+// In the dry run mode the storage is an in-memory one, which is thrown away on exit: the tx statuses and the
+// query heights of the dry run must not make a relayer started later on the same storage skip the txs.
+func NewDefaultStorage(cfg config.NeutronQueryRelayerConfig, logger *zap.Logger) (*storage.LevelDBStorage, error) {
+	if cfg.DryRun {
+		logger.Info("dry run mode: using an in-memory storage, the storage at the storage path is not used",
+			zap.String("storage_path", cfg.StoragePath))
+		memStorage, err := storage.NewLevelDBMemStorage()
+		if err != nil {
+			return nil, fmt.Errorf("failed to create NewLevelDBMemStorage: %w", err)
+		}
+		return memStorage, nil
+	}
 
-	leveldbStorage, err = storage.NewLevelDBStorage(cfg.StoragePath)
+	leveldbStorage, err := storage.NewLevelDBStorage(cfg.StoragePath)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create NewLevelDBStorage: %w", err)
 	}
@@ -113,6 +134,16 @@ func NewDefaultStorage(cfg config.NeutronQueryRelayerConfig, logger *zap.Logger)
 	return leveldbStorage, nil
 }
 
+// NewConnectionStorage returns the part of the shared storage which keeps the data of the given
+// connection. The primary connection uses the root namespace to stay compatible with the storages
+// created before multiple connections were supported. The returned storage can't close the shared one.
+func NewConnectionStorage(cfg config.NeutronQueryRelayerConfig, root *storage.LevelDBStorage, connectionID string) relay.Storage {
+	if connectionID == cfg.NeutronChain.ConnectionID {
+		return root.WithNamespace("")
+	}
+	return root.WithNamespace(storage.ConnectionNamespace(connectionID))
+}
+
 func loadChains(
 	ctx context.Context,
 	cfg config.NeutronQueryRelayerConfig,
@@ -124,10 +155,6 @@ func loadChains(
 		return nil, nil, fmt.Errorf("failed to load target chain from env: %w", err)
 	}
 
-	if err := targetChain.AddPath(connParams.targetClientID, connParams.targetConnectionID); err != nil {
-		return nil, nil, fmt.Errorf("failed to AddPath to source chain: %w", err)
-	}
-
 	if err := targetChain.ChainProvider.Init(ctx); err != nil {
 		return nil, nil, fmt.Errorf("failed to Init source chain provider: %w", err)
 	}
@@ -137,7 +164,9 @@ func loadChains(
 		return nil, nil, fmt.Errorf("failed to load neutron chain from env: %w", err)
 	}
 
-	if err := neutronChain.AddPath(connParams.neutronClientID, cfg.NeutronChain.ConnectionID); err != nil {
+	// the clients and the target chain connection are not set in the paths since they may change while the
+	// relayer is running, the actual ones are provided by the connection Watcher
+	if err := neutronChain.AddPath("", cfg.NeutronChain.ConnectionID); err != nil {
 		return nil, nil, fmt.Errorf("failed to AddPath to destination chain: %w", err)
 	}
 
@@ -173,27 +202,11 @@ func loadConnParams(ctx context.Context, neutronClient, targetClient *rpcclienth
 		return nil, fmt.Errorf("failed to fetch neutron chain status: %w", err)
 	}
 
-	var queryResponse *query.IbcCoreConnectionV1ConnectionOK
+	var params relay.ConnectionParams
 	if err := retry.Do(func() error {
 		var err error
-
-		queryResponse, err = restClient.Query.IbcCoreConnectionV1Connection(&query.IbcCoreConnectionV1ConnectionParams{
-			ConnectionID: neutronConnectionId,
-			Context:      ctx,
-		})
-		if err != nil {
-			return err
-		}
-
-		if queryResponse.GetPayload().Connection.Counterparty.ConnectionID == "" {
-			return fmt.Errorf("empty target connection ID")
-		}
-
-		if queryResponse.GetPayload().Connection.Counterparty.ClientID == "" {
-			return fmt.Errorf("empty target client ID")
-		}
-
-		return nil
+		params, err = connection.FetchParams(ctx, restClient, neutronConnectionId)
+		return err
 	}, retry.Context(ctx), rtyAtt, rtyDel, rtyErr, retry.OnRetry(func(n uint, err error) {
 		logger.Info(
 			"failed to query ibc connection info", zap.Error(err))
@@ -201,16 +214,12 @@ func loadConnParams(ctx context.Context, neutronClient, targetClient *rpcclienth
 		return nil, err
 	}
 
-	if err != nil {
-		return nil, fmt.Errorf("failed to query ibc connection info: %w", err)
-	}
-
 	connParams := connectionParams{
 		neutronChainID:     neutronStatus.NodeInfo.Network,
 		targetChainID:      targetStatus.NodeInfo.Network,
-		neutronClientID:    queryResponse.GetPayload().Connection.ClientID,
-		targetClientID:     queryResponse.GetPayload().Connection.Counterparty.ClientID,
-		targetConnectionID: queryResponse.GetPayload().Connection.Counterparty.ConnectionID,
+		neutronClientID:    params.NeutronClientID,
+		targetClientID:     params.TargetClientID,
+		targetConnectionID: params.TargetConnectionID,
 	}
 
 	logger.Info("loaded conn params",
@@ -0,0 +1,87 @@
+package app_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/app"
+	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	"github.com/neutron-org/neutron-query-relayer/internal/storage"
+)
+
+func TestConnectionStoragesDontCollide(t *testing.T) {
+	root, err := storage.NewLevelDBMemStorage()
+	require.NoError(t, err)
+	defer root.Close()
+
+	cfg := config.NeutronQueryRelayerConfig{NeutronChain: &config.NeutronChainConfig{ConnectionID: "connection-0"}}
+	// connection-1 is a prefix of connection-10, their keys must not overlap either
+	connections := []string{"connection-0", "connection-1", "connection-10"}
+	stores := make(map[string]relay.Storage, len(connections))
+	for i, connectionID := range connections {
+		stores[connectionID] = app.NewConnectionStorage(cfg, root, connectionID)
+
+		// every connection keeps its own data under the same query IDs and tx hashes
+		store := stores[connectionID]
+		require.NoError(t, store.SetLastQueryHeight(1, uint64(100+i)))
+		require.NoError(t, store.SetTxStatus(1, "hash", "neutron-hash", relay.SubmittedTxInfo{Status: relay.Submitted}, nil))
+		require.NoError(t, store.SetTxStatus(1, "failed-hash", "neutron-failed-hash",
+			relay.SubmittedTxInfo{Status: relay.ErrorOnSubmit, Message: connectionID}, nil))
+		require.NoError(t, store.SetQueryFailure(relay.QueryFailureInfo{QueryID: 1, ConsecutiveFailures: uint64(i + 1), Quarantined: true}))
+		require.NoError(t, store.SetCriticalTxError(relay.CriticalTxErrorInfo{QueryID: 1, Message: connectionID}))
+	}
+
+	// the primary connection uses the root namespace
+	height, found, err := root.GetLastQueryHeight(1)
+	require.NoError(t, err)
+	require.True(t, found)
+	assert.Equal(t, uint64(100), height)
+
+	// one connection removes its data and stops, closing its storage
+	failed := stores["connection-1"]
+	require.NoError(t, failed.SetTxStatus(1, "hash", "neutron-hash", relay.SubmittedTxInfo{Status: relay.Committed}, nil))
+	require.NoError(t, failed.RemoveQueryFailure(1))
+	require.NoError(t, failed.RemoveCriticalTxError(1))
+	require.NoError(t, failed.Close())
+
+	for i, connectionID := range connections {
+		store := stores[connectionID]
+		if connectionID == "connection-1" {
+			pending, err := store.GetAllPendingTxs()
+			require.NoError(t, err)
+			assert.Empty(t, pending)
+			quarantined, err := store.GetAllQuarantinedQueries()
+			require.NoError(t, err)
+			assert.Empty(t, quarantined)
+			continue
+		}
+
+		height, found, err := store.GetLastQueryHeight(1)
+		require.NoError(t, err, connectionID)
+		require.True(t, found, connectionID)
+		assert.Equal(t, uint64(100+i), height, connectionID)
+
+		pending, err := store.GetAllPendingTxs()
+		require.NoError(t, err)
+		require.Len(t, pending, 1, connectionID)
+		assert.Equal(t, "neutron-hash", pending[0].NeutronHash)
+
+		unsuccessful, err := store.GetAllUnsuccessfulTxs()
+		require.NoError(t, err)
+		require.Len(t, unsuccessful, 1, connectionID)
+		assert.Equal(t, connectionID, unsuccessful[0].Message)
+
+		quarantined, err := store.GetAllQuarantinedQueries()
+		require.NoError(t, err)
+		require.Len(t, quarantined, 1, connectionID)
+		assert.Equal(t, uint64(i+1), quarantined[0].ConsecutiveFailures)
+
+		criticalErrors, err := store.GetAllCriticalTxErrors()
+		require.NoError(t, err)
+		require.Len(t, criticalErrors, 1, connectionID)
+		assert.Equal(t, connectionID, criticalErrors[0].Message)
+	}
+}
@@ -8,10 +8,13 @@ import (
 	cosmosrelayer "github.com/cosmos/relayer/v2/relayer"
 
 	nlogger "github.com/neutron-org/neutron-logger"
+	"github.com/neutron-org/neutron-query-relayer/internal/clienthealth"
 	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	"github.com/neutron-org/neutron-query-relayer/internal/connection"
 	"github.com/neutron-org/neutron-query-relayer/internal/kvprocessor"
 	"github.com/neutron-org/neutron-query-relayer/internal/raw"
 	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	"github.com/neutron-org/neutron-query-relayer/internal/revision"
 	"github.com/neutron-org/neutron-query-relayer/internal/submit"
 	"github.com/neutron-org/neutron-query-relayer/internal/tmquerier"
 	"github.com/neutron-org/neutron-query-relayer/internal/trusted_headers"
@@ -25,15 +28,61 @@ type DependencyContainer struct {
 	kvProcessor          relay.KVProcessor
 	proofSubmitter       relay.Submitter
 	trustedHeaderFetcher relay.TrustedHeaderFetcher
+	clientHealthMonitor  *clienthealth.Monitor
+	clientKeepAlive      *clienthealth.KeepAlive
+	revisionTracker      *revision.Tracker
+	connectionWatcher    *connection.Watcher
 	targetChain          *cosmosrelayer.Chain
 	neutronChain         *cosmosrelayer.Chain
 	targetQuerier        *tmquerier.Querier
 }
 
+// NewDefaultTxSender returns a TxSender built with cfg. The TxSender is shared by all the connections
+// since they use the same key to sign Neutron transactions.
+//
+// If dryRunRecorder is not nil, the TxSender works in the dry run mode and never broadcasts transactions.
+func NewDefaultTxSender(ctx context.Context,
+	cfg config.NeutronQueryRelayerConfig,
+	logRegistry *nlogger.Registry,
+	dryRunRecorder relay.DryRunRecorder) (*submit.TxSender, error) {
+	neutronClient, err := raw.NewRPCClient(cfg.NeutronChain.RPCAddr, cfg.NeutronChain.Timeout)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create neutron client: %w", err)
+	}
+
+	neutronStatus, err := neutronClient.Status(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch neutron chain status: %w", err)
+	}
+	neutronChainID := neutronStatus.NodeInfo.Network
+
+	cdc := raw.MakeCodecDefault()
+	keybase, err := submit.TestKeybase(neutronChainID, cfg.NeutronChain.HomeDir, codec.NewProtoCodec(cdc.InterfaceRegistry))
+	if err != nil {
+		return nil, fmt.Errorf("cannot initialize keybase: %w", err)
+	}
+
+	txSender, err := submit.NewTxSender(ctx,
+		neutronClient,
+		cdc.Marshaller,
+		keybase,
+		*cfg.NeutronChain,
+		logRegistry.Get(TxSenderContext),
+		neutronChainID,
+		dryRunRecorder)
+	if err != nil {
+		return nil, fmt.Errorf("cannot create tx sender: %w", err)
+	}
+
+	return txSender, nil
+}
+
+// NewDefaultDependencyContainer builds the dependencies of the connection configured in cfg.
 func NewDefaultDependencyContainer(ctx context.Context,
 	cfg config.NeutronQueryRelayerConfig,
 	logRegistry *nlogger.Registry,
-	storage relay.Storage) (*DependencyContainer, error) {
+	storage relay.Storage,
+	txSender *submit.TxSender) (*DependencyContainer, error) {
 	targetClient, err := raw.NewRPCClient(cfg.TargetChain.RPCAddr, cfg.TargetChain.Timeout)
 	if err != nil {
 		return nil, fmt.Errorf("could not initialize target rpc client: %w", err)
@@ -55,33 +104,38 @@ func NewDefaultDependencyContainer(ctx context.Context,
 		return nil, fmt.Errorf("cannot connect to target chain: %w", err)
 	}
 
-	cdc := raw.MakeCodecDefault()
-	keybase, err := submit.TestKeybase(connParams.neutronChainID, cfg.NeutronChain.HomeDir, codec.NewProtoCodec(cdc.InterfaceRegistry))
+	neutronChain, targetChain, err := loadChains(ctx, cfg, logRegistry, connParams)
 	if err != nil {
-		return nil, fmt.Errorf("cannot initialize keybase: %w", err)
+		return nil, fmt.Errorf("failed to loadChains: %w", err)
 	}
 
-	txSender, err := submit.NewTxSender(ctx,
-		neutronClient,
-		cdc.Marshaller,
-		keybase,
-		*cfg.NeutronChain,
-		logRegistry.Get(TxSenderContext),
-		connParams.neutronChainID)
+	restClient, err := raw.NewRESTClient(cfg.NeutronChain.RESTAddr)
 	if err != nil {
-		return nil, fmt.Errorf("cannot create tx sender: %w", err)
+		return nil, fmt.Errorf("failed to get newRESTClient: %w", err)
 	}
-
-	neutronChain, targetChain, err := loadChains(ctx, cfg, logRegistry, connParams)
+	connectionWatcher := connection.NewWatcher(restClient, cfg.NeutronChain.ConnectionID, relay.ConnectionParams{
+		NeutronClientID:    connParams.neutronClientID,
+		TargetClientID:     connParams.targetClientID,
+		TargetConnectionID: connParams.targetConnectionID,
+	}, cfg.ConnectionCheckPeriod, logRegistry.Get(ConnectionWatcherContext))
+
+	proofSubmitter := submit.NewSubmitterImpl(
+		txSender, cfg.NeutronChain.ConnectionID, cfg.AllowKVCallbacks, connectionWatcher, cfg.KvBatchMaxGas, logRegistry.Get(SubmitterContext))
+	var txQuerier relay.TXQuerier
+	if cfg.TargetChain.TxSource == config.TxSourceBlocks {
+		txQuerier = txquerier.NewBlockScannerSrv(targetQuerier.Client, cfg.TxBlockResultsCacheSize,
+			cfg.NeutronChain.ConnectionID, logRegistry.Get(TxQuerierContext))
+	} else {
+		txQuerier = txquerier.NewTXQuerySrv(targetQuerier.Client, cfg.TxBlockResultsCacheSize, cfg.TxProofWorkers)
+	}
+	revisionTracker, err := revision.NewTracker(neutronChain, targetChain, connectionWatcher, cfg.RevisionCheckPeriod, logRegistry.Get(RevisionTrackerContext))
 	if err != nil {
-		return nil, fmt.Errorf("failed to loadChains: %w", err)
+		return nil, fmt.Errorf("failed to create revision tracker: %w", err)
 	}
-
-	proofSubmitter := submit.NewSubmitterImpl(txSender, cfg.AllowKVCallbacks, neutronChain.PathEnd.ClientID)
-	txQuerier := txquerier.NewTXQuerySrv(targetQuerier.Client)
-	trustedHeaderFetcher := trusted_headers.NewTrustedHeaderFetcher(neutronChain, targetChain, logRegistry.Get(TrustedHeadersFetcherContext))
+	trustedHeaderFetcher := trusted_headers.NewTrustedHeaderFetcher(
+		neutronChain, targetChain, logRegistry.Get(TrustedHeadersFetcherContext), cfg.TrustedHeadersCacheSize, cfg.TrustedHeightsRefreshPeriod, revisionTracker, connectionWatcher)
 	txProcessor := txprocessor.NewTxProcessor(
-		trustedHeaderFetcher, storage, proofSubmitter, logRegistry.Get(TxProcessorContext), cfg.CheckSubmittedTxStatusDelay, cfg.IgnoreErrorsRegex)
+		trustedHeaderFetcher, storage, proofSubmitter, cfg.NeutronChain.ConnectionID, logRegistry.Get(TxProcessorContext), cfg.CheckSubmittedTxStatusDelay, cfg.IgnoreErrorsRegex, cfg.DryRun, cfg.CriticalTxErrorPolicy, connectionWatcher)
 	kvProcessor := kvprocessor.NewKVProcessor(
 		trustedHeaderFetcher,
 		targetQuerier,
@@ -91,13 +145,29 @@ func NewDefaultDependencyContainer(ctx context.Context,
 		storage,
 		targetChain,
 		neutronChain,
+		cfg.KvClientUpdatesCacheSize,
+		revisionTracker,
+		connectionWatcher,
+		cfg.DryRun,
 	)
+	clientHealthMonitor := clienthealth.NewMonitor(
+		neutronChain, connectionWatcher, cfg.ClientHealthCheckPeriod, cfg.ClientExpiryWarningPeriod, logRegistry.Get(ClientHealthContext))
+	// the keep-alive client updates are disabled with the zero threshold
+	var clientKeepAlive *clienthealth.KeepAlive
+	if cfg.ClientKeepAliveThreshold > 0 {
+		clientKeepAlive = clienthealth.NewKeepAlive(clientHealthMonitor, kvProcessor, cfg.ClientKeepAliveThreshold,
+			cfg.ClientHealthCheckPeriod, neutronChain.PathEnd.ConnectionID, logRegistry.Get(ClientHealthContext))
+	}
 	return &DependencyContainer{
 		txQuerier:            txQuerier,
 		txProcessor:          txProcessor,
 		kvProcessor:          kvProcessor,
 		proofSubmitter:       proofSubmitter,
 		trustedHeaderFetcher: trustedHeaderFetcher,
+		clientHealthMonitor:  clientHealthMonitor,
+		clientKeepAlive:      clientKeepAlive,
+		revisionTracker:      revisionTracker,
+		connectionWatcher:    connectionWatcher,
 		targetChain:          targetChain,
 		neutronChain:         neutronChain,
 		targetQuerier:        targetQuerier,
@@ -132,6 +202,23 @@ func (c DependencyContainer) GetTrustedHeaderFetcher() relay.TrustedHeaderFetche
 	return c.trustedHeaderFetcher
 }
 
+func (c DependencyContainer) GetClientHealthMonitor() *clienthealth.Monitor {
+	return c.clientHealthMonitor
+}
+
+// GetClientKeepAlive returns nil if the keep-alive client updates are disabled.
+func (c DependencyContainer) GetClientKeepAlive() *clienthealth.KeepAlive {
+	return c.clientKeepAlive
+}
+
+func (c DependencyContainer) GetRevisionTracker() *revision.Tracker {
+	return c.revisionTracker
+}
+
+func (c DependencyContainer) GetConnectionWatcher() *connection.Watcher {
+	return c.connectionWatcher
+}
+
 func (c DependencyContainer) GetTargetQuerier() *tmquerier.Querier {
 	return c.targetQuerier
 }
@@ -0,0 +1,287 @@
+package backfill
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"sync"
+	"time"
+
+	tmtypes "github.com/cometbft/cometbft/types"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+const (
+	// maxPendingJobs is the number of the jobs that can wait for their turn at the same time
+	maxPendingJobs = 16
+	// jobsHistorySize is the number of the latest jobs whose states are kept
+	jobsHistorySize = 100
+	// clientHealthRecheckPeriod is how often the client health is checked while the backfill is paused
+	clientHealthRecheckPeriod = 5 * time.Second
+)
+
+// job is a backfill job waiting in the queue
+type job struct {
+	info        *relay.BackfillInfo
+	queryString string
+}
+
+// Backfiller is an implementation of relay.Backfiller which runs the backfill jobs one by one
+// using the same TXQuerier and TXProcessor as the relayer.
+//
+// The backfill ranges are clamped to the heights the relayer has already processed: the relayer only
+// searches for transactions above the last processed height of a query, which never goes down, so the
+// same transaction is never submitted by both of them concurrently.
+type Backfiller struct {
+	mutex        sync.Mutex
+	jobs         []*relay.BackfillInfo
+	queue        chan job
+	queryFetcher relay.QueryFetcher
+	txQuerier    relay.TXQuerier
+	txProcessor  relay.TXProcessor
+	storage      relay.Storage
+	// clientHealth pauses the jobs the same way it pauses the relayer, may be nil
+	clientHealth relay.ClientHealthChecker
+	logger       *zap.Logger
+}
+
+func NewBackfiller(
+	queryFetcher relay.QueryFetcher,
+	txQuerier relay.TXQuerier,
+	txProcessor relay.TXProcessor,
+	storage relay.Storage,
+	clientHealth relay.ClientHealthChecker,
+	logger *zap.Logger,
+) *Backfiller {
+	return &Backfiller{
+		queue:        make(chan job, maxPendingJobs),
+		queryFetcher: queryFetcher,
+		txQuerier:    txQuerier,
+		txProcessor:  txProcessor,
+		storage:      storage,
+		clientHealth: clientHealth,
+		logger:       logger,
+	}
+}
+
+// Start validates the request and schedules a backfill job for it.
+func (b *Backfiller) Start(ctx context.Context, request relay.BackfillRequest) (relay.BackfillInfo, error) {
+	if request.FromHeight == 0 || request.FromHeight > request.ToHeight {
+		return relay.BackfillInfo{}, fmt.Errorf("invalid height range [%d, %d]", request.FromHeight, request.ToHeight)
+	}
+
+	query, err := b.queryFetcher.RegisteredQuery(ctx, request.QueryID)
+	if err != nil {
+		return relay.BackfillInfo{}, fmt.Errorf("failed to get registered query: %w", err)
+	}
+	if query.QueryType != string(neutrontypes.InterchainQueryTypeTX) {
+		return relay.BackfillInfo{}, fmt.Errorf("query with id=%d is not a TX query", request.QueryID)
+	}
+
+	lastHeight, found, err := b.storage.GetLastQueryHeight(request.QueryID)
+	if err != nil {
+		return relay.BackfillInfo{}, fmt.Errorf("failed to get last query height: %w", err)
+	}
+	if !found || request.FromHeight > lastHeight {
+		return relay.BackfillInfo{}, fmt.Errorf("height range [%d, %d] is not processed by the relayer yet, the last processed height is %d",
+			request.FromHeight, request.ToHeight, lastHeight)
+	}
+	if request.ToHeight > lastHeight {
+		b.logger.Info("backfill range is clamped to the last processed height of the query",
+			zap.Uint64("query_id", request.QueryID),
+			zap.Uint64("to_height", request.ToHeight),
+			zap.Uint64("last_processed_height", lastHeight))
+		request.ToHeight = lastHeight
+	}
+
+	queryString, err := relay.CompileTxFilter(query.TransactionsFilter, request.FromHeight-1, request.ToHeight)
+	if err != nil {
+		return relay.BackfillInfo{}, fmt.Errorf("failed to process tx query params: %w", err)
+	}
+
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	for _, info := range b.jobs {
+		if info.QueryID == request.QueryID && (info.Status == relay.BackfillPending || info.Status == relay.BackfillRunning) {
+			return relay.BackfillInfo{}, fmt.Errorf("query with id=%d is already being backfilled", request.QueryID)
+		}
+	}
+
+	info := &relay.BackfillInfo{
+		BackfillRequest: request,
+		Status:          relay.BackfillPending,
+		ProcessedHeight: request.FromHeight - 1,
+		CreateTime:      time.Now(),
+	}
+	select {
+	case b.queue <- job{info: info, queryString: queryString}:
+	default:
+		return relay.BackfillInfo{}, fmt.Errorf("too many pending backfill jobs")
+	}
+
+	b.jobs = append(b.jobs, info)
+	if len(b.jobs) > jobsHistorySize {
+		b.jobs = b.jobs[len(b.jobs)-jobsHistorySize:]
+	}
+
+	b.logger.Info("backfill job scheduled", zap.Uint64("query_id", request.QueryID),
+		zap.Uint64("from_height", request.FromHeight), zap.Uint64("to_height", request.ToHeight))
+	return *info, nil
+}
+
+// Jobs returns the states of the recent backfill jobs.
+func (b *Backfiller) Jobs() []relay.BackfillInfo {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	out := make([]relay.BackfillInfo, 0, len(b.jobs))
+	for _, info := range b.jobs {
+		out = append(out, *info)
+	}
+	return out
+}
+
+// Run processes the scheduled jobs one by one until ctx is done. The jobs which are not finished by
+// then are cancelled.
+func (b *Backfiller) Run(ctx context.Context, submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo) {
+	for {
+		select {
+		case j := <-b.queue:
+			b.process(ctx, j, submittedTxsTasksQueue)
+		case <-ctx.Done():
+			b.mutex.Lock()
+			for _, info := range b.jobs {
+				if info.Status == relay.BackfillPending {
+					b.finishLocked(info, relay.BackfillCancelled, nil)
+				}
+			}
+			b.mutex.Unlock()
+			b.logger.Info("Context cancelled, shutting down Backfiller...")
+			return
+		}
+	}
+}
+
+func (b *Backfiller) process(ctx context.Context, j job, submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo) {
+	b.update(j.info, func(info *relay.BackfillInfo) {
+		info.Status = relay.BackfillRunning
+	})
+	b.logger.Info("backfill job started", zap.Uint64("query_id", j.info.QueryID), zap.String("query", j.queryString))
+
+	err := b.relayTxs(ctx, j, submittedTxsTasksQueue)
+
+	status := relay.BackfillDone
+	switch {
+	case ctx.Err() != nil:
+		status = relay.BackfillCancelled
+	case err != nil:
+		status = relay.BackfillFailed
+	}
+
+	b.mutex.Lock()
+	b.finishLocked(j.info, status, err)
+	info := *j.info
+	b.mutex.Unlock()
+
+	b.logger.Info("backfill job finished", zap.Uint64("query_id", info.QueryID), zap.String("status", info.Status),
+		zap.Uint64("processed_height", info.ProcessedHeight), zap.Uint64("submitted_txs", info.SubmittedTxs),
+		zap.Uint64("skipped_txs", info.SkippedTxs), zap.Error(err))
+}
+
+// relayTxs relays the transactions of the job's query which haven't been submitted yet.
+func (b *Backfiller) relayTxs(ctx context.Context, j job, submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo) error {
+	cancelCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	txs, errs := b.txQuerier.SearchTransactions(cancelCtx, j.queryString)
+	lastProcessedHeight := uint64(0)
+	for tx := range txs {
+		if tx.Tx == nil {
+			lastProcessedHeight = tx.Height
+			b.update(j.info, func(info *relay.BackfillInfo) {
+				info.ProcessedHeight = tx.Height
+			})
+			continue
+		}
+
+		if tx.Height > lastProcessedHeight && lastProcessedHeight > 0 {
+			height := lastProcessedHeight
+			b.update(j.info, func(info *relay.BackfillInfo) {
+				info.ProcessedHeight = height
+			})
+		}
+		lastProcessedHeight = tx.Height
+
+		hash := hex.EncodeToString(tmtypes.Tx(tx.Tx.Data).Hash())
+		txExists, err := b.storage.TxExists(j.info.QueryID, hash)
+		if err != nil {
+			return fmt.Errorf("failed to check tx existence: %w", err)
+		}
+
+		if txExists {
+			b.update(j.info, func(info *relay.BackfillInfo) {
+				info.SkippedTxs++
+			})
+			continue
+		}
+
+		if err := b.waitForHealthyClient(ctx, j.info.QueryID); err != nil {
+			return err
+		}
+		if err := b.txProcessor.ProcessAndSubmit(ctx, j.info.QueryID, tx, submittedTxsTasksQueue); err != nil {
+			return fmt.Errorf("failed to process txs: %w", err)
+		}
+		b.update(j.info, func(info *relay.BackfillInfo) {
+			info.SubmittedTxs++
+		})
+	}
+
+	if err := <-errs; err != nil {
+		return fmt.Errorf("failed to query txs: %w", err)
+	}
+
+	b.update(j.info, func(info *relay.BackfillInfo) {
+		info.ProcessedHeight = info.ToHeight
+	})
+	return nil
+}
+
+// waitForHealthyClient blocks while Neutron's light client of the target chain can't be used to verify
+// proofs, the same way the relayer pauses the submissions.
+func (b *Backfiller) waitForHealthyClient(ctx context.Context, queryID uint64) error {
+	if b.clientHealth == nil {
+		return nil
+	}
+
+	for paused := false; !b.clientHealth.ClientHealth().Healthy(); paused = true {
+		if !paused {
+			b.logger.Warn("backfill is paused since Neutron's light client of the target chain is not healthy",
+				zap.Uint64("query_id", queryID))
+		}
+		select {
+		case <-time.After(clientHealthRecheckPeriod):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *Backfiller) update(info *relay.BackfillInfo, fn func(info *relay.BackfillInfo)) {
+	b.mutex.Lock()
+	defer b.mutex.Unlock()
+
+	fn(info)
+}
+
+func (b *Backfiller) finishLocked(info *relay.BackfillInfo, status string, err error) {
+	now := time.Now()
+	info.Status = status
+	info.EndTime = &now
+	if err != nil {
+		info.Error = err.Error()
+	}
+}
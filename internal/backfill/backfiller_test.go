@@ -0,0 +1,202 @@
+package backfill_test
+
+import (
+	"context"
+	"encoding/hex"
+	"sync"
+	"testing"
+	"time"
+
+	tmtypes "github.com/cometbft/cometbft/types"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/backfill"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	"github.com/neutron-org/neutron-query-relayer/internal/storage"
+)
+
+const queryID = uint64(1)
+
+type queryFetcher struct{}
+
+func (queryFetcher) RegisteredQuery(_ context.Context, id uint64) (*neutrontypes.RegisteredQuery, error) {
+	return &neutrontypes.RegisteredQuery{
+		Id:                 id,
+		QueryType:          string(neutrontypes.InterchainQueryTypeTX),
+		TransactionsFilter: `[]`,
+	}, nil
+}
+
+// txQuerier returns txs and blocks until ctx is done if block is set.
+type txQuerier struct {
+	txs     []relay.Transaction
+	block   bool
+	mutex   sync.Mutex
+	queries []string
+}
+
+func (q *txQuerier) SearchTransactions(ctx context.Context, query string) (<-chan relay.Transaction, <-chan error) {
+	q.mutex.Lock()
+	q.queries = append(q.queries, query)
+	q.mutex.Unlock()
+
+	txs := make(chan relay.Transaction)
+	errs := make(chan error, 1)
+	go func() {
+		defer close(txs)
+		for _, tx := range q.txs {
+			select {
+			case txs <- tx:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+		if q.block {
+			<-ctx.Done()
+			errs <- ctx.Err()
+			return
+		}
+		errs <- nil
+	}()
+	return txs, errs
+}
+
+type txProcessor struct {
+	mutex     sync.Mutex
+	processed []relay.Transaction
+}
+
+func (p *txProcessor) ProcessAndSubmit(_ context.Context, _ uint64, tx relay.Transaction, _ chan relay.PendingSubmittedTxInfo) error {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	p.processed = append(p.processed, tx)
+	return nil
+}
+
+func newTx(height uint64, data string) relay.Transaction {
+	return relay.Transaction{Tx: &neutrontypes.TxValue{Data: []byte(data)}, Height: height}
+}
+
+func newStorage(t *testing.T, lastHeight uint64) *storage.LevelDBStorage {
+	store, err := storage.NewLevelDBMemStorage()
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = store.Close() })
+	require.NoError(t, store.SetLastQueryHeight(queryID, lastHeight))
+	return store
+}
+
+// waitForJob waits until the only job gets the given status.
+func waitForJob(t *testing.T, b *backfill.Backfiller, status string) relay.BackfillInfo {
+	var info relay.BackfillInfo
+	require.Eventually(t, func() bool {
+		info = b.Jobs()[0]
+		return info.Status == status
+	}, 5*time.Second, 10*time.Millisecond)
+	return info
+}
+
+func TestBackfillerClampsRangeToLastProcessedHeight(t *testing.T) {
+	store := newStorage(t, 10)
+	// the tx at height 5 has already been submitted
+	submitted := newTx(5, "submitted")
+	hash := hex.EncodeToString(tmtypes.Tx(submitted.Tx.Data).Hash())
+	require.NoError(t, store.SetTxStatus(queryID, hash, "", relay.SubmittedTxInfo{Status: relay.Committed}, nil))
+
+	querier := &txQuerier{txs: []relay.Transaction{submitted, newTx(7, "missed")}}
+	processor := &txProcessor{}
+	b := backfill.NewBackfiller(queryFetcher{}, querier, processor, store, nil, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go b.Run(ctx, nil)
+
+	info, err := b.Start(ctx, relay.BackfillRequest{QueryID: queryID, FromHeight: 5, ToHeight: 20})
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), info.ToHeight)
+
+	info = waitForJob(t, b, relay.BackfillDone)
+	assert.Equal(t, uint64(1), info.SubmittedTxs)
+	assert.Equal(t, uint64(1), info.SkippedTxs)
+	assert.Equal(t, uint64(10), info.ProcessedHeight)
+	require.Len(t, processor.processed, 1)
+	assert.Equal(t, uint64(7), processor.processed[0].Height)
+	require.Len(t, querier.queries, 1)
+	assert.Contains(t, querier.queries[0], "tx.height<=10")
+
+	lastHeight, _, err := store.GetLastQueryHeight(queryID)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(10), lastHeight, "backfill must not move the last processed height")
+}
+
+func TestBackfillerRejectsRangeNotProcessedByRelayer(t *testing.T) {
+	tests := []struct {
+		name       string
+		lastHeight *uint64
+		request    relay.BackfillRequest
+	}{
+		{
+			name:       "range above the last processed height",
+			lastHeight: func() *uint64 { h := uint64(10); return &h }(),
+			request:    relay.BackfillRequest{QueryID: queryID, FromHeight: 11, ToHeight: 20},
+		},
+		{
+			name:    "query not processed yet",
+			request: relay.BackfillRequest{QueryID: queryID, FromHeight: 1, ToHeight: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store, err := storage.NewLevelDBMemStorage()
+			require.NoError(t, err)
+			defer store.Close()
+			if tt.lastHeight != nil {
+				require.NoError(t, store.SetLastQueryHeight(queryID, *tt.lastHeight))
+			}
+
+			b := backfill.NewBackfiller(queryFetcher{}, &txQuerier{}, &txProcessor{}, store, nil, zap.NewNop())
+			_, err = b.Start(context.Background(), tt.request)
+			assert.Error(t, err)
+			assert.Empty(t, b.Jobs())
+		})
+	}
+}
+
+func TestBackfillerCancellation(t *testing.T) {
+	store := newStorage(t, 100)
+	processor := &txProcessor{}
+	b := backfill.NewBackfiller(queryFetcher{}, &txQuerier{txs: []relay.Transaction{newTx(5, "tx")}, block: true},
+		processor, store, nil, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		b.Run(ctx, nil)
+	}()
+
+	_, err := b.Start(ctx, relay.BackfillRequest{QueryID: queryID, FromHeight: 1, ToHeight: 50})
+	require.NoError(t, err)
+	waitForJob(t, b, relay.BackfillRunning)
+	require.Eventually(t, func() bool {
+		processor.mutex.Lock()
+		defer processor.mutex.Unlock()
+		return len(processor.processed) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Backfiller didn't stop after the context was cancelled")
+	}
+
+	info := b.Jobs()[0]
+	assert.Equal(t, relay.BackfillCancelled, info.Status)
+	assert.NotNil(t, info.EndTime)
+}
@@ -0,0 +1,99 @@
+package clienthealth
+
+import (
+	"context"
+	"time"
+
+	"go.uber.org/zap"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+// KeepAlive updates Neutron's light client of the target chain once its latest consensus state gets older
+// than the given fraction of the trusting period, so the client of a connection with few KV queries
+// doesn't expire.
+type KeepAlive struct {
+	health       relay.ClientHealthChecker
+	updater      relay.ClientUpdater
+	threshold    float64
+	checkPeriod  time.Duration
+	connectionID string
+	logger       *zap.Logger
+	// lastUpdate is the time of the latest client update, the health checked before it is outdated
+	lastUpdate time.Time
+}
+
+func NewKeepAlive(
+	health relay.ClientHealthChecker,
+	updater relay.ClientUpdater,
+	threshold float64,
+	checkPeriod time.Duration,
+	connectionID string,
+	logger *zap.Logger,
+) *KeepAlive {
+	return &KeepAlive{
+		health:       health,
+		updater:      updater,
+		threshold:    threshold,
+		checkPeriod:  checkPeriod,
+		connectionID: connectionID,
+		logger:       logger,
+	}
+}
+
+// Run checks whether the client needs an update every checkPeriod until ctx is done.
+func (k *KeepAlive) Run(ctx context.Context) {
+	ticker := time.NewTicker(k.checkPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			k.keepAlive(ctx)
+		case <-ctx.Done():
+			k.logger.Info("Context cancelled, shutting down client KeepAlive...")
+			return
+		}
+	}
+}
+
+func (k *KeepAlive) keepAlive(ctx context.Context) {
+	health := k.health.ClientHealth()
+	if !k.needsUpdate(health, time.Now()) {
+		return
+	}
+
+	age := time.Since(health.LatestConsensusTime)
+	height, err := k.updater.UpdateClient(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		neutronmetrics.IncClientKeepAliveUpdates(k.connectionID, false)
+		k.logger.Error("failed to submit keep-alive client update",
+			zap.String("client_id", health.ClientID),
+			zap.Duration("consensus_state_age", age),
+			zap.Duration("time_to_expiry", health.TimeToExpiry),
+			zap.Error(err))
+		return
+	}
+
+	k.lastUpdate = time.Now()
+	neutronmetrics.IncClientKeepAliveUpdates(k.connectionID, true)
+	k.logger.Info("submitted keep-alive client update",
+		zap.String("client_id", health.ClientID),
+		zap.Uint64("height", height),
+		zap.Duration("consensus_state_age", age))
+}
+
+// needsUpdate returns true if the latest consensus state of an active client is older than the threshold
+// fraction of the trusting period. An expired or frozen client can't be updated with a plain MsgUpdateClient.
+func (k *KeepAlive) needsUpdate(health relay.ClientHealthInfo, now time.Time) bool {
+	if health.Status != relay.ClientStatusActive || !health.CheckTime.After(k.lastUpdate) {
+		return false
+	}
+
+	trustingPeriod := health.ExpiryTime.Sub(health.LatestConsensusTime)
+	return now.Sub(health.LatestConsensusTime) >= time.Duration(k.threshold*float64(trustingPeriod))
+}
@@ -0,0 +1,110 @@
+package clienthealth_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/clienthealth"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+type healthChecker relay.ClientHealthInfo
+
+func (h healthChecker) ClientHealth() relay.ClientHealthInfo {
+	return relay.ClientHealthInfo(h)
+}
+
+type clientUpdater struct {
+	mutex   sync.Mutex
+	updates int
+	err     error
+}
+
+func (u *clientUpdater) UpdateClient(context.Context) (uint64, error) {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	u.updates++
+	return 100, u.err
+}
+
+func (u *clientUpdater) count() int {
+	u.mutex.Lock()
+	defer u.mutex.Unlock()
+
+	return u.updates
+}
+
+func TestKeepAlive(t *testing.T) {
+	const trustingPeriod = 10 * time.Hour
+	// health returns the state of a client with the latest consensus state of the given age checked a second ago
+	health := func(status string, age time.Duration) healthChecker {
+		latest := time.Now().Add(-age)
+		return healthChecker{
+			ClientID:            "07-tendermint-0",
+			Status:              status,
+			LatestConsensusTime: latest,
+			ExpiryTime:          latest.Add(trustingPeriod),
+			CheckTime:           time.Now().Add(-time.Second),
+		}
+	}
+
+	tests := []struct {
+		name      string
+		health    healthChecker
+		updateErr error
+		// updates is the expected number of updates, -1 means more than one
+		updates int
+	}{
+		{
+			name:    "consensus state older than the threshold",
+			health:  health(relay.ClientStatusActive, 6*time.Hour),
+			updates: 1,
+		},
+		{
+			name:    "consensus state younger than the threshold",
+			health:  health(relay.ClientStatusActive, 4*time.Hour),
+			updates: 0,
+		},
+		{
+			name:    "expired client",
+			health:  health(relay.ClientStatusExpired, 11*time.Hour),
+			updates: 0,
+		},
+		{
+			name:    "frozen client",
+			health:  health(relay.ClientStatusFrozen, 6*time.Hour),
+			updates: 0,
+		},
+		{
+			name:      "failed update is retried",
+			health:    health(relay.ClientStatusActive, 6*time.Hour),
+			updateErr: errors.New("out of gas"),
+			updates:   -1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			updater := &clientUpdater{err: tt.updateErr}
+			keepAlive := clienthealth.NewKeepAlive(tt.health, updater, 0.5, 10*time.Millisecond, "connection-0", zap.NewNop())
+
+			ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+			defer cancel()
+			// the health is never re-checked, so a successful update is only submitted once
+			keepAlive.Run(ctx)
+
+			if tt.updates < 0 {
+				assert.Greater(t, updater.count(), 1)
+			} else {
+				assert.Equal(t, tt.updates, updater.count())
+			}
+		})
+	}
+}
@@ -0,0 +1,182 @@
+package clienthealth
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	"github.com/cosmos/relayer/v2/relayer"
+	"github.com/cosmos/relayer/v2/relayer/chains/cosmos"
+	"go.uber.org/zap"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+var clientStatuses = []string{
+	relay.ClientStatusUnknown,
+	relay.ClientStatusActive,
+	relay.ClientStatusExpired,
+	relay.ClientStatusFrozen,
+}
+
+// Monitor is an implementation of relay.ClientHealthChecker which periodically checks Neutron's light
+// client of the target chain. The status is the one reported by Neutron itself, the expiry time is
+// computed from the latest consensus state timestamp and the trusting period of the client.
+type Monitor struct {
+	mutex               sync.RWMutex
+	health              relay.ClientHealthInfo
+	neutronChain        *relayer.Chain
+	connection          relay.ConnectionParamsProvider
+	connectionID        string
+	checkPeriod         time.Duration
+	expiryWarningPeriod time.Duration
+	logger              *zap.Logger
+	// fetch gets the current state of the client from Neutron
+	fetch func(ctx context.Context) (relay.ClientHealthInfo, error)
+}
+
+func NewMonitor(
+	neutronChain *relayer.Chain,
+	connection relay.ConnectionParamsProvider,
+	checkPeriod time.Duration,
+	expiryWarningPeriod time.Duration,
+	logger *zap.Logger,
+) *Monitor {
+	m := &Monitor{
+		health: relay.ClientHealthInfo{
+			ClientID: connection.ConnectionParams().NeutronClientID,
+			Status:   relay.ClientStatusUnknown,
+		},
+		neutronChain:        neutronChain,
+		connection:          connection,
+		connectionID:        neutronChain.PathEnd.ConnectionID,
+		checkPeriod:         checkPeriod,
+		expiryWarningPeriod: expiryWarningPeriod,
+		logger:              logger,
+	}
+	m.fetch = m.fetchHealth
+	return m
+}
+
+// ClientHealth returns the state of the client as of the latest check.
+func (m *Monitor) ClientHealth() relay.ClientHealthInfo {
+	m.mutex.RLock()
+	defer m.mutex.RUnlock()
+
+	return m.health
+}
+
+// Run checks the client every checkPeriod until ctx is done.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.checkPeriod)
+	defer ticker.Stop()
+
+	for {
+		m.check(ctx)
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			m.logger.Info("Context cancelled, shutting down client health Monitor...")
+			return
+		}
+	}
+}
+
+func (m *Monitor) check(ctx context.Context) {
+	health, err := m.fetch(ctx)
+	if err != nil {
+		if ctx.Err() != nil {
+			return
+		}
+		m.logger.Warn("failed to check client health", zap.String("client_id", m.health.ClientID), zap.Error(err))
+		// the latest known state is kept, there is no reason to pause or resume the relayer
+		m.mutex.Lock()
+		m.health.Error = err.Error()
+		m.mutex.Unlock()
+		return
+	}
+
+	m.mutex.Lock()
+	previous := m.health
+	m.health = health
+	m.mutex.Unlock()
+
+	neutronmetrics.SetClientStatus(m.connectionID, health.Status, clientStatuses)
+	neutronmetrics.SetClientExpiry(m.connectionID, float64(health.LatestConsensusTime.Unix()), health.TimeToExpiry.Seconds())
+
+	fields := []zap.Field{
+		zap.String("client_id", health.ClientID),
+		zap.String("status", health.Status),
+		zap.Uint64("latest_height", health.LatestHeight),
+		zap.Time("expiry_time", health.ExpiryTime),
+		zap.Duration("time_to_expiry", health.TimeToExpiry),
+	}
+	switch {
+	case !health.Healthy():
+		m.logger.Error("Neutron's light client of the target chain can't be used to verify proofs", fields...)
+	case health.TimeToExpiry < m.expiryWarningPeriod:
+		m.logger.Warn("Neutron's light client of the target chain is about to expire", fields...)
+	case health.Status != previous.Status:
+		m.logger.Info("Neutron's light client of the target chain status changed", fields...)
+	}
+}
+
+func (m *Monitor) fetchHealth(ctx context.Context) (relay.ClientHealthInfo, error) {
+	// Without this hack it doesn't want to work with NewQueryClient
+	neutronProvider, ok := m.neutronChain.ChainProvider.(*cosmos.CosmosProvider)
+	if !ok {
+		return relay.ClientHealthInfo{}, fmt.Errorf("failed to cast ChainProvider to concrete type (cosmos.CosmosProvider)")
+	}
+
+	clientID := m.connection.ConnectionParams().NeutronClientID
+	statusRes, err := clienttypes.NewQueryClient(neutronProvider).ClientStatus(ctx, &clienttypes.QueryClientStatusRequest{ClientId: clientID})
+	if err != nil {
+		return relay.ClientHealthInfo{}, fmt.Errorf("could not fetch client status for ClientId=%s: %w", clientID, err)
+	}
+
+	clientState, err := m.neutronChain.ChainProvider.QueryClientState(ctx, 0, clientID)
+	if err != nil {
+		return relay.ClientHealthInfo{}, fmt.Errorf("could not fetch client state for ClientId=%s: %w", clientID, err)
+	}
+	tmClientState, ok := clientState.(*tmclient.ClientState)
+	if !ok {
+		return relay.ClientHealthInfo{}, fmt.Errorf("expected client state of type *tmclient.ClientState, got %T", clientState)
+	}
+
+	csRes, err := m.neutronChain.ChainProvider.QueryClientConsensusState(ctx, 0, clientID, tmClientState.LatestHeight)
+	if err != nil {
+		return relay.ClientHealthInfo{}, fmt.Errorf("could not fetch latest consensus state for ClientId=%s: %w", clientID, err)
+	}
+	tmCS, ok := csRes.ConsensusState.GetCachedValue().(*tmclient.ConsensusState)
+	if !ok {
+		return relay.ClientHealthInfo{}, fmt.Errorf("expected consensus state of type *tmclient.ConsensusState, got %T", csRes.ConsensusState.GetCachedValue())
+	}
+
+	now := time.Now()
+	expiryTime := tmCS.Timestamp.Add(tmClientState.TrustingPeriod)
+	return relay.ClientHealthInfo{
+		ClientID:            clientID,
+		Status:              clientStatus(statusRes.Status),
+		LatestHeight:        tmClientState.LatestHeight.RevisionHeight,
+		LatestConsensusTime: tmCS.Timestamp,
+		ExpiryTime:          expiryTime,
+		TimeToExpiry:        expiryTime.Sub(now),
+		CheckTime:           now,
+	}, nil
+}
+
+// clientStatus converts the status reported by Neutron (exported.Status) to one of relay.ClientStatus*.
+func clientStatus(status string) string {
+	switch status := strings.ToLower(status); status {
+	case relay.ClientStatusActive, relay.ClientStatusExpired, relay.ClientStatusFrozen:
+		return status
+	default:
+		return relay.ClientStatusUnknown
+	}
+}
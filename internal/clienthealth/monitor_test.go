@@ -0,0 +1,104 @@
+package clienthealth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/cosmos/relayer/v2/relayer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+type staticConnection relay.ConnectionParams
+
+func (c staticConnection) ConnectionParams() relay.ConnectionParams {
+	return relay.ConnectionParams(c)
+}
+
+func newTestMonitor() *Monitor {
+	neutronChain := &relayer.Chain{PathEnd: &relayer.PathEnd{ConnectionID: "connection-0"}}
+	return NewMonitor(neutronChain, staticConnection{NeutronClientID: "07-tendermint-0"}, time.Minute, time.Hour, zap.NewNop())
+}
+
+func TestMonitorHealthChanges(t *testing.T) {
+	m := newTestMonitor()
+	assert.Equal(t, relay.ClientStatusUnknown, m.ClientHealth().Status)
+	assert.True(t, m.ClientHealth().Healthy(), "the relayer must not be paused before the first check")
+
+	steps := []struct {
+		name    string
+		status  string
+		err     error
+		want    string
+		healthy bool
+	}{
+		{name: "active client", status: relay.ClientStatusActive, want: relay.ClientStatusActive, healthy: true},
+		{name: "client expires", status: relay.ClientStatusExpired, want: relay.ClientStatusExpired},
+		{name: "failed check keeps the relayer paused", err: errors.New("rpc is down"), want: relay.ClientStatusExpired},
+		{name: "client is substituted", status: relay.ClientStatusActive, want: relay.ClientStatusActive, healthy: true},
+		{name: "failed check keeps the relayer running", err: errors.New("rpc is down"), want: relay.ClientStatusActive, healthy: true},
+		{name: "client is frozen", status: relay.ClientStatusFrozen, want: relay.ClientStatusFrozen},
+		{name: "unknown status doesn't pause the relayer", status: relay.ClientStatusUnknown, want: relay.ClientStatusUnknown, healthy: true},
+	}
+
+	// the steps change the state of the same monitor one after another
+	for _, step := range steps {
+		m.fetch = func(context.Context) (relay.ClientHealthInfo, error) {
+			if step.err != nil {
+				return relay.ClientHealthInfo{}, step.err
+			}
+			return relay.ClientHealthInfo{ClientID: "07-tendermint-0", Status: step.status, CheckTime: time.Now()}, nil
+		}
+		m.check(context.Background())
+
+		health := m.ClientHealth()
+		assert.Equal(t, step.want, health.Status, step.name)
+		assert.Equal(t, step.healthy, health.Healthy(), step.name)
+		if step.err != nil {
+			assert.Equal(t, step.err.Error(), health.Error, step.name)
+		} else {
+			assert.Empty(t, health.Error, step.name)
+		}
+	}
+}
+
+func TestMonitorRun(t *testing.T) {
+	m := newTestMonitor()
+	m.checkPeriod = 10 * time.Millisecond
+	checks := make(chan struct{}, 10)
+	m.fetch = func(context.Context) (relay.ClientHealthInfo, error) {
+		select {
+		case checks <- struct{}{}:
+		default:
+		}
+		return relay.ClientHealthInfo{Status: relay.ClientStatusExpired, CheckTime: time.Now()}, nil
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		m.Run(ctx)
+	}()
+
+	// the client is checked right away and then every check period
+	for i := 0; i < 2; i++ {
+		select {
+		case <-checks:
+		case <-time.After(5 * time.Second):
+			t.Fatal("client wasn't checked")
+		}
+	}
+	assert.False(t, m.ClientHealth().Healthy())
+
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("Monitor didn't stop after the context was cancelled")
+	}
+}
@@ -2,6 +2,7 @@ package config
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/kelseyhightower/envconfig"
@@ -13,20 +14,67 @@ import (
 type NeutronQueryRelayerConfig struct {
 	NeutronChain                *NeutronChainConfig      `split_words:"true"`
 	TargetChain                 *TargetChainConfig       `split_words:"true"`
+	Connections                 ConnectionsConfig        `split_words:"true"`
+	TxSources                   map[string]string        `split_words:"true"`
 	Registry                    *registry.RegistryConfig `split_words:"true"`
 	AllowTxQueries              bool                     `required:"true" split_words:"true"`
 	AllowKVCallbacks            bool                     `required:"true" split_words:"true"`
 	MinKvUpdatePeriod           uint64                   `split_words:"true" default:"0"`
+	KvBatchMaxQueries           int                      `split_words:"true" default:"10"`
+	KvBatchMaxGas               uint64                   `split_words:"true" default:"0"`
+	KvClientUpdatesCacheSize    int                      `split_words:"true" default:"1000"`
+	TrustedHeadersCacheSize     int                      `split_words:"true" default:"100"`
+	TrustedHeightsRefreshPeriod time.Duration            `split_words:"true" default:"5m"`
+	ClientHealthCheckPeriod     time.Duration            `split_words:"true" default:"1m"`
+	ClientExpiryWarningPeriod   time.Duration            `split_words:"true" default:"24h"`
+	ClientKeepAliveThreshold    float64                  `split_words:"true" default:"0"`
+	RevisionCheckPeriod         time.Duration            `split_words:"true" default:"1m"`
+	ConnectionCheckPeriod       time.Duration            `split_words:"true" default:"5m"`
+	SubscriberMode              string                   `split_words:"true" default:"websocket"`
+	SubscriberBlockPollPeriod   time.Duration            `split_words:"true" default:"1s"`
+	SubscriberQueriesPollPeriod time.Duration            `split_words:"true" default:"30s"`
+	SubscriberHeartbeatTimeout  time.Duration            `split_words:"true" default:"1m"`
+	SubscriberReconcilePeriod   time.Duration            `split_words:"true" default:"10m"`
+	SubscriberMaxReconnectDelay time.Duration            `split_words:"true" default:"1m"`
 	StoragePath                 string                   `required:"true" split_words:"true"`
 	CheckSubmittedTxStatusDelay time.Duration            `split_words:"true" default:"10s"`
 	QueriesTaskQueueCapacity    int                      `split_words:"true" default:"10000"`
+	OwnerWeights                map[string]float64       `split_words:"true"`
 	InitialTxSearchOffset       uint64                   `split_words:"true" default:"0"`
+	TxBlockResultsCacheSize     int                      `split_words:"true" default:"100"`
+	TxProofWorkers              int                      `split_words:"true" default:"4"`
 	ListenAddr                  string                   `split_words:"true" default:"127.0.0.1:9999"`
 	IgnoreErrorsRegex           string                   `split_words:"true" default:"(execute wasm contract failed|failed to build tx query string)"`
+	WorkerPoolSize              int                      `split_words:"true" default:"1"`
+	QueryFailureBackoff         time.Duration            `split_words:"true" default:"10s"`
+	QueryFailureMaxBackoff      time.Duration            `split_words:"true" default:"30m"`
+	QueryQuarantineThreshold    uint64                   `split_words:"true" default:"0"`
+	DryRun                      bool                     `split_words:"true" default:"false"`
+	DryRunResultsCapacity       int                      `split_words:"true" default:"1000"`
+	ShutdownGracePeriod         time.Duration            `split_words:"true" default:"30s"`
+	CriticalTxErrorPolicy       string                   `split_words:"true" default:"exit"`
 }
 
 const EnvPrefix string = "RELAYER"
 
+// Policies of handling TX submission errors which don't match IgnoreErrorsRegex.
+const (
+	// CriticalTxErrorPolicyExit stops the relayer.
+	CriticalTxErrorPolicyExit = "exit"
+	// CriticalTxErrorPolicyPause puts the offending query to quarantine.
+	CriticalTxErrorPolicyPause = "pause"
+	// CriticalTxErrorPolicyMark saves the tx with the ErrorOnSubmit status and goes on.
+	CriticalTxErrorPolicyMark = "mark"
+)
+
+// Modes of the Subscriber watching Neutron for new blocks and registered queries changes.
+const (
+	// SubscriberModeWebsocket subscribes to Neutron events over the rpc websocket.
+	SubscriberModeWebsocket = "websocket"
+	// SubscriberModePolling polls the rpc status and the registered queries, for rpc nodes without websocket.
+	SubscriberModePolling = "polling"
+)
+
 type NeutronChainConfig struct {
 	RPCAddr        string        `required:"true" split_words:"true"`
 	RESTAddr       string        `required:"true" split_words:"true"`
@@ -43,11 +91,55 @@ type NeutronChainConfig struct {
 	SignModeStr    string        `split_words:"true" default:"direct"`
 }
 
+// Sources of the target chain transactions for TX queries.
+const (
+	// TxSourceIndexer searches transactions with tx_search, requires the tx indexer on the target node.
+	TxSourceIndexer = "indexer"
+	// TxSourceBlocks scans blocks and matches the transactions locally.
+	TxSourceBlocks = "blocks"
+)
+
 type TargetChainConfig struct {
 	RPCAddr      string        `required:"true" split_words:"true"`
 	Timeout      time.Duration `split_words:"true" default:"10s"`
 	Debug        bool          `split_words:"true" default:"false"`
 	OutputFormat string        `split_words:"true" default:"json"`
+	TxSource     string        `split_words:"true" default:"indexer"`
+}
+
+// ConnectionConfig describes an additional Neutron connection served by the relayer.
+type ConnectionConfig struct {
+	// ConnectionID is the Neutron's side connection ID.
+	ConnectionID string
+	// TargetRPCAddr is the rpc address of the target chain on the other side of the connection.
+	TargetRPCAddr string
+}
+
+// ConnectionsConfig is a list of additional connections in the `connection_id=target_rpc_addr,...` format.
+type ConnectionsConfig []ConnectionConfig
+
+// Decode implements envconfig.Decoder. The standard map format can't be used here because rpc
+// addresses contain colons.
+func (c *ConnectionsConfig) Decode(value string) error {
+	connections := make(ConnectionsConfig, 0)
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+
+		kv := strings.SplitN(pair, "=", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" || strings.TrimSpace(kv[1]) == "" {
+			return fmt.Errorf("invalid connection item %q, expected connection_id=target_rpc_addr", pair)
+		}
+
+		connections = append(connections, ConnectionConfig{
+			ConnectionID:  strings.TrimSpace(kv[0]),
+			TargetRPCAddr: strings.TrimSpace(kv[1]),
+		})
+	}
+
+	*c = connections
+	return nil
 }
 
 func NewNeutronQueryRelayerConfig() (NeutronQueryRelayerConfig, error) {
@@ -58,5 +150,82 @@ func NewNeutronQueryRelayerConfig() (NeutronQueryRelayerConfig, error) {
 		return cfg, fmt.Errorf("could not read config from env: %w", err)
 	}
 
+	switch cfg.CriticalTxErrorPolicy {
+	case CriticalTxErrorPolicyExit, CriticalTxErrorPolicyPause, CriticalTxErrorPolicyMark:
+	default:
+		return cfg, fmt.Errorf("unknown critical tx error policy %q, expected one of: %s, %s, %s", cfg.CriticalTxErrorPolicy,
+			CriticalTxErrorPolicyExit, CriticalTxErrorPolicyPause, CriticalTxErrorPolicyMark)
+	}
+
+	switch cfg.SubscriberMode {
+	case SubscriberModeWebsocket, SubscriberModePolling:
+	default:
+		return cfg, fmt.Errorf("unknown subscriber mode %q, expected one of: %s, %s", cfg.SubscriberMode,
+			SubscriberModeWebsocket, SubscriberModePolling)
+	}
+
+	if cfg.ClientKeepAliveThreshold < 0 || cfg.ClientKeepAliveThreshold >= 1 {
+		return cfg, fmt.Errorf("client keep-alive threshold must be in [0, 1), got %v", cfg.ClientKeepAliveThreshold)
+	}
+
+	seen := map[string]struct{}{cfg.NeutronChain.ConnectionID: {}}
+	for _, conn := range cfg.Connections {
+		if _, ok := seen[conn.ConnectionID]; ok {
+			return cfg, fmt.Errorf("connection %s is configured more than once", conn.ConnectionID)
+		}
+		seen[conn.ConnectionID] = struct{}{}
+	}
+
+	if err := validateTxSource(cfg.TargetChain.TxSource); err != nil {
+		return cfg, err
+	}
+	for connectionID, txSource := range cfg.TxSources {
+		if _, ok := seen[connectionID]; !ok {
+			return cfg, fmt.Errorf("tx source is configured for unknown connection %s", connectionID)
+		}
+		if err := validateTxSource(txSource); err != nil {
+			return cfg, err
+		}
+	}
+
 	return cfg, nil
 }
+
+func validateTxSource(txSource string) error {
+	switch txSource {
+	case TxSourceIndexer, TxSourceBlocks:
+		return nil
+	default:
+		return fmt.Errorf("unknown tx source %q, expected one of: %s, %s", txSource, TxSourceIndexer, TxSourceBlocks)
+	}
+}
+
+// AllConnections returns all the connections served by the relayer. The first one is always the
+// primary connection configured with RELAYER_NEUTRON_CHAIN_CONNECTION_ID and RELAYER_TARGET_CHAIN_RPC_ADDR.
+func (cfg NeutronQueryRelayerConfig) AllConnections() []ConnectionConfig {
+	connections := []ConnectionConfig{{
+		ConnectionID:  cfg.NeutronChain.ConnectionID,
+		TargetRPCAddr: cfg.TargetChain.RPCAddr,
+	}}
+	return append(connections, cfg.Connections...)
+}
+
+// ForConnection returns a copy of the config in which the Neutron connection ID and the target
+// chain rpc address are replaced with the ones of the given connection. The tx source is taken from
+// RELAYER_TX_SOURCES if it's set for the connection. The rest of the target chain settings are shared
+// by all the connections.
+func (cfg NeutronQueryRelayerConfig) ForConnection(conn ConnectionConfig) NeutronQueryRelayerConfig {
+	neutronChain := *cfg.NeutronChain
+	neutronChain.ConnectionID = conn.ConnectionID
+	targetChain := *cfg.TargetChain
+	targetChain.RPCAddr = conn.TargetRPCAddr
+	if txSource, ok := cfg.TxSources[conn.ConnectionID]; ok {
+		targetChain.TxSource = txSource
+	}
+
+	cfg.NeutronChain = &neutronChain
+	cfg.TargetChain = &targetChain
+	cfg.Connections = nil
+	cfg.TxSources = nil
+	return cfg
+}
@@ -0,0 +1,122 @@
+package connection
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	restclient "github.com/neutron-org/neutron-query-relayer/internal/subscriber/querier/client"
+	"github.com/neutron-org/neutron-query-relayer/internal/subscriber/querier/client/query"
+)
+
+// Watcher is an implementation of relay.ConnectionParamsProvider which periodically re-checks the IBC connection
+// on Neutron, so the relayer switches to the new clients and counterparty connection without a restart.
+type Watcher struct {
+	mutex        sync.RWMutex
+	params       relay.ConnectionParams
+	restClient   *restclient.HTTPAPIConsole
+	connectionID string
+	checkPeriod  time.Duration
+	logger       *zap.Logger
+}
+
+// NewWatcher constructs a Watcher of the given Neutron's connection starting with the params it's loaded with.
+func NewWatcher(
+	restClient *restclient.HTTPAPIConsole,
+	connectionID string,
+	params relay.ConnectionParams,
+	checkPeriod time.Duration,
+	logger *zap.Logger,
+) *Watcher {
+	return &Watcher{
+		params:       params,
+		restClient:   restClient,
+		connectionID: connectionID,
+		checkPeriod:  checkPeriod,
+		logger:       logger,
+	}
+}
+
+// ConnectionParams returns the params of the connection as of the latest check.
+func (w *Watcher) ConnectionParams() relay.ConnectionParams {
+	w.mutex.RLock()
+	defer w.mutex.RUnlock()
+
+	return w.params
+}
+
+// Run re-checks the connection every checkPeriod until ctx is done.
+func (w *Watcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.checkPeriod)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if err := w.check(ctx); err != nil && ctx.Err() == nil {
+				w.logger.Warn("failed to check connection params", zap.String("connection_id", w.connectionID), zap.Error(err))
+			}
+		case <-ctx.Done():
+			w.logger.Info("Context cancelled, shutting down connection Watcher...")
+			return
+		}
+	}
+}
+
+func (w *Watcher) check(ctx context.Context) error {
+	params, err := FetchParams(ctx, w.restClient, w.connectionID)
+	if err != nil {
+		return err
+	}
+
+	w.mutex.Lock()
+	previous := w.params
+	w.params = params
+	w.mutex.Unlock()
+
+	if params != previous {
+		neutronmetrics.IncConnectionParamsSwitches(w.connectionID)
+		w.logger.Warn("connection params changed, switched to the new ones",
+			zap.String("connection_id", w.connectionID),
+			zap.String("previous_neutron_client_id", previous.NeutronClientID),
+			zap.String("neutron_client_id", params.NeutronClientID),
+			zap.String("previous_target_client_id", previous.TargetClientID),
+			zap.String("target_client_id", params.TargetClientID),
+			zap.String("previous_target_connection_id", previous.TargetConnectionID),
+			zap.String("target_connection_id", params.TargetConnectionID))
+	}
+	return nil
+}
+
+// FetchParams fetches the client of the given Neutron's connection and its counterparty from Neutron.
+func FetchParams(ctx context.Context, restClient *restclient.HTTPAPIConsole, connectionID string) (relay.ConnectionParams, error) {
+	res, err := restClient.Query.IbcCoreConnectionV1Connection(&query.IbcCoreConnectionV1ConnectionParams{
+		ConnectionID: connectionID,
+		Context:      ctx,
+	})
+	if err != nil {
+		return relay.ConnectionParams{}, fmt.Errorf("failed to query ibc connection info: %w", err)
+	}
+
+	connection := res.GetPayload().Connection
+	if connection == nil || connection.Counterparty == nil {
+		return relay.ConnectionParams{}, fmt.Errorf("empty connection info")
+	}
+	if connection.Counterparty.ConnectionID == "" {
+		return relay.ConnectionParams{}, fmt.Errorf("empty target connection ID")
+	}
+	if connection.Counterparty.ClientID == "" {
+		return relay.ConnectionParams{}, fmt.Errorf("empty target client ID")
+	}
+
+	return relay.ConnectionParams{
+		NeutronClientID:    connection.ClientID,
+		TargetClientID:     connection.Counterparty.ClientID,
+		TargetConnectionID: connection.Counterparty.ConnectionID,
+	}, nil
+}
@@ -0,0 +1,142 @@
+package connection_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/connection"
+	"github.com/neutron-org/neutron-query-relayer/internal/raw"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+// neutronREST serves the connection-0 of Neutron with the current params.
+type neutronREST struct {
+	mutex  sync.Mutex
+	params relay.ConnectionParams
+	// fail makes the requests fail
+	fail bool
+}
+
+func (n *neutronREST) set(params relay.ConnectionParams, fail bool) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	n.params = params
+	n.fail = fail
+}
+
+func (n *neutronREST) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	n.mutex.Lock()
+	defer n.mutex.Unlock()
+
+	if n.fail || r.URL.Path != "/ibc/core/connection/v1/connections/connection-0" {
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	_ = json.NewEncoder(w).Encode(map[string]any{
+		"connection": map[string]any{
+			"client_id": n.params.NeutronClientID,
+			"counterparty": map[string]any{
+				"client_id":     n.params.TargetClientID,
+				"connection_id": n.params.TargetConnectionID,
+			},
+		},
+	})
+}
+
+func TestWatcherSwitchesToChangedParams(t *testing.T) {
+	initial := relay.ConnectionParams{
+		NeutronClientID:    "07-tendermint-0",
+		TargetClientID:     "07-tendermint-10",
+		TargetConnectionID: "connection-10",
+	}
+	rest := &neutronREST{params: initial}
+	server := httptest.NewServer(rest)
+	defer server.Close()
+
+	restClient, err := raw.NewRESTClient(server.URL)
+	require.NoError(t, err)
+	watcher := connection.NewWatcher(restClient, "connection-0", initial, 10*time.Millisecond, zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go watcher.Run(ctx)
+
+	steps := []struct {
+		name   string
+		params relay.ConnectionParams
+		fail   bool
+		want   relay.ConnectionParams
+	}{
+		{
+			name: "Neutron's client is substituted",
+			params: relay.ConnectionParams{
+				NeutronClientID:    "07-tendermint-1",
+				TargetClientID:     "07-tendermint-10",
+				TargetConnectionID: "connection-10",
+			},
+			want: relay.ConnectionParams{
+				NeutronClientID:    "07-tendermint-1",
+				TargetClientID:     "07-tendermint-10",
+				TargetConnectionID: "connection-10",
+			},
+		},
+		{
+			name: "counterparty client and connection change",
+			params: relay.ConnectionParams{
+				NeutronClientID:    "07-tendermint-1",
+				TargetClientID:     "07-tendermint-11",
+				TargetConnectionID: "connection-11",
+			},
+			want: relay.ConnectionParams{
+				NeutronClientID:    "07-tendermint-1",
+				TargetClientID:     "07-tendermint-11",
+				TargetConnectionID: "connection-11",
+			},
+		},
+		{
+			name: "failed check keeps the current params",
+			fail: true,
+			want: relay.ConnectionParams{
+				NeutronClientID:    "07-tendermint-1",
+				TargetClientID:     "07-tendermint-11",
+				TargetConnectionID: "connection-11",
+			},
+		},
+		{
+			name: "incomplete connection info keeps the current params",
+			params: relay.ConnectionParams{
+				NeutronClientID: "07-tendermint-2",
+				TargetClientID:  "07-tendermint-12",
+			},
+			want: relay.ConnectionParams{
+				NeutronClientID:    "07-tendermint-1",
+				TargetClientID:     "07-tendermint-11",
+				TargetConnectionID: "connection-11",
+			},
+		},
+	}
+
+	for _, step := range steps {
+		rest.set(step.params, step.fail)
+		if step.want == watcher.ConnectionParams() {
+			// the params must not change over a few checks
+			time.Sleep(50 * time.Millisecond)
+			assert.Equal(t, step.want, watcher.ConnectionParams(), step.name)
+			continue
+		}
+		assert.Eventually(t, func() bool { return watcher.ConnectionParams() == step.want }, 5*time.Second,
+			10*time.Millisecond, step.name)
+	}
+}
@@ -0,0 +1,56 @@
+package dryrun
+
+import (
+	"sync"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+// Recorder is an in-memory implementation of relay.DryRunRecorder which keeps a limited number of
+// the latest results in a ring buffer.
+type Recorder struct {
+	mutex   sync.Mutex
+	results []relay.DryRunResult
+	next    int
+	full    bool
+}
+
+// NewRecorder creates a new Recorder which keeps at most capacity results.
+func NewRecorder(capacity int) *Recorder {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &Recorder{results: make([]relay.DryRunResult, capacity)}
+}
+
+// Record saves the result evicting the oldest one if the buffer is full.
+func (r *Recorder) Record(result relay.DryRunResult) {
+	metrics.AddDryRunTx(result.ConnectionID, result.Error == "", result.GasWanted)
+
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	r.results[r.next] = result
+	r.next = (r.next + 1) % len(r.results)
+	if r.next == 0 {
+		r.full = true
+	}
+}
+
+// Results returns the saved results from the oldest to the newest one.
+func (r *Recorder) Results() []relay.DryRunResult {
+	r.mutex.Lock()
+	defer r.mutex.Unlock()
+
+	if !r.full {
+		out := make([]relay.DryRunResult, r.next)
+		copy(out, r.results[:r.next])
+		return out
+	}
+
+	out := make([]relay.DryRunResult, 0, len(r.results))
+	out = append(out, r.results[r.next:]...)
+	return append(out, r.results[:r.next]...)
+}
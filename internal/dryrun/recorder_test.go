@@ -0,0 +1,81 @@
+package dryrun_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/dryrun"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+func TestRecorder(t *testing.T) {
+	hashes := func(results []relay.DryRunResult) []string {
+		out := make([]string, 0, len(results))
+		for _, result := range results {
+			out = append(out, result.TxHash)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name     string
+		capacity int
+		recorded []string
+		expected []string
+	}{
+		{
+			name:     "empty",
+			capacity: 3,
+			expected: []string{},
+		},
+		{
+			name:     "not full",
+			capacity: 3,
+			recorded: []string{"a", "b"},
+			expected: []string{"a", "b"},
+		},
+		{
+			name:     "full",
+			capacity: 3,
+			recorded: []string{"a", "b", "c"},
+			expected: []string{"a", "b", "c"},
+		},
+		{
+			name:     "oldest results are evicted",
+			capacity: 3,
+			recorded: []string{"a", "b", "c", "d", "e"},
+			expected: []string{"c", "d", "e"},
+		},
+		{
+			name:     "evicted more than once",
+			capacity: 2,
+			recorded: []string{"a", "b", "c", "d", "e", "f", "g"},
+			expected: []string{"f", "g"},
+		},
+		{
+			name:     "capacity is at least one",
+			capacity: 0,
+			recorded: []string{"a", "b"},
+			expected: []string{"b"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := dryrun.NewRecorder(tt.capacity)
+			for _, hash := range tt.recorded {
+				recorder.Record(relay.DryRunResult{TxHash: hash, ConnectionID: "connection-0"})
+			}
+
+			results := recorder.Results()
+			assert.Equal(t, tt.expected, hashes(results))
+
+			// the returned results don't share the memory with the buffer
+			if len(results) > 0 {
+				results[0].TxHash = "changed"
+				assert.Equal(t, tt.expected, hashes(recorder.Results()))
+			}
+		})
+	}
+}
@@ -65,6 +65,259 @@ func (c ICQClient) GetUnsuccessfulTxs() ([]relay.UnsuccessfulTxInfo, error) {
 	return txs, nil
 }
 
+func (c ICQClient) GetTasksQueue() ([]relay.TaskInfo, error) {
+	u := *c.host
+	u.Path = TasksQueueResource
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+	tasks := make([]relay.TaskInfo, 0)
+
+	decoder := json.NewDecoder(res.Body)
+	err = decoder.Decode(&tasks)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return tasks, nil
+}
+
+func (c ICQClient) GetQuarantinedQueries() ([]relay.QueryFailureInfo, error) {
+	u := *c.host
+	u.Path = QuarantinedQueries
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+	queries := make([]relay.QueryFailureInfo, 0)
+
+	decoder := json.NewDecoder(res.Body)
+	err = decoder.Decode(&queries)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return queries, nil
+}
+
+func (c ICQClient) GetCriticalTxErrors() ([]relay.CriticalTxErrorInfo, error) {
+	u := *c.host
+	u.Path = CriticalTxErrors
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+	errs := make([]relay.CriticalTxErrorInfo, 0)
+
+	decoder := json.NewDecoder(res.Body)
+	err = decoder.Decode(&errs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return errs, nil
+}
+
+func (c ICQClient) ReleaseQueries(queries ReleaseQueriesRequest) error {
+	u := *c.host
+	u.Path = ReleaseQueries
+	body := bytes.Buffer{}
+	encoder := json.NewEncoder(&body)
+	err := encoder.Encode(queries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal query ids: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), &body)
+	if err != nil {
+		return fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 400 {
+		errBody := bytes.Buffer{}
+		_, err = errBody.ReadFrom(res.Body)
+		if err != nil {
+			return fmt.Errorf("failed to read response(code 400) body: %w", err)
+		}
+		return fmt.Errorf(errBody.String())
+	} else if res.StatusCode != 200 {
+		return fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+
+	return nil
+}
+
+func (c ICQClient) Backfill(backfill BackfillRequest) (relay.BackfillInfo, error) {
+	u := *c.host
+	u.Path = Backfill
+	body := bytes.Buffer{}
+	encoder := json.NewEncoder(&body)
+	err := encoder.Encode(backfill)
+	if err != nil {
+		return relay.BackfillInfo{}, fmt.Errorf("failed to marshal backfill request: %w", err)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, u.String(), &body)
+	if err != nil {
+		return relay.BackfillInfo{}, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return relay.BackfillInfo{}, fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 400 {
+		errBody := bytes.Buffer{}
+		_, err = errBody.ReadFrom(res.Body)
+		if err != nil {
+			return relay.BackfillInfo{}, fmt.Errorf("failed to read response(code 400) body: %w", err)
+		}
+		return relay.BackfillInfo{}, fmt.Errorf(errBody.String())
+	} else if res.StatusCode != 200 {
+		return relay.BackfillInfo{}, fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+
+	info := relay.BackfillInfo{}
+	decoder := json.NewDecoder(res.Body)
+	err = decoder.Decode(&info)
+	if err != nil {
+		return relay.BackfillInfo{}, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return info, nil
+}
+
+func (c ICQClient) GetBackfills() ([]relay.BackfillInfo, error) {
+	u := *c.host
+	u.Path = BackfillsResource
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+	jobs := make([]relay.BackfillInfo, 0)
+
+	decoder := json.NewDecoder(res.Body)
+	err = decoder.Decode(&jobs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return jobs, nil
+}
+
+func (c ICQClient) GetClientHealth() ([]relay.ClientHealthInfo, error) {
+	u := *c.host
+	u.Path = ClientHealthResource
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != 200 {
+		return nil, fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+	clients := make([]relay.ClientHealthInfo, 0)
+
+	decoder := json.NewDecoder(res.Body)
+	err = decoder.Decode(&clients)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return clients, nil
+}
+
+func (c ICQClient) GetDryRunResults() ([]relay.DryRunResult, error) {
+	u := *c.host
+	u.Path = DryRunResultsResource
+
+	req, err := http.NewRequest(http.MethodGet, u.String(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to build http request: %w", err)
+	}
+
+	res, err := c.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to make http request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode == 404 {
+		return nil, fmt.Errorf("dry run mode is disabled")
+	} else if res.StatusCode != 200 {
+		return nil, fmt.Errorf("got unexpected http response status code: %d", res.StatusCode)
+	}
+	results := make([]relay.DryRunResult, 0)
+
+	decoder := json.NewDecoder(res.Body)
+	err = decoder.Decode(&results)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode response body: %w", err)
+	}
+
+	return results, nil
+}
+
 func (c ICQClient) ResubmitTxs(txs ResubmitRequest) error {
 	u := *c.host
 	u.Path = ResubmitTxs
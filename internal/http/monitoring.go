@@ -8,7 +8,6 @@ import (
 	"github.com/prometheus/client_golang/prometheus/promhttp"
 
 	nlogger "github.com/neutron-org/neutron-logger"
-	"github.com/neutron-org/neutron-query-relayer/internal/relay"
 
 	"go.uber.org/zap"
 )
@@ -17,27 +16,38 @@ const MonitoringLoggerContext = "monitoring"
 
 type PromWrapper struct {
 	promHandler http.Handler
-	storage     relay.Storage
+	connections []Connection
 	logger      *zap.Logger
 }
 
-func NewPromWrapper(logRegistry *nlogger.Registry, storage relay.Storage) PromWrapper {
+func NewPromWrapper(logRegistry *nlogger.Registry, connections []Connection) PromWrapper {
 	return PromWrapper{
 		promHandler: promhttp.Handler(),
-		storage:     storage,
+		connections: connections,
 		logger:      logRegistry.Get(MonitoringLoggerContext),
 	}
 }
 
-func (p PromWrapper) fillUnsuccessfulTxsMetric() {
-	txs, err := p.storage.GetAllUnsuccessfulTxs()
+func (p PromWrapper) fillUnsuccessfulTxsMetric(conn Connection) {
+	txs, err := conn.Storage.GetAllUnsuccessfulTxs()
 	if err != nil {
-		p.logger.Error("failed to get unsuccessful txs from storage", zap.Error(err))
+		p.logger.Error("failed to get unsuccessful txs from storage", zap.String("connection_id", conn.ID), zap.Error(err))
 	}
-	metrics.SetUnsuccessfulTxsSizeQueue(len(txs))
+	metrics.SetUnsuccessfulTxsSizeQueue(conn.ID, len(txs))
+}
+
+func (p PromWrapper) fillQuarantinedQueriesMetric(conn Connection) {
+	queries, err := conn.Storage.GetAllQuarantinedQueries()
+	if err != nil {
+		p.logger.Error("failed to get quarantined queries from storage", zap.String("connection_id", conn.ID), zap.Error(err))
+	}
+	metrics.SetQuarantinedQueries(conn.ID, len(queries))
 }
 
 func (p PromWrapper) ServeHTTP(res http.ResponseWriter, req *http.Request) {
-	p.fillUnsuccessfulTxsMetric()
+	for _, conn := range p.connections {
+		p.fillUnsuccessfulTxsMetric(conn)
+		p.fillQuarantinedQueriesMetric(conn)
+	}
 	p.promHandler.ServeHTTP(res, req)
 }
@@ -23,22 +23,67 @@ const (
 	ServerContext           = "http"
 	UnsuccessfulTxsResource = "/unsuccessful-txs"
 	ResubmitTxs             = "/resubmit-txs"
+	TasksQueueResource      = "/tasks-queue"
+	QuarantinedQueries      = "/quarantined-queries"
+	ReleaseQueries          = "/release-queries"
+	CriticalTxErrors        = "/critical-tx-errors"
+	DryRunResultsResource   = "/dry-run-results"
+	Backfill                = "/backfill"
+	BackfillsResource       = "/backfills"
+	ClientHealthResource    = "/client-health"
+	HealthResource          = "/health"
 	PrometheusMetrics       = "/metrics"
 )
 
 type ResubmitTx struct {
-	QueryID uint64 `json:"query_id"`
-	Hash    string `json:"hash"`
+	// ConnectionID is the connection of the query, the primary connection is used if it's empty
+	ConnectionID string `json:"connection_id,omitempty"`
+	QueryID      uint64 `json:"query_id"`
+	Hash         string `json:"hash"`
 }
 
+// HealthResponse is the status of the relayer: "ok" while it's running and "draining" while it's shutting down.
+type HealthResponse struct {
+	Status string `json:"status"`
+}
+
+const (
+	StatusOK       = "ok"
+	StatusDraining = "draining"
+)
+
 type ResubmitRequest struct {
 	Txs []ResubmitTx `json:"txs"`
 }
 
-func Run(ctx context.Context, logRegistry *nlogger.Registry, storage relay.Storage, txProcessor relay.TXProcessor, submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo, ListenAddr string) error {
+type ReleaseQueriesRequest struct {
+	QueryIDs []uint64 `json:"query_ids"`
+}
+
+type BackfillRequest struct {
+	// ConnectionID is the connection of the query, the primary connection is used if it's empty
+	ConnectionID string `json:"connection_id,omitempty"`
+	relay.BackfillRequest
+}
+
+// Connection contains the dependencies of a single connection served by the api.
+type Connection struct {
+	ID                     string
+	Storage                relay.Storage
+	TxProcessor            relay.TXProcessor
+	SubmittedTxsTasksQueue chan relay.PendingSubmittedTxInfo
+	TasksQueue             relay.TaskQueue
+	Backfiller             relay.Backfiller
+	ClientHealth           relay.ClientHealthChecker
+}
+
+// Run serves the api for the given connections. The first connection is the primary one, it's used
+// for requests which don't specify a connection. dryRunRecorder is nil unless the relayer runs in the dry run mode.
+// The server keeps running until ctx is done, the relayer is reported as draining once the draining channel is closed.
+func Run(ctx context.Context, logRegistry *nlogger.Registry, connections []Connection, dryRunRecorder relay.DryRunRecorder, draining <-chan struct{}, ListenAddr string) error {
 	server := &http.Server{
 		Addr:    ListenAddr,
-		Handler: Router(logRegistry, storage, txProcessor, submittedTxsTasksQueue),
+		Handler: Router(logRegistry, connections, dryRunRecorder, draining),
 	}
 	logger := logRegistry.Get(ServerContext)
 	errch := make(chan error)
@@ -70,35 +115,319 @@ func Run(ctx context.Context, logRegistry *nlogger.Registry, storage relay.Stora
 	return nil
 }
 
-func Router(logRegistry *nlogger.Registry, storage relay.Storage, txProcessor relay.TXProcessor, submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo) *mux.Router {
-	promHandler := NewPromWrapper(logRegistry, storage)
+func Router(logRegistry *nlogger.Registry, connections []Connection, dryRunRecorder relay.DryRunRecorder, draining <-chan struct{}) *mux.Router {
+	promHandler := NewPromWrapper(logRegistry, connections)
 	router := mux.NewRouter().StrictSlash(true)
-	router.HandleFunc(UnsuccessfulTxsResource, unsuccessfulTxs(logRegistry.Get(ServerContext), storage))
-	router.HandleFunc(ResubmitTxs, resubmitFailedTxs(logRegistry.Get(ServerContext), storage, txProcessor, submittedTxsTasksQueue)).Methods(http.MethodPost)
+	router.HandleFunc(UnsuccessfulTxsResource, unsuccessfulTxs(logRegistry.Get(ServerContext), connections))
+	router.HandleFunc(ResubmitTxs, resubmitFailedTxs(logRegistry.Get(ServerContext), connections)).Methods(http.MethodPost)
+	router.HandleFunc(TasksQueueResource, queuedTasks(logRegistry.Get(ServerContext), connections)).Methods(http.MethodGet)
+	router.HandleFunc(QuarantinedQueries, quarantinedQueries(logRegistry.Get(ServerContext), connections)).Methods(http.MethodGet)
+	router.HandleFunc(CriticalTxErrors, criticalTxErrors(logRegistry.Get(ServerContext), connections)).Methods(http.MethodGet)
+	router.HandleFunc(ReleaseQueries, releaseQueries(logRegistry.Get(ServerContext), connections)).Methods(http.MethodPost)
+	router.HandleFunc(Backfill, backfill(logRegistry.Get(ServerContext), connections)).Methods(http.MethodPost)
+	router.HandleFunc(BackfillsResource, backfills(logRegistry.Get(ServerContext), connections)).Methods(http.MethodGet)
+	router.HandleFunc(ClientHealthResource, clientHealth(logRegistry.Get(ServerContext), connections)).Methods(http.MethodGet)
+	router.HandleFunc(DryRunResultsResource, dryRunResults(logRegistry.Get(ServerContext), dryRunRecorder)).Methods(http.MethodGet)
+	router.HandleFunc(HealthResource, health(logRegistry.Get(ServerContext), draining)).Methods(http.MethodGet)
 	router.Handle(PrometheusMetrics, promHandler)
 	return router
 }
 
-func unsuccessfulTxs(logger *zap.Logger, storage relay.Storage) http.HandlerFunc {
+// findConnection returns the connection with the given ID or the primary connection if the ID is empty.
+func findConnection(connections []Connection, connectionID string) (Connection, bool) {
+	if connectionID == "" && len(connections) > 0 {
+		return connections[0], true
+	}
+
+	for _, conn := range connections {
+		if conn.ID == connectionID {
+			return conn, true
+		}
+	}
+	return Connection{}, false
+}
+
+func unsuccessfulTxs(logger *zap.Logger, connections []Connection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		// use `make` to avoid printing empty value in json as `null`
+		res := make([]*relay.UnsuccessfulTxInfo, 0)
+		for _, conn := range connections {
+			txs, err := conn.Storage.GetAllUnsuccessfulTxs()
+			if err != nil {
+				logger.Error("failed to execute GetAllUnsuccessfulTxs", zap.String("connection_id", conn.ID), zap.Error(err))
+				http.Error(w, "Error processing request", http.StatusInternalServerError)
+				return
+			}
+
+			for _, tx := range txs {
+				tx.ConnectionID = conn.ID
+			}
+			res = append(res, txs...)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(res)
+		if err != nil {
+			logger.Error("failed to encode result of GetAllUnsuccessfulTxs", zap.Error(err))
+			http.Error(w, "Error processing request", http.StatusInternalServerError)
+		}
+	}
+}
+
+func queuedTasks(logger *zap.Logger, connections []Connection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := make([]relay.TaskInfo, 0)
+		for _, conn := range connections {
+			res = append(res, conn.TasksQueue.Tasks()...)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(res)
+		if err != nil {
+			logger.Error("failed to encode queued tasks", zap.Error(err))
+			http.Error(w, "Error processing request", http.StatusInternalServerError)
+		}
+	}
+}
+
+func quarantinedQueries(logger *zap.Logger, connections []Connection) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
-		res, err := storage.GetAllUnsuccessfulTxs()
+		res := make([]*relay.QueryFailureInfo, 0)
+		for _, conn := range connections {
+			queries, err := conn.Storage.GetAllQuarantinedQueries()
+			if err != nil {
+				logger.Error("failed to execute GetAllQuarantinedQueries", zap.String("connection_id", conn.ID), zap.Error(err))
+				http.Error(w, "Error processing request", http.StatusInternalServerError)
+				return
+			}
+
+			for _, query := range queries {
+				query.ConnectionID = conn.ID
+			}
+			res = append(res, queries...)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(res)
 		if err != nil {
-			logger.Error("failed to execute GetAllUnsuccessfulTxs", zap.Error(err))
+			logger.Error("failed to encode result of GetAllQuarantinedQueries", zap.Error(err))
 			http.Error(w, "Error processing request", http.StatusInternalServerError)
+		}
+	}
+}
+
+func criticalTxErrors(logger *zap.Logger, connections []Connection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := make([]*relay.CriticalTxErrorInfo, 0)
+		for _, conn := range connections {
+			errs, err := conn.Storage.GetAllCriticalTxErrors()
+			if err != nil {
+				logger.Error("failed to execute GetAllCriticalTxErrors", zap.String("connection_id", conn.ID), zap.Error(err))
+				http.Error(w, "Error processing request", http.StatusInternalServerError)
+				return
+			}
+
+			for _, errInfo := range errs {
+				errInfo.ConnectionID = conn.ID
+			}
+			res = append(res, errs...)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(res)
+		if err != nil {
+			logger.Error("failed to encode result of GetAllCriticalTxErrors", zap.Error(err))
+			http.Error(w, "Error processing request", http.StatusInternalServerError)
+		}
+	}
+}
+
+// releaseQueries removes the failures and the critical tx errors of the queries, so they are processed again.
+func releaseQueries(logger *zap.Logger, connections []Connection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqBody := ReleaseQueriesRequest{}
+		decoder := json.NewDecoder(r.Body)
+		err := decoder.Decode(&reqBody)
+		if err != nil {
+			logger.Error("failed to decode request body of releaseQueries", zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error processing request: %s", err), http.StatusBadRequest)
 			return
 		}
 
+		for _, queryID := range reqBody.QueryIDs {
+			// query IDs are unique across all the connections, so we just look for the one having the query failures
+			var (
+				storage relay.Storage
+				connID  string
+			)
+			for _, conn := range connections {
+				found, err := hasQueryFailures(conn.Storage, queryID)
+				if err != nil {
+					logger.Error("failed to get query failure info", zap.Uint64("query_id", queryID), zap.Error(err))
+					http.Error(w, fmt.Sprintf("Error processing request: %s", err), http.StatusInternalServerError)
+					return
+				}
+				if found {
+					storage, connID = conn.Storage, conn.ID
+					break
+				}
+			}
+			if storage == nil {
+				http.Error(w, fmt.Sprintf("no failures found for query with queryID=%d", queryID), http.StatusBadRequest)
+				return
+			}
+
+			logger.Info("releasing query", zap.Uint64("query_id", queryID), zap.String("connection_id", connID))
+			if err := storage.RemoveQueryFailure(queryID); err != nil {
+				logger.Error("failed to release query", zap.Uint64("query_id", queryID), zap.Error(err))
+				http.Error(w, fmt.Sprintf("Error processing request: %s", err), http.StatusInternalServerError)
+				return
+			}
+			if err := storage.RemoveCriticalTxError(queryID); err != nil {
+				logger.Error("failed to remove critical tx error", zap.Uint64("query_id", queryID), zap.Error(err))
+				http.Error(w, fmt.Sprintf("Error processing request: %s", err), http.StatusInternalServerError)
+				return
+			}
+		}
+	}
+}
+
+// hasQueryFailures returns true if the storage has either failures or a critical tx error of the query.
+func hasQueryFailures(storage relay.Storage, queryID uint64) (bool, error) {
+	_, found, err := storage.GetQueryFailure(queryID)
+	if err != nil || found {
+		return found, err
+	}
+
+	errs, err := storage.GetAllCriticalTxErrors()
+	if err != nil {
+		return false, err
+	}
+	for _, errInfo := range errs {
+		if errInfo.QueryID == queryID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+func backfill(logger *zap.Logger, connections []Connection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		reqBody := BackfillRequest{}
+		decoder := json.NewDecoder(r.Body)
+		err := decoder.Decode(&reqBody)
+		if err != nil {
+			logger.Error("failed to decode request body of backfill", zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error processing request: %s", err), http.StatusBadRequest)
+			return
+		}
+
+		conn, ok := findConnection(connections, reqBody.ConnectionID)
+		if !ok {
+			http.Error(w, fmt.Sprintf("unknown connection %s", reqBody.ConnectionID), http.StatusBadRequest)
+			return
+		}
+
+		info, err := conn.Backfiller.Start(r.Context(), reqBody.BackfillRequest)
+		if err != nil {
+			logger.Error("failed to start backfill", zap.Uint64("query_id", reqBody.QueryID),
+				zap.String("connection_id", conn.ID), zap.Error(err))
+			http.Error(w, fmt.Sprintf("Error processing request: %s", err), http.StatusBadRequest)
+			return
+		}
+		info.ConnectionID = conn.ID
+
 		encoder := json.NewEncoder(w)
 		encoder.SetIndent("", "  ")
-		err = encoder.Encode(res)
+		err = encoder.Encode(info)
 		if err != nil {
-			logger.Error("failed to encode result of GetAllUnsuccessfulTxs", zap.Error(err))
+			logger.Error("failed to encode backfill job", zap.Error(err))
 			http.Error(w, "Error processing request", http.StatusInternalServerError)
 		}
 	}
 }
 
-func resubmitFailedTxs(logger *zap.Logger, store relay.Storage, txProcessor relay.TXProcessor, submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo) http.HandlerFunc {
+func backfills(logger *zap.Logger, connections []Connection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := make([]relay.BackfillInfo, 0)
+		for _, conn := range connections {
+			for _, info := range conn.Backfiller.Jobs() {
+				info.ConnectionID = conn.ID
+				res = append(res, info)
+			}
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(res)
+		if err != nil {
+			logger.Error("failed to encode backfill jobs", zap.Error(err))
+			http.Error(w, "Error processing request", http.StatusInternalServerError)
+		}
+	}
+}
+
+func clientHealth(logger *zap.Logger, connections []Connection) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := make([]relay.ClientHealthInfo, 0, len(connections))
+		for _, conn := range connections {
+			if conn.ClientHealth == nil {
+				continue
+			}
+			info := conn.ClientHealth.ClientHealth()
+			info.ConnectionID = conn.ID
+			res = append(res, info)
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(res)
+		if err != nil {
+			logger.Error("failed to encode client health", zap.Error(err))
+			http.Error(w, "Error processing request", http.StatusInternalServerError)
+		}
+	}
+}
+
+func health(logger *zap.Logger, draining <-chan struct{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		res := HealthResponse{Status: StatusOK}
+		select {
+		case <-draining:
+			res.Status = StatusDraining
+			w.WriteHeader(http.StatusServiceUnavailable)
+		default:
+		}
+
+		err := json.NewEncoder(w).Encode(res)
+		if err != nil {
+			logger.Error("failed to encode health status", zap.Error(err))
+		}
+	}
+}
+
+func dryRunResults(logger *zap.Logger, dryRunRecorder relay.DryRunRecorder) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		if dryRunRecorder == nil {
+			http.Error(w, "dry run mode is disabled", http.StatusNotFound)
+			return
+		}
+
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		err := encoder.Encode(dryRunRecorder.Results())
+		if err != nil {
+			logger.Error("failed to encode dry run results", zap.Error(err))
+			http.Error(w, "Error processing request", http.StatusInternalServerError)
+		}
+	}
+}
+
+func resubmitFailedTxs(logger *zap.Logger, connections []Connection) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
 		reqBody := ResubmitRequest{}
 		decoder := json.NewDecoder(r.Body)
@@ -110,8 +439,15 @@ func resubmitFailedTxs(logger *zap.Logger, store relay.Storage, txProcessor rela
 		}
 
 		for _, txInfo := range reqBody.Txs {
-			logger.Debug("resubmitting tx", zap.Uint64("query_id", txInfo.QueryID), zap.String("hash", txInfo.Hash))
-			tx, err := store.GetCachedTx(txInfo.QueryID, txInfo.Hash)
+			conn, ok := findConnection(connections, txInfo.ConnectionID)
+			if !ok {
+				http.Error(w, fmt.Sprintf("unknown connection %s", txInfo.ConnectionID), http.StatusBadRequest)
+				return
+			}
+
+			logger.Debug("resubmitting tx", zap.Uint64("query_id", txInfo.QueryID), zap.String("hash", txInfo.Hash),
+				zap.String("connection_id", conn.ID))
+			tx, err := conn.Storage.GetCachedTx(txInfo.QueryID, txInfo.Hash)
 			if err != nil {
 				logger.Error("failed to get unsuccessful tx", zap.Error(err))
 				httpErrorCode := http.StatusInternalServerError
@@ -126,7 +462,7 @@ func resubmitFailedTxs(logger *zap.Logger, store relay.Storage, txProcessor rela
 			logger.Debug("tx", zap.Any("tx", *tx))
 			// we do not want to pass r.Context() at this place, because r.Context() is canceled at the end of the function
 			// but we have delayed call of txsubmitchecker which depends on the context passed into the ProcessAndSubmit
-			err = txProcessor.ProcessAndSubmit(context.Background(), txInfo.QueryID, *tx, submittedTxsTasksQueue)
+			err = conn.TxProcessor.ProcessAndSubmit(context.Background(), txInfo.QueryID, *tx, conn.SubmittedTxsTasksQueue)
 			if err != nil {
 				logger.Error("failed to process and resubmit tx", zap.Error(err))
 				http.Error(w, fmt.Sprintf("Error processing request: %s", err), http.StatusInternalServerError)
@@ -0,0 +1,141 @@
+package kvprocessor
+
+import (
+	"context"
+	"fmt"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	"github.com/cosmos/relayer/v2/relayer/chains/cosmos"
+	"go.uber.org/zap"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+)
+
+const (
+	clientUpdateSourceCache = "cache"
+	clientUpdateSourceChain = "chain"
+)
+
+// clientHeight is the height of a consensus state in a Neutron's client of the target chain.
+type clientHeight struct {
+	clientID string
+	height   clienttypes.Height
+}
+
+// clientHeight returns the height of the consensus state for the given target chain height in the current
+// Neutron's client and the current revision of the target chain.
+func (p *KVProcessor) clientHeight(height int64) clientHeight {
+	return clientHeight{
+		clientID: p.connection.ConnectionParams().NeutronClientID,
+		height:   clienttypes.NewHeight(p.revision.Revision(), uint64(height)),
+	}
+}
+
+// prepareClientUpdate returns the MsgUpdateClient with the target chain header at the given height,
+// which is needed to verify the proofs obtained on height-1. It returns nil if Neutron's client already
+// has a consensus state at the height.
+func (p *KVProcessor) prepareClientUpdate(ctx context.Context, csHeight clientHeight) (sdk.Msg, error) {
+	if p.clientUpdates != nil && p.clientUpdates.Contains(csHeight) {
+		neutronmetrics.IncSkippedClientUpdates(p.connectionID, clientUpdateSourceCache)
+		return nil, nil
+	}
+
+	if p.hasConsensusState(ctx, csHeight) {
+		if p.clientUpdates != nil {
+			p.clientUpdates.Add(csHeight, struct{}{})
+		}
+		neutronmetrics.IncSkippedClientUpdates(p.connectionID, clientUpdateSourceChain)
+		return nil, nil
+	}
+
+	height := csHeight.height.RevisionHeight
+	srcHeader, err := p.getSrcChainHeader(ctx, int64(height))
+	if err != nil {
+		return nil, fmt.Errorf("failed to get header for height: %d: %w", height, err)
+	}
+
+	updateClientMsg, err := p.getUpdateClientMsg(ctx, csHeight.clientID, srcHeader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to getUpdateClientMsg: %w", err)
+	}
+	return updateClientMsg, nil
+}
+
+// UpdateClient submits a MsgUpdateClient with the latest target chain header, so Neutron's client gets
+// a fresh consensus state even if there are no KV queries to update. It's the same client update a KV
+// query result on the latest height would be submitted with.
+func (p *KVProcessor) UpdateClient(ctx context.Context) (uint64, error) {
+	latestHeight, err := p.targetChain.ChainProvider.QueryLatestHeight(ctx)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get latest height of src chain: %w", err)
+	}
+
+	csHeight := p.clientHeight(latestHeight)
+	updateClientMsg, err := p.prepareClientUpdate(ctx, csHeight)
+	if err != nil {
+		return 0, err
+	}
+	if updateClientMsg == nil {
+		return uint64(latestHeight), nil
+	}
+
+	err = p.submitter.SubmitClientUpdate(ctx, updateClientMsg)
+	p.trackClientUpdate(csHeight, updateClientMsg, err == nil)
+	if err != nil {
+		return 0, fmt.Errorf("could not submit client update: %w", err)
+	}
+	return uint64(latestHeight), nil
+}
+
+// trackClientUpdate lets the trusted header fetcher know about the consensus state created by a submitted
+// client update. The height is not cached until Neutron's client is seen to have the consensus state, since
+// the update tx may still fail after it has been broadcast. If the proofs relying on an existing consensus
+// state failed to be submitted, the height is forgotten, so the next attempt checks Neutron's client again.
+// Nothing is tracked in dry run, since nothing is broadcast then.
+func (p *KVProcessor) trackClientUpdate(csHeight clientHeight, updateClientMsg sdk.Msg, submitted bool) {
+	if p.dryRun {
+		return
+	}
+
+	if submitted && updateClientMsg != nil {
+		if header := clientUpdateHeader(updateClientMsg); header != nil {
+			p.trustedHeaderFetcher.TrackClientUpdate(csHeight.clientID, header)
+		}
+	}
+
+	if p.clientUpdates != nil && !submitted && updateClientMsg == nil {
+		p.clientUpdates.Remove(csHeight)
+	}
+}
+
+// clientUpdateHeader returns the header of the MsgUpdateClient or nil if it's not a tendermint header.
+func clientUpdateHeader(updateClientMsg sdk.Msg) *tmclient.Header {
+	msg, ok := updateClientMsg.(*clienttypes.MsgUpdateClient)
+	if !ok || msg.ClientMessage == nil {
+		return nil
+	}
+	header, _ := msg.ClientMessage.GetCachedValue().(*tmclient.Header)
+	return header
+}
+
+// hasConsensusState checks whether Neutron's client has a consensus state at the given height.
+func (p *KVProcessor) hasConsensusState(ctx context.Context, csHeight clientHeight) bool {
+	neutronProvider, ok := p.neutronChain.ChainProvider.(*cosmos.CosmosProvider)
+	if !ok {
+		return false
+	}
+
+	_, err := clienttypes.NewQueryClient(neutronProvider).ConsensusState(ctx, &clienttypes.QueryConsensusStateRequest{
+		ClientId:       csHeight.clientID,
+		RevisionNumber: csHeight.height.RevisionNumber,
+		RevisionHeight: csHeight.height.RevisionHeight,
+	})
+	if err != nil {
+		// most likely there is just no consensus state at the height, anyway we can't rely on it
+		p.logger.Debug("no consensus state found for height", zap.String("client_id", csHeight.clientID), zap.Uint64("height", csHeight.height.RevisionHeight), zap.Error(err))
+		return false
+	}
+	return true
+}
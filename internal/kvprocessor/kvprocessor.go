@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"github.com/avast/retry-go/v4"
 	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	lru "github.com/hashicorp/golang-lru"
 	"time"
 
 	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
@@ -32,6 +33,16 @@ type KVProcessor struct {
 	storage              relay.Storage
 	targetChain          *relayer.Chain
 	neutronChain         *relayer.Chain
+	connectionID         string
+	// revision is the revision of the target chain the proofs are submitted for
+	revision relay.TargetRevision
+	// connection provides the ID of Neutron's client the proofs are verified with
+	connection relay.ConnectionParamsProvider
+	// clientUpdates contains the heights of the consensus states that are known to be in Neutron's client,
+	// it's nil if the cache is disabled
+	clientUpdates *lru.Cache
+	// dryRun is true if the txs are never broadcast
+	dryRun bool
 }
 
 func NewKVProcessor(
@@ -42,7 +53,17 @@ func NewKVProcessor(
 	submitter relay.Submitter,
 	storage relay.Storage,
 	targetChain *relayer.Chain,
-	neutronChain *relayer.Chain) *KVProcessor {
+	neutronChain *relayer.Chain,
+	clientUpdatesCacheSize int,
+	revision relay.TargetRevision,
+	connection relay.ConnectionParamsProvider,
+	dryRun bool) *KVProcessor {
+	var clientUpdates *lru.Cache
+	if clientUpdatesCacheSize > 0 {
+		// the only error is returned for a non-positive size
+		clientUpdates, _ = lru.New(clientUpdatesCacheSize)
+	}
+
 	return &KVProcessor{
 		trustedHeaderFetcher: trustedHeaderFetcher,
 		querier:              querier,
@@ -52,6 +73,11 @@ func NewKVProcessor(
 		storage:              storage,
 		targetChain:          targetChain,
 		neutronChain:         neutronChain,
+		connectionID:         neutronChain.PathEnd.ConnectionID,
+		revision:             revision,
+		connection:           connection,
+		clientUpdates:        clientUpdates,
+		dryRun:               dryRun,
 	}
 }
 
@@ -74,6 +100,63 @@ func (p *KVProcessor) ProcessAndSubmit(ctx context.Context, m *relay.MessageKV)
 	return p.submitKVWithProof(ctx, int64(height), m.QueryId, proofs)
 }
 
+// ProcessAndSubmitBatch processes several relay.MessageKV at once. The values of all the queries are
+// obtained on the same latest height of the target chain, so they share a single header and are
+// submitted together. It returns the error of each message by its position.
+func (p *KVProcessor) ProcessAndSubmitBatch(ctx context.Context, msgs []*relay.MessageKV) []error {
+	errs := make([]error, len(msgs))
+	latestHeight, err := p.targetChain.ChainProvider.QueryLatestHeight(ctx)
+	if err != nil {
+		for i := range errs {
+			errs[i] = fmt.Errorf("failed to get header for src chain: %w", err)
+		}
+		return errs
+	}
+
+	// the proofs are grouped by the height they are obtained on, normally there is only one group
+	var (
+		heights  []uint64
+		byHeight = make(map[uint64]*kvProofsGroup)
+	)
+	for i, m := range msgs {
+		ok, err := p.isQueryOnTime(m.QueryId, uint64(latestHeight))
+		if err != nil || !ok {
+			errs[i] = fmt.Errorf("error on checking previous query update with query_id=%d: %w", m.QueryId, err)
+			continue
+		}
+
+		proofs, height, err := p.getStorageValues(ctx, uint64(latestHeight), m.KVKeys)
+		if err != nil {
+			errs[i] = fmt.Errorf("failed to get storage values with proofs for query_id=%d: %w", m.QueryId, err)
+			continue
+		}
+
+		group, ok := byHeight[height]
+		if !ok {
+			group = &kvProofsGroup{}
+			byHeight[height] = group
+			heights = append(heights, height)
+		}
+		group.indexes = append(group.indexes, i)
+		group.proofs = append(group.proofs, relay.KVProof{QueryID: m.QueryId, Proof: proofs})
+	}
+
+	for _, height := range heights {
+		group := byHeight[height]
+		groupErrs := p.submitKVWithProofs(ctx, int64(height), group.proofs)
+		for i, idx := range group.indexes {
+			errs[idx] = groupErrs[i]
+		}
+	}
+	return errs
+}
+
+// kvProofsGroup contains the proofs obtained on the same height and the positions of their messages.
+type kvProofsGroup struct {
+	indexes []int
+	proofs  []relay.KVProof
+}
+
 // getStorageValues gets proofs for query type = 'kv'
 func (p *KVProcessor) getStorageValues(ctx context.Context, inputHeight uint64, keys neutrontypes.KVKeys) ([]*neutrontypes.StorageValue, uint64, error) {
 	stValues := make([]*neutrontypes.StorageValue, 0, len(keys))
@@ -112,7 +195,7 @@ func (p *KVProcessor) isQueryOnTime(queryID uint64, currentBlock uint64) (bool,
 		return true, nil
 	}
 
-	return false, fmt.Errorf("attempted to update query results too soon: last update was on block=%d, current block=%d, maximum update period=%d", previous, currentBlock, p.minKVUpdatePeriod)
+	return false, fmt.Errorf("%w: last update was on block=%d, current block=%d, maximum update period=%d", relay.ErrQueryTooEarly, previous, currentBlock, p.minKVUpdatePeriod)
 }
 
 // submitKVWithProof submits the proof for the given query on the given height and tracks the result.
@@ -122,33 +205,66 @@ func (p *KVProcessor) submitKVWithProof(
 	queryID uint64,
 	proof []*neutrontypes.StorageValue,
 ) error {
-	srcHeader, err := p.getSrcChainHeader(ctx, height)
-	if err != nil {
-		return fmt.Errorf("failed to get header for height: %d: %w", height, err)
-	}
-
-	updateClientMsg, err := p.getUpdateClientMsg(ctx, srcHeader)
+	csHeight := p.clientHeight(height)
+	updateClientMsg, err := p.prepareClientUpdate(ctx, csHeight)
 	if err != nil {
-		return fmt.Errorf("failed to getUpdateClientMsg: %w", err)
+		return err
 	}
 
 	st := time.Now()
-	if err = p.submitter.SubmitKVProof(
+	err = p.submitter.SubmitKVProof(
 		ctx,
 		uint64(height-1),
-		srcHeader.GetHeight().GetRevisionNumber(),
+		csHeight.height.RevisionNumber,
 		queryID,
 		proof,
 		updateClientMsg,
-	); err != nil {
+	)
+	p.trackClientUpdate(csHeight, updateClientMsg, err == nil)
+	if err != nil {
 		neutronmetrics.AddFailedProof(string(neutrontypes.InterchainQueryTypeKV), time.Since(st).Seconds())
 		return fmt.Errorf("could not submit proof: %w", err)
 	}
 	neutronmetrics.AddSuccessProof(string(neutrontypes.InterchainQueryTypeKV), time.Since(st).Seconds())
-	p.logger.Info("proof for query_id submitted successfully", zap.Uint64("query_id", queryID), zap.Uint64("remote_height", uint64(height-1)), zap.Uint64("trusted_header_height", srcHeader.GetHeight().GetRevisionHeight()))
+	p.logger.Info("proof for query_id submitted successfully", zap.Uint64("query_id", queryID), zap.Uint64("remote_height", uint64(height-1)), zap.Bool("client_updated", updateClientMsg != nil))
 	return nil
 }
 
+// submitKVWithProofs submits the proofs for several queries on the given height in one go and tracks the results.
+func (p *KVProcessor) submitKVWithProofs(ctx context.Context, height int64, proofs []relay.KVProof) []error {
+	errs := make([]error, len(proofs))
+	csHeight := p.clientHeight(height)
+	updateClientMsg, err := p.prepareClientUpdate(ctx, csHeight)
+	if err != nil {
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	st := time.Now()
+	submitErrs := p.submitter.SubmitKVProofs(
+		ctx,
+		uint64(height-1),
+		csHeight.height.RevisionNumber,
+		proofs,
+		updateClientMsg,
+	)
+	submitted := false
+	for i, proof := range proofs {
+		if submitErrs[i] != nil {
+			neutronmetrics.AddFailedProof(string(neutrontypes.InterchainQueryTypeKV), time.Since(st).Seconds())
+			errs[i] = fmt.Errorf("could not submit proof: %w", submitErrs[i])
+			continue
+		}
+		submitted = true
+		neutronmetrics.AddSuccessProof(string(neutrontypes.InterchainQueryTypeKV), time.Since(st).Seconds())
+		p.logger.Info("proof for query_id submitted successfully", zap.Uint64("query_id", proof.QueryID), zap.Uint64("remote_height", uint64(height-1)), zap.Bool("client_updated", updateClientMsg != nil), zap.Int("batch_size", len(proofs)))
+	}
+	p.trackClientUpdate(csHeight, updateClientMsg, submitted)
+	return errs
+}
+
 func (p *KVProcessor) getSrcChainHeader(ctx context.Context, height int64) (*tmclient.Header, error) {
 	start := time.Now()
 	var srcHeader *tmclient.Header
@@ -167,13 +283,13 @@ func (p *KVProcessor) getSrcChainHeader(ctx context.Context, height int64) (*tmc
 	return srcHeader, nil
 }
 
-func (p *KVProcessor) getUpdateClientMsg(ctx context.Context, srcHeader *tmclient.Header) (sdk.Msg, error) {
+func (p *KVProcessor) getUpdateClientMsg(ctx context.Context, clientID string, srcHeader *tmclient.Header) (sdk.Msg, error) {
 	start := time.Now()
 	// Construct UpdateClient msg
 	var updateMsgRelayer provider.RelayerMessage
 	if err := retry.Do(func() error {
 		var err error
-		updateMsgRelayer, err = p.neutronChain.ChainProvider.MsgUpdateClient(p.neutronChain.PathEnd.ClientID, srcHeader)
+		updateMsgRelayer, err = p.neutronChain.ChainProvider.MsgUpdateClient(clientID, srcHeader)
 		return err
 	}, retry.Context(ctx), relayer.RtyAtt, relayer.RtyDel, relayer.RtyErr, retry.OnRetry(func(n uint, err error) {
 		p.logger.Error(
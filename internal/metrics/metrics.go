@@ -8,15 +8,29 @@ import (
 const (
 	labelMethod = "method"
 	labelType   = "type"
-	typeSuccess = "success"
-	typeFailed  = "failed"
+	// labelConnection is the Neutron's side connection ID the metric belongs to.
+	labelConnection = "connection_id"
+	labelPolicy     = "policy"
+	labelSource     = "source"
+	labelCache      = "cache"
+	labelStatus     = "status"
+	typeSuccess     = "success"
+	typeFailed      = "failed"
+	typeHit         = "hit"
+	typeMiss        = "miss"
+	typeAdded       = "added"
+	typeRemoved     = "removed"
+	typeUpdated     = "updated"
+	// sources of the revision numbers
+	sourceTargetChain = "target_chain"
+	sourceClient      = "client"
 )
 
 var (
 	relayerRequests = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "relayer_requests",
 		Help: "The total number of requests (counter)",
-	}, []string{labelType})
+	}, []string{labelType, labelConnection})
 
 	relayerProofs = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "relayer_proofs",
@@ -27,7 +41,7 @@ var (
 		Name:    "request_time",
 		Help:    "A histogram of requests duration",
 		Buckets: []float64{0.05, 0.1, 0.25, 0.5, 1, 2, 3, 5, 10, 30},
-	}, []string{labelMethod, labelType})
+	}, []string{labelMethod, labelType, labelConnection})
 
 	proofNeutronTime = promauto.NewHistogramVec(prometheus.HistogramOpts{
 		Name:    "proof_neutron_time",
@@ -44,33 +58,132 @@ var (
 	submittedTxCounter = promauto.NewCounterVec(prometheus.CounterOpts{
 		Name: "submitted_txs",
 		Help: "The total number of submitted txs (counter)",
-	}, []string{labelType})
+	}, []string{labelType, labelConnection})
 
-	unsuccessfulTxsQueueSize = promauto.NewGauge(prometheus.GaugeOpts{
+	unsuccessfulTxsQueueSize = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "unsuccessful_txs",
 		Help: "The total number of unsuccessful txs in the storage",
-	})
+	}, []string{labelConnection})
+
+	quarantinedQueries = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "quarantined_queries",
+		Help: "The total number of quarantined queries in the storage",
+	}, []string{labelConnection})
+
+	dryRunTxs = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "dry_run_txs",
+		Help: "The total number of txs built but not broadcast in the dry run mode (counter)",
+	}, []string{labelType, labelConnection})
+
+	dryRunGas = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "dry_run_gas",
+		Help:    "A histogram of gas estimated for txs built in the dry run mode",
+		Buckets: prometheus.ExponentialBuckets(50000, 2, 10),
+	}, []string{labelConnection})
+
+	criticalTxErrors = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "critical_tx_errors",
+		Help: "The total number of tx submission errors not matching the ignored errors regex (counter)",
+	}, []string{labelPolicy, labelConnection})
+
+	kvBatchSize = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name:    "kv_batch_size",
+		Help:    "The number of KV query results submitted in a single transaction",
+		Buckets: []float64{1, 2, 5, 10, 20, 50, 100},
+	}, []string{labelConnection})
+
+	kvBatchFallbacks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "kv_batch_fallbacks",
+		Help: "The total number of KV query results batches which were split in halves after the batch had failed (counter)",
+	}, []string{labelConnection})
+
+	skippedClientUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "skipped_client_updates",
+		Help: "The total number of KV proofs submitted without MsgUpdateClient since the consensus state was found in the cache or on the chain (counter)",
+	}, []string{labelSource, labelConnection})
+
+	trustedHeadersCache = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "trusted_headers_cache",
+		Help: "The total number of TrustedHeaderFetcher cache lookups by cache and result (counter)",
+	}, []string{labelCache, labelType, labelConnection})
+
+	skippedPrunedBlocks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "skipped_pruned_blocks",
+		Help: "The total number of blocks skipped by the block scanner since the target chain node had pruned them (counter)",
+	}, []string{labelConnection})
+
+	headerVerificationFailures = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "header_verification_failures",
+		Help: "The total number of target chain headers which failed the local light client verification (counter)",
+	}, []string{labelConnection})
+
+	clientStatus = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "client_status",
+		Help: "The status of Neutron's light client of the target chain: 1 for the current status, 0 for the others (gauge)",
+	}, []string{labelConnection, labelStatus})
+
+	clientLatestConsensusTimestamp = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "client_latest_consensus_timestamp_seconds",
+		Help: "The timestamp of the latest consensus state of Neutron's light client of the target chain (gauge)",
+	}, []string{labelConnection})
+
+	clientTimeToExpiry = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "client_time_to_expiry_seconds",
+		Help: "The time left until Neutron's light client of the target chain expires unless it's updated (gauge)",
+	}, []string{labelConnection})
+
+	revisionNumber = promauto.NewGaugeVec(prometheus.GaugeOpts{
+		Name: "revision_number",
+		Help: "The revision number of the target chain as reported by the target chain and by Neutron's light client of it (gauge)",
+	}, []string{labelConnection, labelSource})
+
+	connectionParamsSwitches = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "connection_params_switches",
+		Help: "The total number of switches to the changed clients or counterparty connection of a connection (counter)",
+	}, []string{labelConnection})
+
+	clientKeepAliveUpdates = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "client_keep_alive_updates",
+		Help: "The total number of keep-alive updates of Neutron's light client of the target chain by result (counter)",
+	}, []string{labelConnection, labelType})
+
+	coalescedTasks = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "coalesced_tasks",
+		Help: "The total number of tasks merged into an already queued task for the same query (counter)",
+	}, []string{labelConnection})
+
+	subscriberReconnects = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "subscriber_reconnects",
+		Help: "The total number of Subscriber reconnects after its Neutron events subscriptions died, by reason (counter)",
+	}, []string{labelConnection, labelType})
+
+	queriesDrift = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "queries_drift",
+		Help: "The total number of active queries added, removed or updated by the Subscriber on reloading the queries registered on Neutron, i.e. the changes it had missed the events of (counter)",
+	}, []string{labelConnection, labelType})
 
 	subscriberTaskQueueNumElements = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "subscriber_task_queue_num_elements",
 		Help: "The total number of elements in Subscriber's task queue",
-	}, []string{})
+	}, []string{labelConnection})
 
 	queriesToProcess = promauto.NewGaugeVec(prometheus.GaugeOpts{
 		Name: "queries_to_process",
 		Help: "The total number of active registered queries to process (counter)",
-	}, []string{})
+	}, []string{labelConnection})
 )
 
-func incFailedRequests() {
+func incFailedRequests(connectionID string) {
 	relayerRequests.With(prometheus.Labels{
-		labelType: typeFailed,
+		labelType:       typeFailed,
+		labelConnection: connectionID,
 	}).Inc()
 }
 
-func incSuccessRequests() {
+func incSuccessRequests(connectionID string) {
 	relayerRequests.With(prometheus.Labels{
-		labelType: typeSuccess,
+		labelType:       typeSuccess,
+		labelConnection: connectionID,
 	}).Inc()
 }
 
@@ -86,19 +199,21 @@ func incSuccessProofs() {
 	}).Inc()
 }
 
-func AddFailedRequest(message string, dur float64) {
-	incFailedRequests()
+func AddFailedRequest(connectionID string, message string, dur float64) {
+	incFailedRequests(connectionID)
 	requestTime.With(prometheus.Labels{
-		labelMethod: message,
-		labelType:   typeFailed,
+		labelMethod:     message,
+		labelType:       typeFailed,
+		labelConnection: connectionID,
 	}).Observe(dur)
 }
 
-func AddSuccessRequest(message string, dur float64) {
-	incSuccessRequests()
+func AddSuccessRequest(connectionID string, message string, dur float64) {
+	incSuccessRequests(connectionID)
 	requestTime.With(prometheus.Labels{
-		labelMethod: message,
-		labelType:   typeSuccess,
+		labelMethod:     message,
+		labelType:       typeSuccess,
+		labelConnection: connectionID,
 	}).Observe(dur)
 }
 
@@ -125,26 +240,120 @@ func RecordActionDuration(action string, dur float64) {
 	}).Observe(dur)
 }
 
-func IncSuccessTxSubmit() {
+func IncSuccessTxSubmit(connectionID string) {
 	submittedTxCounter.With(prometheus.Labels{
-		labelType: typeSuccess,
+		labelType:       typeSuccess,
+		labelConnection: connectionID,
 	}).Inc()
 }
 
-func IncFailedTxSubmit() {
+func IncFailedTxSubmit(connectionID string) {
 	submittedTxCounter.With(prometheus.Labels{
-		labelType: typeFailed,
+		labelType:       typeFailed,
+		labelConnection: connectionID,
 	}).Inc()
 }
 
-func SetUnsuccessfulTxsSizeQueue(size int) {
-	unsuccessfulTxsQueueSize.Set(float64(size))
+func SetUnsuccessfulTxsSizeQueue(connectionID string, size int) {
+	unsuccessfulTxsQueueSize.With(prometheus.Labels{labelConnection: connectionID}).Set(float64(size))
+}
+
+func SetQuarantinedQueries(connectionID string, size int) {
+	quarantinedQueries.With(prometheus.Labels{labelConnection: connectionID}).Set(float64(size))
+}
+
+func AddDryRunTx(connectionID string, success bool, gas uint64) {
+	if !success {
+		dryRunTxs.With(prometheus.Labels{labelType: typeFailed, labelConnection: connectionID}).Inc()
+		return
+	}
+
+	dryRunTxs.With(prometheus.Labels{labelType: typeSuccess, labelConnection: connectionID}).Inc()
+	dryRunGas.With(prometheus.Labels{labelConnection: connectionID}).Observe(float64(gas))
+}
+
+func IncCriticalTxErrors(connectionID string, policy string) {
+	criticalTxErrors.With(prometheus.Labels{labelPolicy: policy, labelConnection: connectionID}).Inc()
+}
+
+func ObserveKVBatchSize(connectionID string, size int) {
+	kvBatchSize.With(prometheus.Labels{labelConnection: connectionID}).Observe(float64(size))
+}
+
+func IncKVBatchFallbacks(connectionID string) {
+	kvBatchFallbacks.With(prometheus.Labels{labelConnection: connectionID}).Inc()
+}
+
+func IncSkippedClientUpdates(connectionID string, source string) {
+	skippedClientUpdates.With(prometheus.Labels{labelSource: source, labelConnection: connectionID}).Inc()
+}
+
+func IncTrustedHeadersCache(connectionID string, cache string, hit bool) {
+	result := typeMiss
+	if hit {
+		result = typeHit
+	}
+	trustedHeadersCache.With(prometheus.Labels{labelCache: cache, labelType: result, labelConnection: connectionID}).Inc()
+}
+
+func AddSkippedPrunedBlocks(connectionID string, blocks int64) {
+	skippedPrunedBlocks.With(prometheus.Labels{labelConnection: connectionID}).Add(float64(blocks))
+}
+
+func IncHeaderVerificationFailures(connectionID string) {
+	headerVerificationFailures.With(prometheus.Labels{labelConnection: connectionID}).Inc()
+}
+
+func SetClientStatus(connectionID string, status string, statuses []string) {
+	for _, s := range statuses {
+		value := 0.0
+		if s == status {
+			value = 1
+		}
+		clientStatus.With(prometheus.Labels{labelConnection: connectionID, labelStatus: s}).Set(value)
+	}
+}
+
+func SetClientExpiry(connectionID string, latestConsensusTimestamp float64, timeToExpiry float64) {
+	clientLatestConsensusTimestamp.With(prometheus.Labels{labelConnection: connectionID}).Set(latestConsensusTimestamp)
+	clientTimeToExpiry.With(prometheus.Labels{labelConnection: connectionID}).Set(timeToExpiry)
+}
+
+func SetRevisions(connectionID string, targetRevision uint64, clientRevision uint64) {
+	revisionNumber.With(prometheus.Labels{labelConnection: connectionID, labelSource: sourceTargetChain}).Set(float64(targetRevision))
+	revisionNumber.With(prometheus.Labels{labelConnection: connectionID, labelSource: sourceClient}).Set(float64(clientRevision))
+}
+
+func IncConnectionParamsSwitches(connectionID string) {
+	connectionParamsSwitches.With(prometheus.Labels{labelConnection: connectionID}).Inc()
+}
+
+func IncClientKeepAliveUpdates(connectionID string, success bool) {
+	result := typeFailed
+	if success {
+		result = typeSuccess
+	}
+	clientKeepAliveUpdates.With(prometheus.Labels{labelConnection: connectionID, labelType: result}).Inc()
+}
+
+func IncCoalescedTasks(connectionID string) {
+	coalescedTasks.With(prometheus.Labels{labelConnection: connectionID}).Inc()
+}
+
+func IncSubscriberReconnects(connectionID string, reason string) {
+	subscriberReconnects.With(prometheus.Labels{labelConnection: connectionID, labelType: reason}).Inc()
+}
+
+func AddQueriesDrift(connectionID string, added int, removed int, updated int) {
+	queriesDrift.With(prometheus.Labels{labelConnection: connectionID, labelType: typeAdded}).Add(float64(added))
+	queriesDrift.With(prometheus.Labels{labelConnection: connectionID, labelType: typeRemoved}).Add(float64(removed))
+	queriesDrift.With(prometheus.Labels{labelConnection: connectionID, labelType: typeUpdated}).Add(float64(updated))
 }
 
-func SetSubscriberTaskQueueNumElements(numElements int) {
-	subscriberTaskQueueNumElements.With(prometheus.Labels{}).Set(float64(numElements))
+func SetSubscriberTaskQueueNumElements(connectionID string, numElements int) {
+	subscriberTaskQueueNumElements.With(prometheus.Labels{labelConnection: connectionID}).Set(float64(numElements))
 }
 
-func SetQueriesToProcessNumElements(numElements int) {
-	queriesToProcess.With(prometheus.Labels{}).Set(float64(numElements))
+func SetQueriesToProcessNumElements(connectionID string, numElements int) {
+	queriesToProcess.With(prometheus.Labels{labelConnection: connectionID}).Set(float64(numElements))
 }
@@ -0,0 +1,64 @@
+package relay
+
+import (
+	"context"
+	"time"
+
+	"github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// Statuses of a backfill job.
+const (
+	BackfillPending   = "pending"
+	BackfillRunning   = "running"
+	BackfillDone      = "done"
+	BackfillFailed    = "failed"
+	BackfillCancelled = "cancelled"
+)
+
+// BackfillRequest describes the transactions to be relayed for a TX query over an explicit height range.
+type BackfillRequest struct {
+	// QueryID is the ID of the TX query
+	QueryID uint64 `json:"query_id"`
+	// FromHeight is the first height of the range
+	FromHeight uint64 `json:"from_height"`
+	// ToHeight is the last height of the range
+	ToHeight uint64 `json:"to_height"`
+}
+
+// BackfillInfo is the state of a backfill job.
+type BackfillInfo struct {
+	// ConnectionID is the Neutron's side connection ID of the query. It's filled in by the api
+	ConnectionID string `json:"connection_id,omitempty"`
+	BackfillRequest
+	// Status is one of BackfillPending, BackfillRunning, BackfillDone, BackfillFailed and BackfillCancelled
+	Status string `json:"status"`
+	// ProcessedHeight is the last height whose transactions are all relayed
+	ProcessedHeight uint64 `json:"processed_height"`
+	// SubmittedTxs is the number of transactions submitted by the job
+	SubmittedTxs uint64 `json:"submitted_txs"`
+	// SkippedTxs is the number of transactions skipped because they had already been submitted
+	SkippedTxs uint64 `json:"skipped_txs"`
+	// Error is the reason of the job failure
+	Error string `json:"error,omitempty"`
+	// CreateTime is the time the job was scheduled
+	CreateTime time.Time `json:"create_time"`
+	// EndTime is the time the job was finished
+	EndTime *time.Time `json:"end_time,omitempty"`
+}
+
+// QueryFetcher fetches registered queries from the Neutron chain.
+type QueryFetcher interface {
+	// RegisteredQuery returns the registered query with the given ID if it's served by the relayer.
+	RegisteredQuery(ctx context.Context, queryID uint64) (*types.RegisteredQuery, error)
+}
+
+// Backfiller relays the transactions of TX queries over explicit height ranges alongside the normal
+// processing, without moving the last processed heights of the queries. Only the heights up to the
+// last processed height of a query can be backfilled, the ones above are relayed by the normal processing.
+type Backfiller interface {
+	// Start schedules a backfill job and returns its initial state
+	Start(ctx context.Context, request BackfillRequest) (BackfillInfo, error)
+	// Jobs returns the states of the recent backfill jobs from the oldest to the newest one
+	Jobs() []BackfillInfo
+}
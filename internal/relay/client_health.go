@@ -0,0 +1,46 @@
+package relay
+
+import (
+	"time"
+)
+
+// Statuses of Neutron's light client of the target chain.
+const (
+	ClientStatusUnknown = "unknown"
+	ClientStatusActive  = "active"
+	ClientStatusExpired = "expired"
+	ClientStatusFrozen  = "frozen"
+)
+
+// ClientHealthInfo describes the state of Neutron's light client of the target chain.
+type ClientHealthInfo struct {
+	// ConnectionID is the Neutron's side connection ID of the client. It's filled in by the api
+	ConnectionID string `json:"connection_id,omitempty"`
+	// ClientID is the ID of the client on Neutron
+	ClientID string `json:"client_id"`
+	// Status is one of ClientStatusUnknown, ClientStatusActive, ClientStatusExpired and ClientStatusFrozen
+	Status string `json:"status"`
+	// LatestHeight is the latest height of the target chain the client has a consensus state for
+	LatestHeight uint64 `json:"latest_height"`
+	// LatestConsensusTime is the timestamp of the latest consensus state
+	LatestConsensusTime time.Time `json:"latest_consensus_time"`
+	// ExpiryTime is the time the client expires at unless it's updated
+	ExpiryTime time.Time `json:"expiry_time"`
+	// TimeToExpiry is the time left until ExpiryTime as of CheckTime
+	TimeToExpiry time.Duration `json:"time_to_expiry"`
+	// CheckTime is the time of the latest successful check
+	CheckTime time.Time `json:"check_time"`
+	// Error is the reason the latest check failed, if it did
+	Error string `json:"error,omitempty"`
+}
+
+// Healthy returns false if the client can't be updated or used to verify proofs anymore.
+func (i ClientHealthInfo) Healthy() bool {
+	return i.Status != ClientStatusExpired && i.Status != ClientStatusFrozen
+}
+
+// ClientHealthChecker tracks the health of Neutron's light client of the target chain.
+type ClientHealthChecker interface {
+	// ClientHealth returns the state of the client as of the latest check
+	ClientHealth() ClientHealthInfo
+}
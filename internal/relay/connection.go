@@ -0,0 +1,14 @@
+package relay
+
+// ConnectionParams are the IBC identifiers of a Neutron's connection to the target chain and of its counterparty.
+type ConnectionParams struct {
+	NeutronClientID    string `json:"neutron_client_id"`
+	TargetClientID     string `json:"target_client_id"`
+	TargetConnectionID string `json:"target_connection_id"`
+}
+
+// ConnectionParamsProvider provides the actual ConnectionParams of the connection the relayer works with. They may
+// change while the relayer is running, e.g. when Neutron's client of the target chain is substituted.
+type ConnectionParamsProvider interface {
+	ConnectionParams() ConnectionParams
+}
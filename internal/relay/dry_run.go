@@ -0,0 +1,34 @@
+package relay
+
+import (
+	"time"
+)
+
+// DryRunResult describes a Neutron transaction which was built, simulated and signed but not broadcast
+// because the relayer runs in the dry run mode.
+type DryRunResult struct {
+	// Time is the time the transaction was built
+	Time time.Time `json:"time"`
+	// TxHash is the hash the transaction would have had if it was broadcast
+	TxHash string `json:"tx_hash,omitempty"`
+	// ConnectionID is the Neutron's side connection ID the transaction is built for
+	ConnectionID string `json:"connection_id"`
+	// ClientID is the Neutron's side client ID the query results are submitted for
+	ClientID string `json:"client_id"`
+	// QueryIDs are the IDs of the queries whose results are in the transaction
+	QueryIDs []uint64 `json:"query_ids"`
+	// MsgTypes are the type urls of the transaction messages
+	MsgTypes []string `json:"msg_types"`
+	// GasWanted is the gas estimated by the transaction simulation
+	GasWanted uint64 `json:"gas_wanted"`
+	// Error is the error which would have prevented the transaction from being broadcast
+	Error string `json:"error,omitempty"`
+}
+
+// DryRunRecorder keeps the latest dry run results.
+type DryRunRecorder interface {
+	// Record saves the result, possibly evicting the oldest one
+	Record(result DryRunResult)
+	// Results returns the saved results from the oldest to the newest one
+	Results() []DryRunResult
+}
@@ -1,6 +1,13 @@
 package relay
 
-import "context"
+import (
+	"context"
+	"errors"
+)
+
+// ErrQueryTooEarly is returned by the KVProcessor when a query is due earlier than the relayer's
+// minimal KV update period allows to update it.
+var ErrQueryTooEarly = errors.New("attempted to update query results too soon")
 
 // KVProcessor processes event query KV type. Obtains the proof for a query we need to process, and sends it to  the neutron
 type KVProcessor interface {
@@ -8,4 +15,15 @@ type KVProcessor interface {
 	// to execute the query (based on the relayer's settings), queries values and proofs for the query
 	// keys, and submits the result to the Neutron chain.
 	ProcessAndSubmit(ctx context.Context, m *MessageKV) error
+	// ProcessAndSubmitBatch does the same as ProcessAndSubmit for several queries at once, the
+	// results obtained on the same height are submitted together. It returns the error of each
+	// message by its position.
+	ProcessAndSubmitBatch(ctx context.Context, msgs []*MessageKV) []error
+}
+
+// ClientUpdater updates Neutron's light client of the target chain regardless of the queries.
+type ClientUpdater interface {
+	// UpdateClient submits a MsgUpdateClient with the latest target chain header unless Neutron's client
+	// already has a consensus state at the height. It returns the height of the consensus state.
+	UpdateClient(ctx context.Context) (uint64, error)
 }
@@ -0,0 +1,116 @@
+package relay
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// isQueryBackedOff returns true if the query must not be processed right now because it is either
+// quarantined or its failure backoff period hasn't passed yet. A query which has failed because of
+// an invalid transactions filter is released as soon as its owner updates the filter.
+func (r *Relayer) isQueryBackedOff(query neutrontypes.RegisteredQuery) (bool, error) {
+	queryID := query.Id
+	info, found, err := r.storage.GetQueryFailure(queryID)
+	if err != nil {
+		return false, fmt.Errorf("failed to get query failure info: %w", err)
+	}
+	if !found {
+		return false, nil
+	}
+
+	if info.InvalidTxFilter != nil && info.InvalidTxFilter.Filter != query.TransactionsFilter {
+		r.logger.Info("transactions filter of the query has been updated, releasing the query", zap.Uint64("query_id", queryID))
+		if err := r.storage.RemoveQueryFailure(queryID); err != nil {
+			return false, fmt.Errorf("failed to remove query failure info: %w", err)
+		}
+		return false, nil
+	}
+
+	if info.Quarantined {
+		r.logger.Debug("skipping quarantined query", zap.Uint64("query_id", queryID),
+			zap.Uint64("consecutive_failures", info.ConsecutiveFailures))
+		return true, nil
+	}
+
+	if time.Now().Before(info.NextAttemptTime) {
+		r.logger.Debug("skipping query until its failure backoff expires", zap.Uint64("query_id", queryID),
+			zap.Uint64("consecutive_failures", info.ConsecutiveFailures),
+			zap.Time("next_attempt_time", info.NextAttemptTime))
+		return true, nil
+	}
+
+	return false, nil
+}
+
+// registerQueryFailure increments the number of consecutive failures of the query, computes the
+// time of the next attempt to process it and puts the query to quarantine once the number of
+// failures reaches the configured threshold. If quarantine is true, the query is put to quarantine
+// regardless of the number of failures.
+func (r *Relayer) registerQueryFailure(queryID uint64, processErr error, quarantine bool) error {
+	info, found, err := r.storage.GetQueryFailure(queryID)
+	if err != nil {
+		return fmt.Errorf("failed to get query failure info: %w", err)
+	}
+	if !found {
+		info = &QueryFailureInfo{QueryID: queryID}
+	}
+
+	info.ConsecutiveFailures++
+	info.LastError = processErr.Error()
+	info.LastFailureTime = time.Now()
+	info.NextAttemptTime = info.LastFailureTime.Add(r.failureBackoff(info.ConsecutiveFailures))
+	info.InvalidTxFilter = nil
+	var filterErr ErrInvalidTxFilter
+	if errors.As(processErr, &filterErr) {
+		info.InvalidTxFilter = &filterErr
+	}
+
+	threshold := r.cfg.QueryQuarantineThreshold
+	if !info.Quarantined && (quarantine || threshold > 0 && info.ConsecutiveFailures >= threshold) {
+		info.Quarantined = true
+		r.logger.Warn("query is put to quarantine", zap.Uint64("query_id", queryID),
+			zap.Uint64("consecutive_failures", info.ConsecutiveFailures), zap.Error(processErr))
+	}
+
+	if err := r.storage.SetQueryFailure(*info); err != nil {
+		return fmt.Errorf("failed to save query failure info: %w", err)
+	}
+
+	return nil
+}
+
+// resetQueryFailures removes failures info of the query after it has been processed successfully.
+func (r *Relayer) resetQueryFailures(queryID uint64) error {
+	_, found, err := r.storage.GetQueryFailure(queryID)
+	if err != nil {
+		return fmt.Errorf("failed to get query failure info: %w", err)
+	}
+	if !found {
+		return nil
+	}
+
+	if err := r.storage.RemoveQueryFailure(queryID); err != nil {
+		return fmt.Errorf("failed to remove query failure info: %w", err)
+	}
+
+	return nil
+}
+
+// failureBackoff returns the time to wait before processing a query after the given number of
+// consecutive failures. The backoff doubles with every failure and is limited by QueryFailureMaxBackoff.
+func (r *Relayer) failureBackoff(failures uint64) time.Duration {
+	backoff := r.cfg.QueryFailureBackoff
+	for i := uint64(1); i < failures && backoff < r.cfg.QueryFailureMaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > r.cfg.QueryFailureMaxBackoff {
+		backoff = r.cfg.QueryFailureMaxBackoff
+	}
+	return backoff
+}
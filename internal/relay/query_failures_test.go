@@ -0,0 +1,157 @@
+package relay
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// failuresStorage keeps the query failures in memory, the rest of the Storage methods are not implemented.
+type failuresStorage struct {
+	Storage
+	mutex    sync.Mutex
+	failures map[uint64]QueryFailureInfo
+}
+
+func (s *failuresStorage) GetQueryFailure(queryID uint64) (*QueryFailureInfo, bool, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	info, ok := s.failures[queryID]
+	return &info, ok, nil
+}
+
+func (s *failuresStorage) SetQueryFailure(info QueryFailureInfo) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	s.failures[info.QueryID] = info
+	return nil
+}
+
+func (s *failuresStorage) RemoveQueryFailure(queryID uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	delete(s.failures, queryID)
+	return nil
+}
+
+func newFailuresTestRelayer(threshold uint64) (*Relayer, *failuresStorage) {
+	store := &failuresStorage{failures: make(map[uint64]QueryFailureInfo)}
+	cfg := config.NeutronQueryRelayerConfig{
+		QueryFailureBackoff:      10 * time.Second,
+		QueryFailureMaxBackoff:   time.Minute,
+		QueryQuarantineThreshold: threshold,
+	}
+	return NewRelayer(cfg, nil, store, nil, nil, nil, nil, zap.NewNop()), store
+}
+
+func TestFailureBackoff(t *testing.T) {
+	r, _ := newFailuresTestRelayer(0)
+
+	tests := []struct {
+		failures uint64
+		expected time.Duration
+	}{
+		{failures: 1, expected: 10 * time.Second},
+		{failures: 2, expected: 20 * time.Second},
+		{failures: 3, expected: 40 * time.Second},
+		{failures: 4, expected: time.Minute},
+		{failures: 1000, expected: time.Minute},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.expected, r.failureBackoff(tt.failures), "failures: %d", tt.failures)
+	}
+}
+
+func TestQueryQuarantine(t *testing.T) {
+	query := neutrontypes.RegisteredQuery{Id: 1, QueryType: string(neutrontypes.InterchainQueryTypeKV)}
+	processErr := errors.New("failed to get proof")
+
+	t.Run("threshold", func(t *testing.T) {
+		r, store := newFailuresTestRelayer(3)
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, r.registerQueryFailure(query.Id, processErr, false))
+			assert.False(t, store.failures[query.Id].Quarantined)
+		}
+		require.NoError(t, r.registerQueryFailure(query.Id, processErr, false))
+		info := store.failures[query.Id]
+		assert.True(t, info.Quarantined)
+		assert.Equal(t, uint64(3), info.ConsecutiveFailures)
+		assert.Equal(t, processErr.Error(), info.LastError)
+
+		// the quarantined query stays skipped after its backoff expires
+		info.NextAttemptTime = time.Now().Add(-time.Second)
+		store.failures[query.Id] = info
+		backedOff, err := r.isQueryBackedOff(query)
+		require.NoError(t, err)
+		assert.True(t, backedOff)
+
+		// the released query is processed again
+		require.NoError(t, store.RemoveQueryFailure(query.Id))
+		backedOff, err = r.isQueryBackedOff(query)
+		require.NoError(t, err)
+		assert.False(t, backedOff)
+	})
+
+	t.Run("zero threshold disables quarantine", func(t *testing.T) {
+		r, store := newFailuresTestRelayer(0)
+
+		for i := 0; i < 100; i++ {
+			require.NoError(t, r.registerQueryFailure(query.Id, processErr, false))
+		}
+		assert.False(t, store.failures[query.Id].Quarantined)
+
+		// the query is backed off until the next attempt time and is processed again afterwards
+		backedOff, err := r.isQueryBackedOff(query)
+		require.NoError(t, err)
+		assert.True(t, backedOff)
+		info := store.failures[query.Id]
+		info.NextAttemptTime = time.Now().Add(-time.Second)
+		store.failures[query.Id] = info
+		backedOff, err = r.isQueryBackedOff(query)
+		require.NoError(t, err)
+		assert.False(t, backedOff)
+	})
+
+	t.Run("success resets the failures", func(t *testing.T) {
+		r, store := newFailuresTestRelayer(3)
+
+		for i := 0; i < 2; i++ {
+			require.NoError(t, r.registerQueryFailure(query.Id, processErr, false))
+		}
+		require.NoError(t, r.resetQueryFailures(query.Id))
+		assert.NotContains(t, store.failures, query.Id)
+
+		require.NoError(t, r.registerQueryFailure(query.Id, processErr, false))
+		assert.Equal(t, uint64(1), store.failures[query.Id].ConsecutiveFailures)
+		assert.False(t, store.failures[query.Id].Quarantined)
+	})
+
+	t.Run("updated filter releases the query", func(t *testing.T) {
+		r, store := newFailuresTestRelayer(0)
+		txQuery := neutrontypes.RegisteredQuery{Id: 2, QueryType: string(neutrontypes.InterchainQueryTypeTX), TransactionsFilter: `[{"field":"x"}]`}
+
+		filterErr := ErrInvalidTxFilter{Filter: txQuery.TransactionsFilter}
+		require.NoError(t, r.registerQueryFailure(txQuery.Id, filterErr, true))
+		backedOff, err := r.isQueryBackedOff(txQuery)
+		require.NoError(t, err)
+		assert.True(t, backedOff)
+
+		txQuery.TransactionsFilter = `[{"field":"transfer.amount","op":"gt","value":1}]`
+		backedOff, err = r.isQueryBackedOff(txQuery)
+		require.NoError(t, err)
+		assert.False(t, backedOff)
+		assert.NotContains(t, store.failures, txQuery.Id)
+	})
+}
@@ -3,10 +3,11 @@ package relay
 import (
 	"context"
 	"encoding/hex"
-	"encoding/json"
 	"errors"
 	"fmt"
-	"strings"
+	"slices"
+	"sync"
+	"sync/atomic"
 	"time"
 
 	tmtypes "github.com/cometbft/cometbft/types"
@@ -24,6 +25,9 @@ import (
 // TxHeight describes tendermint filter by tx.height that we use to get only actual txs
 const TxHeight = "tx.height"
 
+// clientHealthRecheckPeriod is how often the client health is checked while the submissions are paused
+const clientHealthRecheckPeriod = 5 * time.Second
+
 // Relayer is controller for the whole app:
 // 1. takes events from Neutron chain
 // 2. dispatches each query by type to fetch proof for the right query
@@ -36,6 +40,13 @@ type Relayer struct {
 	txProcessor TXProcessor
 	kvProcessor KVProcessor
 	targetChain *relayer.Chain
+	// clientHealth is checked before handing queries over to the workers, may be nil
+	clientHealth ClientHealthChecker
+
+	// draining is set once the Relayer stops accepting new tasks
+	draining atomic.Bool
+	// paused is set while the submissions are paused due to the unhealthy client
+	paused bool
 }
 
 func NewRelayer(
@@ -45,62 +56,364 @@ func NewRelayer(
 	txProcessor TXProcessor,
 	kvProcessor KVProcessor,
 	targetChain *relayer.Chain,
+	clientHealth ClientHealthChecker,
 	logger *zap.Logger,
 ) *Relayer {
 	return &Relayer{
-		cfg:         cfg,
-		txQuerier:   txQuerier,
-		logger:      logger,
-		storage:     store,
-		txProcessor: txProcessor,
-		kvProcessor: kvProcessor,
-		targetChain: targetChain,
+		cfg:          cfg,
+		txQuerier:    txQuerier,
+		logger:       logger,
+		storage:      store,
+		txProcessor:  txProcessor,
+		kvProcessor:  kvProcessor,
+		targetChain:  targetChain,
+		clientHealth: clientHealth,
 	}
 }
 
+// queryResult is the outcome of a single query processed by one of the Relayer's workers.
+type queryResult struct {
+	query neutrontypes.RegisteredQuery
+	err   error
+}
+
 // Run starts the relaying process: subscribes on the incoming interchain query messages from the
 // Neutron and performs the queries by interacting with the target chain and submitting them to
 // the Neutron chain.
+//
+// The queries are processed by a pool of cfg.WorkerPoolSize workers. Different queries are
+// processed in parallel, but the same query is never processed by more than one worker at a
+// time: if a task for a query arrives while the previous one is still in progress, it is held
+// back (only the most recent one is kept) and dispatched once the worker is done. KV queries which
+// are due at the same time are handed over to a worker together (up to cfg.KvBatchMaxQueries of
+// them), so their results are submitted in a single transaction.
+//
+// While Neutron's light client of the target chain is expired or frozen, no queries are handed over
+// to the workers since none of the proofs could be verified anyway; the tasks wait in the queue.
+//
+// ctx bounds the whole Relayer lifetime. Once acceptCtx is done, the Relayer drains: it stops
+// taking new tasks, lets the queries in flight finish (TX queries stop at the next block boundary)
+// and returns; the work that is still in progress when ctx is done is abandoned.
 func (r *Relayer) Run(
 	ctx context.Context,
-	queriesTasksQueue <-chan neutrontypes.RegisteredQuery, // Input tasks come from this channel
+	acceptCtx context.Context,
+	queriesTasksQueue TaskQueue, // Input tasks come from this queue
 	submittedTxsTasksQueue chan PendingSubmittedTxInfo, // Tasks for the TxSubmitChecker are sent to this channel
 ) error {
+	workersCtx, cancelWorkers := context.WithCancel(ctx)
+	readerCtx, cancelReader := context.WithCancel(acceptCtx)
+	var (
+		tasks   = make(chan neutrontypes.RegisteredQuery)
+		jobs    = make(chan []neutrontypes.RegisteredQuery)
+		results = make(chan queryResult)
+		wg      = &sync.WaitGroup{}
+	)
+	defer func() {
+		cancelReader()
+		cancelWorkers()
+		wg.Wait()
+	}()
+
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		r.readTasks(readerCtx, queriesTasksQueue, tasks)
+	}()
+
+	workersNum := r.cfg.WorkerPoolSize
+	if workersNum < 1 {
+		workersNum = 1
+	}
+	for i := 0; i < workersNum; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			r.runWorker(workersCtx, jobs, results, submittedTxsTasksQueue)
+		}()
+	}
+
+	// the client health is re-checked on every tick while the submissions are paused
+	recheckTicker := time.NewTicker(clientHealthRecheckPeriod)
+	defer recheckTicker.Stop()
+
+	var (
+		// inFlight contains IDs of the queries that are being processed by the workers.
+		inFlight = make(map[uint64]struct{})
+		// postponed contains the latest task for each query that arrived while the query was in flight.
+		postponed = make(map[uint64]neutrontypes.RegisteredQuery)
+		// ready is a FIFO of tasks that can be handed over to a worker right away, at most one per query.
+		ready []neutrontypes.RegisteredQuery
+		// accepting is closed once the Relayer starts draining.
+		accepting = acceptCtx.Done()
+	)
 	for {
-		var err error
+		if r.draining.Load() && len(inFlight) == 0 {
+			r.logger.Info("relayer drained, shutting down...", zap.Int("abandoned_tasks", len(ready)+len(postponed)+queriesTasksQueue.Len()))
+			return nil
+		}
+
+		var (
+			next     []neutrontypes.RegisteredQuery
+			jobsChan chan<- []neutrontypes.RegisteredQuery
+			input    = tasks
+		)
+		// We don't read new tasks until the ready ones are handed over to the workers, so the
+		// tasks queue still applies backpressure to the Subscriber. The only exception is a KV batch
+		// which isn't full yet: the KV queries waiting in the queue are let in to join it.
+		if len(ready) > 0 {
+			next = r.nextBatch(ready)
+			jobsChan = jobs
+			input = nil
+			if len(next) == len(ready) && len(next) < r.cfg.KvBatchMaxQueries &&
+				next[0].QueryType == string(neutrontypes.InterchainQueryTypeKV) && queriesTasksQueue.Len() > 0 {
+				jobsChan = nil
+				input = tasks
+			}
+		}
+		if r.draining.Load() {
+			jobsChan = nil
+			input = nil
+		}
+		var recheck <-chan time.Time
+		if r.submissionsPaused() {
+			jobsChan = nil
+			input = nil
+			recheck = recheckTicker.C
+		}
+
 		select {
-		case query := <-queriesTasksQueue:
-			start := time.Now()
-			neutronmetrics.SetSubscriberTaskQueueNumElements(len(queriesTasksQueue))
-			switch query.QueryType {
-			case string(neutrontypes.InterchainQueryTypeKV):
-				msg := &MessageKV{QueryId: query.Id, KVKeys: query.Keys}
-				err = r.processMessageKV(ctx, msg)
-			case string(neutrontypes.InterchainQueryTypeTX):
-				msg := &MessageTX{QueryId: query.Id, TransactionsFilter: query.TransactionsFilter}
-				err = r.processMessageTX(ctx, msg, submittedTxsTasksQueue)
-
-				var critErr ErrSubmitTxProofCritical
-				if errors.As(errors.Unwrap(err), &critErr) {
-					return err
-				}
-			default:
-				err = fmt.Errorf("unknown query type: %s", query.QueryType)
+		case query := <-input:
+			neutronmetrics.SetSubscriberTaskQueueNumElements(r.cfg.NeutronChain.ConnectionID, queriesTasksQueue.Len())
+			if _, ok := inFlight[query.Id]; ok {
+				r.logger.Debug("query is already being processed, postponing the task", zap.Uint64("query_id", query.Id))
+				postponed[query.Id] = query
+				continue
 			}
+			if i := slices.IndexFunc(ready, func(q neutrontypes.RegisteredQuery) bool { return q.Id == query.Id }); i >= 0 {
+				// the query is going to be handed over anyway, only the latest task is kept
+				ready[i] = query
+				continue
+			}
+			ready = append(ready, query)
+		case jobsChan <- next:
+			for _, query := range next {
+				inFlight[query.Id] = struct{}{}
+			}
+			ready = ready[len(next):]
+		case res := <-results:
+			delete(inFlight, res.query.Id)
 
-			if err != nil {
-				r.logger.Error("could not process message", zap.Uint64("query_id", query.Id), zap.Error(err))
-				neutronmetrics.AddFailedRequest(string(query.QueryType), time.Since(start).Seconds())
-			} else {
-				neutronmetrics.AddSuccessRequest(string(query.QueryType), time.Since(start).Seconds())
+			// with the "pause" policy the query is put to quarantine instead, see processQuery
+			var critErr ErrSubmitTxProofCritical
+			if errors.As(res.err, &critErr) && r.cfg.CriticalTxErrorPolicy != config.CriticalTxErrorPolicyPause {
+				return res.err
+			}
+
+			if query, ok := postponed[res.query.Id]; ok {
+				delete(postponed, res.query.Id)
+				ready = append(ready, query)
 			}
+		case <-recheck:
+		case <-accepting:
+			accepting = nil
+			cancelReader()
+			r.draining.Store(true)
+			r.logger.Info("stopped accepting new tasks, draining relayer...", zap.Int("queries_in_flight", len(inFlight)))
 		case <-ctx.Done():
-			r.logger.Info("context cancelled, shutting down relayer...")
+			abandoned := make([]uint64, 0, len(inFlight))
+			for queryID := range inFlight {
+				abandoned = append(abandoned, queryID)
+			}
+			r.logger.Info("context cancelled, shutting down relayer...", zap.Uint64s("abandoned_query_ids", abandoned))
 			return nil
 		}
 	}
 }
 
+// submissionsPaused returns true if Neutron's light client of the target chain can't be used to verify
+// proofs. The reason is logged once the submissions are paused and once they are resumed.
+func (r *Relayer) submissionsPaused() bool {
+	if r.clientHealth == nil {
+		return false
+	}
+
+	health := r.clientHealth.ClientHealth()
+	paused := !health.Healthy()
+	if paused != r.paused {
+		r.paused = paused
+		if paused {
+			r.logger.Error("submissions are paused since Neutron's light client of the target chain is not healthy",
+				zap.String("client_id", health.ClientID),
+				zap.String("client_status", health.Status),
+				zap.Time("expiry_time", health.ExpiryTime))
+		} else {
+			r.logger.Info("submissions are resumed, Neutron's light client of the target chain is healthy again",
+				zap.String("client_id", health.ClientID),
+				zap.String("client_status", health.Status))
+		}
+	}
+	return paused
+}
+
+// nextBatch returns the queries to be handed over to a worker next: either the first ready query,
+// or, if it's a KV one, the first ready KV queries up to cfg.KvBatchMaxQueries.
+func (r *Relayer) nextBatch(ready []neutrontypes.RegisteredQuery) []neutrontypes.RegisteredQuery {
+	batch := ready[:1]
+	if ready[0].QueryType != string(neutrontypes.InterchainQueryTypeKV) {
+		return batch
+	}
+
+	for _, query := range ready[1:] {
+		if len(batch) >= r.cfg.KvBatchMaxQueries || query.QueryType != string(neutrontypes.InterchainQueryTypeKV) {
+			break
+		}
+		batch = ready[:len(batch)+1]
+	}
+	// the batch is handed over to a worker, so it must not share the memory with the ready queue
+	return append([]neutrontypes.RegisteredQuery(nil), batch...)
+}
+
+// readTasks pops the tasks from the queue one by one and sends them to the tasks channel.
+func (r *Relayer) readTasks(ctx context.Context, queue TaskQueue, tasks chan<- neutrontypes.RegisteredQuery) {
+	for {
+		query, err := queue.Pop(ctx)
+		if err != nil {
+			return
+		}
+
+		select {
+		case tasks <- query:
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// runWorker processes the batches of queries coming from the jobs channel one by one and reports
+// the result of each query to the results channel.
+func (r *Relayer) runWorker(
+	ctx context.Context,
+	jobs <-chan []neutrontypes.RegisteredQuery,
+	results chan<- queryResult,
+	submittedTxsTasksQueue chan PendingSubmittedTxInfo,
+) {
+	for {
+		select {
+		case batch := <-jobs:
+			var errs []error
+			if len(batch) == 1 {
+				errs = []error{r.processQuery(ctx, batch[0], submittedTxsTasksQueue)}
+			} else {
+				errs = r.processKVBatch(ctx, batch)
+			}
+
+			for i, query := range batch {
+				select {
+				case results <- queryResult{query: query, err: errs[i]}:
+				case <-ctx.Done():
+					return
+				}
+			}
+		case <-ctx.Done():
+			return
+		}
+	}
+}
+
+// processQuery dispatches the query by its type and records the request metrics.
+func (r *Relayer) processQuery(
+	ctx context.Context,
+	query neutrontypes.RegisteredQuery,
+	submittedTxsTasksQueue chan PendingSubmittedTxInfo,
+) error {
+	if r.isQuerySkipped(query) {
+		return nil
+	}
+
+	var err error
+	start := time.Now()
+	switch query.QueryType {
+	case string(neutrontypes.InterchainQueryTypeKV):
+		msg := &MessageKV{QueryId: query.Id, KVKeys: query.Keys}
+		err = r.processMessageKV(ctx, msg)
+	case string(neutrontypes.InterchainQueryTypeTX):
+		msg := &MessageTX{QueryId: query.Id, TransactionsFilter: query.TransactionsFilter}
+		err = r.processMessageTX(ctx, msg, submittedTxsTasksQueue)
+	default:
+		err = fmt.Errorf("unknown query type: %s", query.QueryType)
+	}
+
+	r.trackQueryResult(ctx, query, start, err)
+	return err
+}
+
+// processKVBatch processes several KV queries at once, so their results are submitted together,
+// and records the request metrics of each query. It returns the error of each query by its position.
+func (r *Relayer) processKVBatch(ctx context.Context, queries []neutrontypes.RegisteredQuery) []error {
+	var (
+		errs    = make([]error, len(queries))
+		msgs    = make([]*MessageKV, 0, len(queries))
+		indexes = make([]int, 0, len(queries))
+	)
+	for i, query := range queries {
+		if r.isQuerySkipped(query) {
+			continue
+		}
+		msgs = append(msgs, &MessageKV{QueryId: query.Id, KVKeys: query.Keys})
+		indexes = append(indexes, i)
+	}
+	if len(msgs) == 0 {
+		return errs
+	}
+
+	r.logger.Debug("running processKVBatch", zap.Int("batch_size", len(msgs)))
+	start := time.Now()
+	batchErrs := r.kvProcessor.ProcessAndSubmitBatch(ctx, msgs)
+	for i, idx := range indexes {
+		errs[idx] = batchErrs[i]
+		r.trackQueryResult(ctx, queries[idx], start, errs[idx])
+	}
+	return errs
+}
+
+// isQuerySkipped returns true if the query must not be processed right now because of its failures.
+func (r *Relayer) isQuerySkipped(query neutrontypes.RegisteredQuery) bool {
+	backedOff, err := r.isQueryBackedOff(query)
+	if err != nil {
+		r.logger.Error("failed to check query failures", zap.Uint64("query_id", query.Id), zap.Error(err))
+	}
+	return backedOff
+}
+
+// trackQueryResult records the request metrics of the processed query and updates its failures.
+func (r *Relayer) trackQueryResult(ctx context.Context, query neutrontypes.RegisteredQuery, start time.Time, err error) {
+	if err != nil {
+		r.logger.Error("could not process message", zap.Uint64("query_id", query.Id), zap.Error(err))
+		neutronmetrics.AddFailedRequest(r.cfg.NeutronChain.ConnectionID, string(query.QueryType), time.Since(start).Seconds())
+
+		// Neither a query being due too early nor the relayer shutting down says anything about
+		// the query itself, so we don't back it off in such cases.
+		if !errors.Is(err, ErrQueryTooEarly) && ctx.Err() == nil {
+			// an invalid filter stays invalid until the query owner updates it, so there is no point in retrying
+			var (
+				critErr   ErrSubmitTxProofCritical
+				filterErr ErrInvalidTxFilter
+			)
+			quarantine := errors.As(err, &critErr) && r.cfg.CriticalTxErrorPolicy == config.CriticalTxErrorPolicyPause ||
+				errors.As(err, &filterErr)
+			if errFailure := r.registerQueryFailure(query.Id, err, quarantine); errFailure != nil {
+				r.logger.Error("failed to register query failure", zap.Uint64("query_id", query.Id), zap.Error(errFailure))
+			}
+		}
+		return
+	}
+
+	neutronmetrics.AddSuccessRequest(r.cfg.NeutronChain.ConnectionID, string(query.QueryType), time.Since(start).Seconds())
+	if errFailure := r.resetQueryFailures(query.Id); errFailure != nil {
+		r.logger.Error("failed to reset query failures", zap.Uint64("query_id", query.Id), zap.Error(errFailure))
+	}
+}
+
 // processMessageKV handles an incoming KV interchain query message and passes it to the kvProcessor for further processing.
 func (r *Relayer) processMessageKV(ctx context.Context, m *MessageKV) error {
 	r.logger.Debug("running processMessageKV for msg", zap.Uint64("query_id", m.QueryId))
@@ -125,6 +438,23 @@ func (r *Relayer) processMessageTX(ctx context.Context, m *MessageTX, submittedT
 	txs, errs := r.txQuerier.SearchTransactions(cancelCtx, queryString)
 	lastProcessedHeight := uint64(0)
 	for tx := range txs {
+		if tx.Tx == nil {
+			// a checkpoint, the blocks without matching transactions don't have to be searched again
+			err := r.storage.SetLastQueryHeight(m.QueryId, tx.Height)
+			if err != nil {
+				return fmt.Errorf("failed to save last height of query: %w", err)
+			}
+			lastProcessedHeight = tx.Height
+
+			if r.draining.Load() {
+				r.logger.Info("relayer is draining, stopping tx query processing at the checkpoint",
+					zap.Uint64("query_id", m.QueryId),
+					zap.Uint64("processed_height", lastProcessedHeight))
+				return nil
+			}
+			continue
+		}
+
 		if tx.Height > lastProcessedHeight && lastProcessedHeight > 0 {
 			err := r.storage.SetLastQueryHeight(m.QueryId, lastProcessedHeight)
 			if err != nil {
@@ -134,6 +464,14 @@ func (r *Relayer) processMessageTX(ctx context.Context, m *MessageTX, submittedT
 				zap.Uint64("query_id", m.QueryId),
 				zap.Uint64("processed_height", lastProcessedHeight),
 				zap.Uint64("next_height_to_process", tx.Height))
+
+			// the rest of the blocks will be processed after the restart
+			if r.draining.Load() {
+				r.logger.Info("relayer is draining, stopping tx query processing at the block boundary",
+					zap.Uint64("query_id", m.QueryId),
+					zap.Uint64("processed_height", lastProcessedHeight))
+				return nil
+			}
 		}
 		lastProcessedHeight = tx.Height
 
@@ -183,14 +521,7 @@ func (r *Relayer) buildTxQuery(ctx context.Context, m *MessageTX) (string, error
 		return "", fmt.Errorf("could not get last query height: %w", err)
 	}
 
-	var params neutrontypes.TransactionsFilter
-	if err = json.Unmarshal([]byte(m.TransactionsFilter), &params); err != nil {
-		return "", fmt.Errorf("could not unmarshal transactions filter: %w", err)
-	}
-	// add filter by tx.height (tx.height>n)
-	params = append(params, neutrontypes.TransactionsFilterItem{Field: TxHeight, Op: "gt", Value: queryLastHeight})
-
-	queryString, err := queryFromTxFilter(params)
+	queryString, err := CompileTxFilter(m.TransactionsFilter, queryLastHeight, 0)
 	if err != nil {
 		return "", fmt.Errorf("failed to process tx query params: %w", err)
 	}
@@ -226,41 +557,3 @@ func (r *Relayer) getLastQueryHeight(ctx context.Context, queryID uint64) (uint6
 
 	return height, nil
 }
-
-// QueryFromTxFilter creates query from transactions filter like
-// `key1{=,>,>=,<,<=}value1 AND key2{=,>,>=,<,<=}value2 AND ...`
-func queryFromTxFilter(params neutrontypes.TransactionsFilter) (string, error) {
-	queryParamsList := make([]string, 0, len(params))
-	for _, row := range params {
-		sign, err := getOpSign(row.Op)
-		if err != nil {
-			return "", err
-		}
-		switch r := row.Value.(type) {
-		case string:
-			queryParamsList = append(queryParamsList, fmt.Sprintf("%s%s'%s'", row.Field, sign, r))
-		case float64:
-			queryParamsList = append(queryParamsList, fmt.Sprintf("%s%s%d", row.Field, sign, uint64(r)))
-		case uint64:
-			queryParamsList = append(queryParamsList, fmt.Sprintf("%s%s%d", row.Field, sign, r))
-		}
-	}
-	return strings.Join(queryParamsList, " AND "), nil
-}
-
-func getOpSign(op string) (string, error) {
-	switch strings.ToLower(op) {
-	case "eq":
-		return "=", nil
-	case "gt":
-		return ">", nil
-	case "gte":
-		return ">=", nil
-	case "lt":
-		return "<", nil
-	case "lte":
-		return "<=", nil
-	default:
-		return "", fmt.Errorf("unsupported operator %s", op)
-	}
-}
@@ -0,0 +1,421 @@
+package relay
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// chanQueue is a FIFO TaskQueue.
+type chanQueue chan neutrontypes.RegisteredQuery
+
+func (q chanQueue) Push(ctx context.Context, query neutrontypes.RegisteredQuery, _ uint64) error {
+	select {
+	case q <- query:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (q chanQueue) Pop(ctx context.Context) (neutrontypes.RegisteredQuery, error) {
+	select {
+	case query := <-q:
+		return query, nil
+	case <-ctx.Done():
+		return neutrontypes.RegisteredQuery{}, ctx.Err()
+	}
+}
+
+func (q chanQueue) Len() int {
+	return len(q)
+}
+
+func (q chanQueue) Tasks() []TaskInfo {
+	return nil
+}
+
+// heightsStorage keeps the last query heights and the query failures in memory.
+type heightsStorage struct {
+	*failuresStorage
+	heights sync.Map
+}
+
+func (s *heightsStorage) GetLastQueryHeight(queryID uint64) (uint64, bool, error) {
+	height, ok := s.heights.Load(queryID)
+	if !ok {
+		return 0, false, nil
+	}
+	return height.(uint64), true, nil
+}
+
+func (s *heightsStorage) SetLastQueryHeight(queryID uint64, block uint64) error {
+	s.heights.Store(queryID, block)
+	return nil
+}
+
+func (s *heightsStorage) TxExists(uint64, string) (bool, error) {
+	return false, nil
+}
+
+// processedQuery is a processed KV query, the version of the task is passed in the path of its first key.
+type processedQuery struct {
+	queryID uint64
+	version string
+}
+
+// kvProcessor records the processed queries. If started is set, the processing of every query is
+// reported to it, and if release is set, every query is processed until a value is received from it.
+type kvProcessor struct {
+	started chan uint64
+	release chan struct{}
+	delay   time.Duration
+
+	mutex     sync.Mutex
+	active    map[uint64]int
+	maxActive int
+	// overlaps is the number of times a query was processed while it was still being processed
+	overlaps  int
+	processed []processedQuery
+	batches   [][]uint64
+}
+
+func newKVProcessor() *kvProcessor {
+	return &kvProcessor{active: make(map[uint64]int)}
+}
+
+func (p *kvProcessor) ProcessAndSubmit(ctx context.Context, m *MessageKV) error {
+	p.mutex.Lock()
+	p.active[m.QueryId]++
+	if p.active[m.QueryId] > 1 {
+		p.overlaps++
+	}
+	active := 0
+	for _, n := range p.active {
+		active += n
+	}
+	if active > p.maxActive {
+		p.maxActive = active
+	}
+	p.processed = append(p.processed, processedQuery{queryID: m.QueryId, version: m.KVKeys[0].Path})
+	p.mutex.Unlock()
+
+	if p.started != nil {
+		p.started <- m.QueryId
+	}
+	if p.release != nil {
+		select {
+		case <-p.release:
+		case <-ctx.Done():
+		}
+	}
+	time.Sleep(p.delay)
+
+	p.mutex.Lock()
+	p.active[m.QueryId]--
+	p.mutex.Unlock()
+	return nil
+}
+
+func (p *kvProcessor) ProcessAndSubmitBatch(ctx context.Context, msgs []*MessageKV) []error {
+	ids := make([]uint64, 0, len(msgs))
+	for _, m := range msgs {
+		ids = append(ids, m.QueryId)
+	}
+	p.mutex.Lock()
+	p.batches = append(p.batches, ids)
+	p.mutex.Unlock()
+
+	errs := make([]error, len(msgs))
+	for i, m := range msgs {
+		errs[i] = p.ProcessAndSubmit(ctx, m)
+	}
+	return errs
+}
+
+func (p *kvProcessor) stats() (maxActive int, overlaps int, processed []processedQuery) {
+	p.mutex.Lock()
+	defer p.mutex.Unlock()
+
+	return p.maxActive, p.overlaps, append([]processedQuery(nil), p.processed...)
+}
+
+// txQuerier sends the same transaction for every query.
+type txQuerier struct{}
+
+func (txQuerier) SearchTransactions(context.Context, string) (<-chan Transaction, <-chan error) {
+	txs := make(chan Transaction, 1)
+	errs := make(chan error, 1)
+	txs <- Transaction{Tx: &neutrontypes.TxValue{Data: []byte("tx")}, Height: 10}
+	close(txs)
+	close(errs)
+	return txs, errs
+}
+
+// txProcessor fails to submit every transaction with the error.
+type txProcessor struct {
+	err error
+}
+
+func (p txProcessor) ProcessAndSubmit(context.Context, uint64, Transaction, chan PendingSubmittedTxInfo) error {
+	return p.err
+}
+
+func kvQuery(id uint64, version string) neutrontypes.RegisteredQuery {
+	return neutrontypes.RegisteredQuery{
+		Id:        id,
+		QueryType: string(neutrontypes.InterchainQueryTypeKV),
+		Keys:      []*neutrontypes.KVKey{{Path: version}},
+	}
+}
+
+func txQuery(id uint64) neutrontypes.RegisteredQuery {
+	return neutrontypes.RegisteredQuery{Id: id, QueryType: string(neutrontypes.InterchainQueryTypeTX), TransactionsFilter: `[]`}
+}
+
+func newRunTestRelayer(cfg config.NeutronQueryRelayerConfig, kv KVProcessor, txp TXProcessor) (*Relayer, *heightsStorage) {
+	cfg.NeutronChain = &config.NeutronChainConfig{ConnectionID: "connection-0"}
+	cfg.QueryFailureBackoff = time.Hour
+	cfg.QueryFailureMaxBackoff = time.Hour
+	store := &heightsStorage{failuresStorage: &failuresStorage{failures: make(map[uint64]QueryFailureInfo)}}
+	return NewRelayer(cfg, txQuerier{}, store, txp, kv, nil, nil, zap.NewNop()), store
+}
+
+// startRelayer runs the relayer in background. The returned stop function makes the relayer drain
+// and returns the result of Run; done receives it if Run returns on its own.
+func startRelayer(t *testing.T, r *Relayer, queue TaskQueue) (stop func() error, done <-chan error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	acceptCtx, stopAccepting := context.WithCancel(ctx)
+	result := make(chan error, 1)
+	go func() {
+		result <- r.Run(ctx, acceptCtx, queue, make(chan PendingSubmittedTxInfo, 100))
+	}()
+	t.Cleanup(cancel)
+
+	return func() error {
+		stopAccepting()
+		select {
+		case err := <-result:
+			return err
+		case <-time.After(5 * time.Second):
+			t.Fatal("relayer hasn't drained in time")
+			return nil
+		}
+	}, result
+}
+
+// waitStarted returns the IDs of the n queries the processor has started to process.
+func waitStarted(t *testing.T, p *kvProcessor, n int) []uint64 {
+	ids := make([]uint64, 0, n)
+	for len(ids) < n {
+		select {
+		case id := <-p.started:
+			ids = append(ids, id)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("only %d of %d queries have been started", len(ids), n)
+		}
+	}
+	return ids
+}
+
+func TestRelayerWorkerPool(t *testing.T) {
+	kv := newKVProcessor()
+	kv.started = make(chan uint64)
+	kv.release = make(chan struct{})
+	r, _ := newRunTestRelayer(config.NeutronQueryRelayerConfig{WorkerPoolSize: 3, KvBatchMaxQueries: 1}, kv, nil)
+	queue := make(chanQueue, 10)
+	for id := uint64(1); id <= 5; id++ {
+		require.NoError(t, queue.Push(context.Background(), kvQuery(id, "v1"), 0))
+	}
+	stop, _ := startRelayer(t, r, queue)
+
+	// all the workers are busy, so the rest of the queries wait
+	assert.ElementsMatch(t, []uint64{1, 2, 3}, waitStarted(t, kv, 3))
+	select {
+	case id := <-kv.started:
+		t.Fatalf("query %d has been started while all the workers are busy", id)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	kv.release <- struct{}{}
+	assert.Len(t, waitStarted(t, kv, 1), 1)
+	close(kv.release)
+	waitStarted(t, kv, 1)
+	require.NoError(t, stop())
+
+	maxActive, overlaps, processed := kv.stats()
+	assert.Equal(t, 3, maxActive)
+	assert.Zero(t, overlaps)
+	assert.Len(t, processed, 5)
+}
+
+func TestRelayerPostponesQueryInFlight(t *testing.T) {
+	kv := newKVProcessor()
+	kv.started = make(chan uint64)
+	kv.release = make(chan struct{})
+	r, _ := newRunTestRelayer(config.NeutronQueryRelayerConfig{WorkerPoolSize: 2, KvBatchMaxQueries: 1}, kv, nil)
+	queue := make(chanQueue, 10)
+	stop, _ := startRelayer(t, r, queue)
+
+	require.NoError(t, queue.Push(context.Background(), kvQuery(1, "v1"), 0))
+	assert.Equal(t, []uint64{1}, waitStarted(t, kv, 1))
+
+	// the tasks are read in order, so both new tasks of the query 1 are postponed by the time the query 2
+	// is started by the free worker, and only the latest one is kept
+	for _, query := range []neutrontypes.RegisteredQuery{kvQuery(1, "v2"), kvQuery(1, "v3"), kvQuery(2, "v1")} {
+		require.NoError(t, queue.Push(context.Background(), query, 0))
+	}
+	assert.Equal(t, []uint64{2}, waitStarted(t, kv, 1))
+
+	// the postponed task is re-queued once the query is done
+	kv.release <- struct{}{}
+	kv.release <- struct{}{}
+	assert.Equal(t, []uint64{1}, waitStarted(t, kv, 1))
+	close(kv.release)
+	require.NoError(t, stop())
+
+	_, overlaps, processed := kv.stats()
+	assert.Zero(t, overlaps)
+	assert.ElementsMatch(t, []processedQuery{{1, "v1"}, {2, "v1"}, {1, "v3"}}, processed)
+}
+
+func TestRelayerSerializesConcurrentTasksOfQuery(t *testing.T) {
+	kv := newKVProcessor()
+	kv.delay = time.Millisecond
+	r, _ := newRunTestRelayer(config.NeutronQueryRelayerConfig{WorkerPoolSize: 4, KvBatchMaxQueries: 3}, kv, nil)
+	queue := make(chanQueue, 10)
+	stop, _ := startRelayer(t, r, queue)
+
+	// the same query is submitted by two producers at once along with the other queries
+	var wg sync.WaitGroup
+	for producer := 0; producer < 2; producer++ {
+		wg.Add(1)
+		go func(other uint64) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				assert.NoError(t, queue.Push(context.Background(), kvQuery(1, "v1"), 0))
+				assert.NoError(t, queue.Push(context.Background(), kvQuery(other, "v1"), 0))
+			}
+		}(uint64(producer + 2))
+	}
+	wg.Wait()
+	require.Eventually(t, func() bool { return queue.Len() == 0 }, 5*time.Second, time.Millisecond)
+	require.NoError(t, stop())
+
+	_, overlaps, processed := kv.stats()
+	assert.Zero(t, overlaps)
+	assert.NotEmpty(t, processed)
+	for _, batch := range kv.batches {
+		assert.LessOrEqual(t, len(batch), 3)
+	}
+}
+
+func TestRelayerCriticalError(t *testing.T) {
+	tests := []struct {
+		policy string
+		// stops is true if the relayer is expected to stop with the error
+		stops bool
+	}{
+		{policy: config.CriticalTxErrorPolicyExit, stops: true},
+		{policy: config.CriticalTxErrorPolicyPause, stops: false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			critErr := NewErrSubmitTxProofCritical(assert.AnError)
+			kv := newKVProcessor()
+			r, store := newRunTestRelayer(config.NeutronQueryRelayerConfig{
+				WorkerPoolSize:        2,
+				KvBatchMaxQueries:     1,
+				CriticalTxErrorPolicy: tt.policy,
+			}, kv, txProcessor{err: critErr})
+			queue := make(chanQueue, 10)
+			require.NoError(t, queue.Push(context.Background(), txQuery(1), 0))
+			stop, done := startRelayer(t, r, queue)
+
+			if tt.stops {
+				select {
+				case err := <-done:
+					assert.ErrorIs(t, err, critErr)
+				case <-time.After(5 * time.Second):
+					t.Fatal("relayer hasn't stopped on the critical error")
+				}
+				return
+			}
+
+			// the query is put to quarantine and the relayer goes on with the other queries
+			require.Eventually(t, func() bool {
+				info, found, _ := store.GetQueryFailure(1)
+				return found && info.Quarantined
+			}, 5*time.Second, time.Millisecond)
+			require.NoError(t, queue.Push(context.Background(), kvQuery(2, "v1"), 0))
+			require.Eventually(t, func() bool {
+				_, _, processed := kv.stats()
+				return len(processed) == 1
+			}, 5*time.Second, time.Millisecond)
+			assert.NoError(t, stop())
+		})
+	}
+}
+
+func TestNextBatch(t *testing.T) {
+	tests := []struct {
+		name     string
+		maxBatch int
+		ready    []neutrontypes.RegisteredQuery
+		expected []uint64
+	}{
+		{
+			name:     "kv queries up to the batch limit",
+			maxBatch: 3,
+			ready:    []neutrontypes.RegisteredQuery{kvQuery(1, "v1"), kvQuery(2, "v1"), kvQuery(3, "v1"), kvQuery(4, "v1")},
+			expected: []uint64{1, 2, 3},
+		},
+		{
+			name:     "tx query is handed over alone",
+			maxBatch: 3,
+			ready:    []neutrontypes.RegisteredQuery{txQuery(1), kvQuery(2, "v1")},
+			expected: []uint64{1},
+		},
+		{
+			name:     "kv batch stops at a tx query",
+			maxBatch: 3,
+			ready:    []neutrontypes.RegisteredQuery{kvQuery(1, "v1"), txQuery(2), kvQuery(3, "v1")},
+			expected: []uint64{1},
+		},
+		{
+			name:     "batching disabled",
+			maxBatch: 1,
+			ready:    []neutrontypes.RegisteredQuery{kvQuery(1, "v1"), kvQuery(2, "v1")},
+			expected: []uint64{1},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := NewRelayer(config.NeutronQueryRelayerConfig{KvBatchMaxQueries: tt.maxBatch}, nil, nil, nil, nil, nil, nil, zap.NewNop())
+			ready := append([]neutrontypes.RegisteredQuery(nil), tt.ready...)
+
+			batch := r.nextBatch(ready)
+			ids := make([]uint64, 0, len(batch))
+			for _, query := range batch {
+				ids = append(ids, query.Id)
+			}
+			assert.Equal(t, tt.expected, ids)
+
+			// a kv batch doesn't share the memory with the ready queue
+			if len(batch) > 1 {
+				batch[0].Id = 100
+				assert.Equal(t, tt.ready[0].Id, ready[0].Id)
+			}
+		})
+	}
+}
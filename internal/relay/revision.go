@@ -0,0 +1,7 @@
+package relay
+
+// TargetRevision provides the revision number of the target chain the relayer works in. It may change while
+// the relayer is running, e.g. after an upgrade of the target chain that bumps the chain ID revision.
+type TargetRevision interface {
+	Revision() uint64
+}
@@ -15,6 +15,8 @@ type PendingSubmittedTxInfo struct {
 }
 
 type UnsuccessfulTxInfo struct {
+	// ConnectionID is the Neutron's side connection ID of the query. It's filled in by the api and is not stored
+	ConnectionID string `json:"connection_id,omitempty"`
 	// QueryID is the query_id transactions was submitted for
 	QueryID uint64 `json:"query_id"`
 	// SubmittedTxHash is the hash of a transaction we fetched from the remote chain
@@ -48,8 +50,48 @@ const (
 	Committed SubmittedTxStatus = "Committed"
 	// ErrorOnCommit describes error during commit operation
 	ErrorOnCommit SubmittedTxStatus = "ErrorOnCommit"
+	// DryRun describes tx which was built but not broadcast because the relayer runs in the dry run mode
+	DryRun SubmittedTxStatus = "DryRun"
 )
 
+// QueryFailureInfo contains information about consecutive processing failures of a query
+type QueryFailureInfo struct {
+	// ConnectionID is the Neutron's side connection ID of the query. It's filled in by the api and is not stored
+	ConnectionID string `json:"connection_id,omitempty"`
+	// QueryID is the query_id of the failing query
+	QueryID uint64 `json:"query_id"`
+	// ConsecutiveFailures is the number of failed attempts to process the query since the last successful one
+	ConsecutiveFailures uint64 `json:"consecutive_failures"`
+	// LastError is the error message of the last failed attempt
+	LastError string `json:"last_error"`
+	// LastFailureTime is the time of the last failed attempt
+	LastFailureTime time.Time `json:"last_failure_time"`
+	// NextAttemptTime is the time before which the query is not going to be processed
+	NextAttemptTime time.Time `json:"next_attempt_time"`
+	// Quarantined is true if the query is not going to be processed until it's released manually
+	// or, in case of an invalid transactions filter, until the filter is updated
+	Quarantined bool `json:"quarantined"`
+	// InvalidTxFilter is the reason why the transactions filter of the query can't be used, if that's why the query fails
+	InvalidTxFilter *ErrInvalidTxFilter `json:"invalid_tx_filter,omitempty"`
+}
+
+// CriticalTxErrorInfo contains information about the latest critical tx submission error of a query,
+// i.e. an error which doesn't match the IgnoreErrorsRegex
+type CriticalTxErrorInfo struct {
+	// ConnectionID is the Neutron's side connection ID of the query. It's filled in by the api and is not stored
+	ConnectionID string `json:"connection_id,omitempty"`
+	// QueryID is the query_id the tx was submitted for
+	QueryID uint64 `json:"query_id"`
+	// SubmittedTxHash is the hash of a transaction we fetched from the remote chain
+	SubmittedTxHash string `json:"submitted_tx_hash"`
+	// ErrorTime is the time when the error happened
+	ErrorTime time.Time `json:"error_time"`
+	// Policy is the critical tx error policy applied to the error
+	Policy string `json:"policy"`
+	// Message is the error message
+	Message string `json:"message"`
+}
+
 // Storage is local storage we use to store queries history: known queries, know transactions and its statuses
 type Storage interface {
 	GetAllPendingTxs() ([]*PendingSubmittedTxInfo, error)
@@ -59,5 +101,12 @@ type Storage interface {
 	SetLastQueryHeight(queryID uint64, block uint64) error
 	SetTxStatus(queryID uint64, hash string, neutronHash string, status SubmittedTxInfo, processedTx *Transaction) (err error)
 	TxExists(queryID uint64, hash string) (exists bool, err error)
+	GetQueryFailure(queryID uint64) (info *QueryFailureInfo, found bool, err error)
+	SetQueryFailure(info QueryFailureInfo) error
+	RemoveQueryFailure(queryID uint64) error
+	GetAllQuarantinedQueries() ([]*QueryFailureInfo, error)
+	SetCriticalTxError(info CriticalTxErrorInfo) error
+	RemoveCriticalTxError(queryID uint64) error
+	GetAllCriticalTxErrors() ([]*CriticalTxErrorInfo, error)
 	Close() error
 }
@@ -8,8 +8,19 @@ import (
 	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
 )
 
+// KVProof is the result of a single KV query with its proofs
+type KVProof struct {
+	QueryID uint64
+	Proof   []*neutrontypes.StorageValue
+}
+
 // Submitter knows how to submit proof to the chain
 type Submitter interface {
 	SubmitKVProof(ctx context.Context, height, revision, queryId uint64, proof []*neutrontypes.StorageValue, updateClientMsg sdk.Msg) error
+	// SubmitKVProofs submits the results of several KV queries obtained on the same height in as few
+	// transactions as possible. It returns the submission error of each proof by its position.
+	SubmitKVProofs(ctx context.Context, height, revision uint64, proofs []KVProof, updateClientMsg sdk.Msg) []error
 	SubmitTxProof(ctx context.Context, queryId uint64, proof *neutrontypes.Block) (string, error)
+	// SubmitClientUpdate submits a MsgUpdateClient alone, without any query results.
+	SubmitClientUpdate(ctx context.Context, updateClientMsg sdk.Msg) error
 }
@@ -4,14 +4,14 @@ import (
 	"context"
 
 	"github.com/neutron-org/neutron/x/interchainqueries/types"
-	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
 )
 
 // Subscriber is an interface that subscribes to Neutron and provides chain data in real time.
 type Subscriber interface {
-	// Subscribe starts sending neutrontypes.RegisteredQuery values to the tasks channel when
+	// Subscribe starts pushing neutrontypes.RegisteredQuery values to the tasks queue when
 	// respective queries need to be updated.
-	Subscribe(ctx context.Context, tasks chan neutrontypes.RegisteredQuery) error
+	Subscribe(ctx context.Context, tasks TaskQueue) error
+	QueryFetcher
 }
 
 // MessageKV contains params of a KV interchain query.
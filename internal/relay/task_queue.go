@@ -0,0 +1,44 @@
+package relay
+
+import (
+	"context"
+	"time"
+
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// TaskQueue is a handoff between the Subscriber and the Relayer. The Subscriber pushes the queries
+// that need to be updated into it, and the Relayer pops them in the order defined by the queue.
+type TaskQueue interface {
+	// Push adds a task for the query. currentHeight is the Neutron height at which the query was
+	// found to be due. Push blocks while the queue is full.
+	Push(ctx context.Context, query neutrontypes.RegisteredQuery, currentHeight uint64) error
+	// Pop removes and returns the task that must be served next. Pop blocks while the queue is empty.
+	Pop(ctx context.Context) (neutrontypes.RegisteredQuery, error)
+	// Len returns the number of tasks in the queue.
+	Len() int
+	// Tasks returns a snapshot of the queue contents in the order the tasks are going to be served.
+	Tasks() []TaskInfo
+}
+
+// TaskInfo describes a task waiting in the TaskQueue.
+type TaskInfo struct {
+	// ConnectionID is the Neutron's side connection ID of the query.
+	ConnectionID string `json:"connection_id"`
+	// QueryID is the ID of the query.
+	QueryID uint64 `json:"query_id"`
+	// QueryType is the type of the query (kv or tx).
+	QueryType string `json:"query_type"`
+	// Owner is the address of the query owner.
+	Owner string `json:"owner"`
+	// DueHeight is the Neutron height starting from which the query needs to be updated.
+	DueHeight uint64 `json:"due_height"`
+	// EnqueuedHeight is the Neutron height at which the task was added to the queue.
+	EnqueuedHeight uint64 `json:"enqueued_height"`
+	// OverdueBlocks is the number of blocks the query was overdue by when the task was added to the queue.
+	OverdueBlocks uint64 `json:"overdue_blocks"`
+	// Weight is the priority weight of the query owner.
+	Weight float64 `json:"weight"`
+	// EnqueuedAt is the time the task was added to the queue.
+	EnqueuedAt time.Time `json:"enqueued_at"`
+}
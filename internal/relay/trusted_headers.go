@@ -2,11 +2,45 @@ package relay
 
 import (
 	"context"
+	"fmt"
+
 	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
 )
 
+// NewErrHeaderVerification creates a new ErrHeaderVerification.
+func NewErrHeaderVerification(height uint64, trustedHeight uint64, details error) ErrHeaderVerification {
+	return ErrHeaderVerification{Height: height, TrustedHeight: trustedHeight, details: details}
+}
+
+// ErrHeaderVerification is an error type that represents a target chain header which failed the local light
+// client verification against a consensus state of Neutron's client. It means the target chain RPC node serves
+// headers Neutron is going to reject, e.g. the node is faulty, malicious or on a fork.
+type ErrHeaderVerification struct {
+	// Height is the height of the header.
+	Height uint64
+	// TrustedHeight is the height of the consensus state the header was verified against.
+	TrustedHeight uint64
+	// details is the inner error.
+	details error
+}
+
+// Error implements the error interface.
+func (e ErrHeaderVerification) Error() string {
+	return fmt.Sprintf("target chain header at height %d failed verification against trusted height %d: %s",
+		e.Height, e.TrustedHeight, e.details.Error())
+}
+
+// Unwrap returns the inner error.
+func (e ErrHeaderVerification) Unwrap() error {
+	return e.details
+}
+
 // TrustedHeaderFetcher able to get trusted headers for a given height
 type TrustedHeaderFetcher interface {
 	// Fetch returns only one trusted Header for specified height
 	Fetch(ctx context.Context, height uint64) (*tmclient.Header, error)
+	// TrackClientUpdate lets the fetcher know that Neutron's client with the given ID has been updated with the header
+	TrackClientUpdate(clientID string, header *tmclient.Header)
+	// Run keeps the fetcher's view of Neutron's client up to date until ctx is done
+	Run(ctx context.Context)
 }
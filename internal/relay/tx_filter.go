@@ -0,0 +1,204 @@
+package relay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/cometbft/cometbft/libs/pubsub/query"
+
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// Operators of a transactions filter item.
+const (
+	TxFilterOpEq       = "eq"
+	TxFilterOpGt       = "gt"
+	TxFilterOpGte      = "gte"
+	TxFilterOpLt       = "lt"
+	TxFilterOpLte      = "lte"
+	TxFilterOpContains = "contains"
+	TxFilterOpExists   = "exists"
+)
+
+// txFilterForbiddenFieldChars are the characters a CometBFT query tag can't contain.
+const txFilterForbiddenFieldChars = " \t\n\r\\()\"'=><"
+
+// ErrInvalidTxFilter is returned when a transactions filter of a query can't be translated into a
+// CometBFT query. The filter is not going to become valid until the query owner updates it.
+type ErrInvalidTxFilter struct {
+	// Filter is the transactions filter of the query
+	Filter string `json:"filter"`
+	// Item is the position of the invalid item in the filter, -1 if the filter is invalid as a whole
+	Item int `json:"item"`
+	// Field is the field of the invalid item
+	Field string `json:"field,omitempty"`
+	// Op is the operator of the invalid item
+	Op string `json:"op,omitempty"`
+	// Reason describes what's wrong with the filter
+	Reason string `json:"reason"`
+}
+
+// Error implements the error interface.
+func (e ErrInvalidTxFilter) Error() string {
+	if e.Item < 0 {
+		return "invalid transactions filter: " + e.Reason
+	}
+	return fmt.Sprintf("invalid transactions filter item #%d (field=%q, op=%q): %s", e.Item, e.Field, e.Op, e.Reason)
+}
+
+// CompileTxFilter translates the transactions filter of a query into a CometBFT query like
+// `key1{=,>,>=,<,<=}value1 AND key2 CONTAINS 'value2' AND key3 EXISTS AND ...` which only matches
+// the transactions included after afterHeight and, unless untilHeight is 0, not later than untilHeight.
+// It returns ErrInvalidTxFilter if any item of the filter can't be expressed in the CometBFT query grammar.
+func CompileTxFilter(filter string, afterHeight, untilHeight uint64) (string, error) {
+	var params neutrontypes.TransactionsFilter
+	// keep the numbers as they are, a float64 can't hold every uint64
+	decoder := json.NewDecoder(bytes.NewReader([]byte(filter)))
+	decoder.UseNumber()
+	if err := decoder.Decode(&params); err != nil {
+		return "", ErrInvalidTxFilter{Filter: filter, Item: -1, Reason: fmt.Sprintf("malformed json: %s", err)}
+	}
+	// add filter by tx.height (tx.height>n)
+	params = append(params, neutrontypes.TransactionsFilterItem{Field: TxHeight, Op: TxFilterOpGt, Value: afterHeight})
+	if untilHeight > 0 {
+		params = append(params, neutrontypes.TransactionsFilterItem{Field: TxHeight, Op: TxFilterOpLte, Value: untilHeight})
+	}
+
+	conditions := make([]string, 0, len(params))
+	for i, item := range params {
+		condition, reason := compileTxFilterItem(item)
+		if reason != "" {
+			return "", ErrInvalidTxFilter{Filter: filter, Item: i, Field: item.Field, Op: item.Op, Reason: reason}
+		}
+		conditions = append(conditions, condition)
+	}
+
+	queryString := strings.Join(conditions, " AND ")
+	// the compiled query must be accepted by the CometBFT itself
+	if _, err := query.New(queryString); err != nil {
+		return "", ErrInvalidTxFilter{Filter: filter, Item: -1, Reason: fmt.Sprintf("rejected by the query parser: %s", err)}
+	}
+	return queryString, nil
+}
+
+// compileTxFilterItem translates a single filter item into a query condition. It returns a non-empty
+// reason if the item is invalid.
+func compileTxFilterItem(item neutrontypes.TransactionsFilterItem) (string, string) {
+	if item.Field == "" {
+		return "", "empty field"
+	}
+	if strings.ContainsAny(item.Field, txFilterForbiddenFieldChars) {
+		return "", fmt.Sprintf("field must not contain whitespaces or any of %q", `\()"'=><`)
+	}
+
+	op := strings.ToLower(item.Op)
+	if op == TxFilterOpExists {
+		return item.Field + " EXISTS", ""
+	}
+
+	number, isNumber, reason := txFilterNumber(item.Value)
+	if reason != "" {
+		return "", reason
+	}
+
+	switch op {
+	case TxFilterOpGt, TxFilterOpGte, TxFilterOpLt, TxFilterOpLte:
+		if !isNumber {
+			return "", fmt.Sprintf("operator requires a non-negative number, got %T", item.Value)
+		}
+		return item.Field + txFilterOpSign(op) + number, ""
+	case TxFilterOpEq:
+		if isNumber {
+			return item.Field + "=" + number, ""
+		}
+		value, reason := txFilterString(item.Value)
+		if reason != "" {
+			return "", reason
+		}
+		return item.Field + "=" + value, ""
+	case TxFilterOpContains:
+		value, reason := txFilterString(item.Value)
+		if reason != "" {
+			return "", reason
+		}
+		return item.Field + " CONTAINS " + value, ""
+	default:
+		return "", fmt.Sprintf("unsupported operator %s", item.Op)
+	}
+}
+
+func txFilterOpSign(op string) string {
+	switch op {
+	case TxFilterOpGt:
+		return ">"
+	case TxFilterOpGte:
+		return ">="
+	case TxFilterOpLt:
+		return "<"
+	default:
+		return "<="
+	}
+}
+
+// txFilterNumber formats the value as a query number if it is a number. It returns a non-empty reason
+// if the value is a number which can't be expressed in a query.
+func txFilterNumber(value interface{}) (string, bool, string) {
+	switch v := value.(type) {
+	case uint64:
+		return strconv.FormatUint(v, 10), true, ""
+	case int64:
+		return txFilterInt(v)
+	case int:
+		return txFilterInt(int64(v))
+	case float64:
+		return txFilterFloat(v)
+	case json.Number:
+		if u, err := strconv.ParseUint(v.String(), 10, 64); err == nil {
+			return strconv.FormatUint(u, 10), true, ""
+		}
+		f, err := v.Float64()
+		if err != nil {
+			return "", true, fmt.Sprintf("invalid number %s", v)
+		}
+		return txFilterFloat(f)
+	default:
+		return "", false, ""
+	}
+}
+
+func txFilterInt(v int64) (string, bool, string) {
+	if v < 0 {
+		return "", true, fmt.Sprintf("negative numbers are not supported, got %d", v)
+	}
+	return strconv.FormatInt(v, 10), true, ""
+}
+
+func txFilterFloat(v float64) (string, bool, string) {
+	if v < 0 {
+		return "", true, fmt.Sprintf("negative numbers are not supported, got %v", v)
+	}
+	return strconv.FormatFloat(v, 'f', -1, 64), true, ""
+}
+
+// txFilterString formats the value as a quoted query string. Booleans are compared as strings since
+// that's how they are stored in the events. It returns a non-empty reason if the value is not a string.
+func txFilterString(value interface{}) (string, string) {
+	var s string
+	switch v := value.(type) {
+	case string:
+		s = v
+	case bool:
+		s = strconv.FormatBool(v)
+	default:
+		return "", fmt.Sprintf("unsupported value type %T", value)
+	}
+
+	// the query grammar has no escape sequences, so there is no way to express a quote inside a value
+	if strings.ContainsAny(s, `'"`) {
+		return "", "string value must not contain quotes"
+	}
+	return "'" + s + "'", ""
+}
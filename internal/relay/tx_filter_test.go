@@ -0,0 +1,87 @@
+package relay_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+func TestCompileTxFilter(t *testing.T) {
+	tests := []struct {
+		name     string
+		filter   string
+		expected string
+	}{
+		{
+			name:     "empty filter",
+			filter:   `[]`,
+			expected: "tx.height>100",
+		},
+		{
+			name:     "comparison operators",
+			filter:   `[{"field":"transfer.amount","op":"Gte","value":10},{"field":"transfer.amount","op":"lt","value":20.5}]`,
+			expected: "transfer.amount>=10 AND transfer.amount<20.5 AND tx.height>100",
+		},
+		{
+			name:     "big numbers are kept precisely",
+			filter:   `[{"field":"message.nonce","op":"eq","value":18446744073709551615}]`,
+			expected: "message.nonce=18446744073709551615 AND tx.height>100",
+		},
+		{
+			name:     "strings and bools",
+			filter:   `[{"field":"transfer.recipient","op":"eq","value":"cosmos1"},{"field":"message.ok","op":"eq","value":true}]`,
+			expected: "transfer.recipient='cosmos1' AND message.ok='true' AND tx.height>100",
+		},
+		{
+			name:     "contains and exists",
+			filter:   `[{"field":"message.memo","op":"contains","value":"abc"},{"field":"message.sender","op":"exists"}]`,
+			expected: "message.memo CONTAINS 'abc' AND message.sender EXISTS AND tx.height>100",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, err := relay.CompileTxFilter(tt.filter, 100, 0)
+			require.NoError(t, err)
+			assert.Equal(t, tt.expected, query)
+		})
+	}
+}
+
+func TestCompileTxFilterHeightRange(t *testing.T) {
+	query, err := relay.CompileTxFilter(`[{"field":"transfer.recipient","op":"eq","value":"cosmos1"}]`, 99, 200)
+	require.NoError(t, err)
+	assert.Equal(t, "transfer.recipient='cosmos1' AND tx.height>99 AND tx.height<=200", query)
+}
+
+func TestCompileTxFilterRejectsInvalidFilters(t *testing.T) {
+	tests := []struct {
+		name   string
+		filter string
+		item   int
+	}{
+		{name: "malformed json", filter: `[{"field":`, item: -1},
+		{name: "unknown operator", filter: `[{"field":"a.b","op":"ne","value":1}]`, item: 0},
+		{name: "nested value", filter: `[{"field":"a.b","op":"eq","value":1},{"field":"a.c","op":"eq","value":{"x":1}}]`, item: 1},
+		{name: "string comparison", filter: `[{"field":"a.b","op":"gt","value":"1"}]`, item: 0},
+		{name: "negative number", filter: `[{"field":"a.b","op":"gt","value":-1}]`, item: 0},
+		{name: "quote in value", filter: `[{"field":"a.b","op":"eq","value":"x' OR a.c='y"}]`, item: 0},
+		{name: "bad field", filter: `[{"field":"a.b>1 AND c","op":"eq","value":1}]`, item: 0},
+		{name: "empty field", filter: `[{"field":"","op":"exists"}]`, item: 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := relay.CompileTxFilter(tt.filter, 100, 0)
+			var filterErr relay.ErrInvalidTxFilter
+			require.True(t, errors.As(err, &filterErr), "unexpected error: %v", err)
+			assert.Equal(t, tt.item, filterErr.Item)
+			assert.Equal(t, tt.filter, filterErr.Filter)
+			assert.NotEmpty(t, filterErr.Reason)
+		})
+	}
+}
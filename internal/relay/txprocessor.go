@@ -8,7 +8,8 @@ import (
 	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
 )
 
-// Transaction represents single searched tx with height
+// Transaction represents single searched tx with height. A Transaction with a nil Tx is a checkpoint
+// which tells that all the transactions up to and including Height have been sent.
 type Transaction struct {
 	Tx     *neutrontypes.TxValue `json:"tx"`
 	Height uint64                `json:"height"`
@@ -21,13 +22,16 @@ type TXQuerier interface {
 	// a) All transactions from an RPC call preprocessed successfully
 	// b) error encountered during the SearchTransactions method (the error will be written into the returned errs channel)
 	// After a txs channel is closed, it's necessary to check the errs channel for a possible errors in a SearchTransactions goroutine
+	// The transactions are sent in the order of their heights and indexes, checkpoints may be sent in between blocks
 	SearchTransactions(ctx context.Context, query string) (<-chan Transaction, <-chan error)
 }
 
 // ChainClient is a minimal interface for tendermint client
 type ChainClient interface {
 	BlockResults(ctx context.Context, height *int64) (*ctypes.ResultBlockResults, error)
+	Block(ctx context.Context, height *int64) (*ctypes.ResultBlock, error)
 	TxSearch(ctx context.Context, query string, prove bool, page, perPage *int, orderBy string) (*ctypes.ResultTxSearch, error)
+	Status(ctx context.Context) (*ctypes.ResultStatus, error)
 }
 
 // TXProcessor precesses transactions from a remote chain and sends them to the neutron
@@ -0,0 +1,47 @@
+package revision
+
+import (
+	"context"
+	"sync/atomic"
+
+	lightprovider "github.com/cometbft/cometbft/light/provider"
+	lighthttp "github.com/cometbft/cometbft/light/provider/http"
+	tmtypes "github.com/cometbft/cometbft/types"
+)
+
+// lightProvider is the light blocks provider of the target chain provider which can be switched to another
+// chain ID while in use, since the light blocks of a chain ID other than the configured one are rejected.
+type lightProvider struct {
+	current atomic.Pointer[lightprovider.Provider]
+	rpcAddr string
+}
+
+func newLightProvider(chainID string, rpcAddr string) (*lightProvider, error) {
+	p := &lightProvider{rpcAddr: rpcAddr}
+	if err := p.switchTo(chainID); err != nil {
+		return nil, err
+	}
+	return p, nil
+}
+
+// switchTo replaces the underlying provider with a new one for the given chain ID.
+func (p *lightProvider) switchTo(chainID string) error {
+	provider, err := lighthttp.New(chainID, p.rpcAddr)
+	if err != nil {
+		return err
+	}
+	p.current.Store(&provider)
+	return nil
+}
+
+func (p *lightProvider) ChainID() string {
+	return (*p.current.Load()).ChainID()
+}
+
+func (p *lightProvider) LightBlock(ctx context.Context, height int64) (*tmtypes.LightBlock, error) {
+	return (*p.current.Load()).LightBlock(ctx, height)
+}
+
+func (p *lightProvider) ReportEvidence(ctx context.Context, ev tmtypes.Evidence) error {
+	return (*p.current.Load()).ReportEvidence(ctx, ev)
+}
@@ -0,0 +1,147 @@
+package revision
+
+import (
+	"context"
+	"fmt"
+	"sync/atomic"
+	"time"
+
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	"github.com/cosmos/relayer/v2/relayer"
+	"github.com/cosmos/relayer/v2/relayer/chains/cosmos"
+	"go.uber.org/zap"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+// Tracker is an implementation of relay.TargetRevision which follows the chain ID changes of the target chain,
+// e.g. after an upgrade that bumps the chain ID revision, without restarting the relayer.
+//
+// The light blocks provider of the target chain is switched to the new chain ID as soon as the target chain
+// reports it, since the light blocks of the new chain ID are rejected otherwise. The revision the relayer
+// works in is switched once Neutron's client of the target chain is upgraded to the new revision: neither
+// headers nor proofs of the new revision can be verified by the client before that.
+type Tracker struct {
+	neutronChain  *relayer.Chain
+	connection    relay.ConnectionParamsProvider
+	targetChain   *cosmos.CosmosProvider
+	lightProvider *lightProvider
+	checkPeriod   time.Duration
+	connectionID  string
+	logger        *zap.Logger
+
+	revision atomic.Uint64
+	// chainID is the chain ID of the light blocks provider
+	chainID string
+}
+
+// NewTracker constructs a Tracker starting with the revision of the target chain ID the chains are loaded with.
+// The light blocks provider of targetChain is replaced with the one the Tracker is able to switch, so it must be
+// called before targetChain is in use.
+func NewTracker(
+	neutronChain *relayer.Chain,
+	targetChain *relayer.Chain,
+	connection relay.ConnectionParamsProvider,
+	checkPeriod time.Duration,
+	logger *zap.Logger,
+) (*Tracker, error) {
+	targetProvider, ok := targetChain.ChainProvider.(*cosmos.CosmosProvider)
+	if !ok {
+		return nil, fmt.Errorf("failed to cast ChainProvider to concrete type (cosmos.CosmosProvider)")
+	}
+
+	chainID := targetChain.ChainID()
+	lightProvider, err := newLightProvider(chainID, targetProvider.PCfg.RPCAddr)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create light provider for chain %s: %w", chainID, err)
+	}
+	targetProvider.LightProvider = lightProvider
+
+	t := &Tracker{
+		neutronChain:  neutronChain,
+		connection:    connection,
+		targetChain:   targetProvider,
+		lightProvider: lightProvider,
+		checkPeriod:   checkPeriod,
+		connectionID:  neutronChain.PathEnd.ConnectionID,
+		logger:        logger,
+		chainID:       chainID,
+	}
+	t.revision.Store(clienttypes.ParseChainID(chainID))
+	return t, nil
+}
+
+// Revision returns the revision number of the target chain the relayer works in.
+func (t *Tracker) Revision() uint64 {
+	return t.revision.Load()
+}
+
+// Run checks the target chain ID and the revision of Neutron's client every checkPeriod until ctx is done.
+func (t *Tracker) Run(ctx context.Context) {
+	ticker := time.NewTicker(t.checkPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := t.check(ctx); err != nil && ctx.Err() == nil {
+			t.logger.Warn("failed to check target chain revision", zap.Error(err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			t.logger.Info("Context cancelled, shutting down revision Tracker...")
+			return
+		}
+	}
+}
+
+func (t *Tracker) check(ctx context.Context) error {
+	status, err := t.targetChain.QueryStatus(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to fetch target chain status: %w", err)
+	}
+	if err := t.switchChainID(status.NodeInfo.Network); err != nil {
+		return err
+	}
+	targetRevision := clienttypes.ParseChainID(status.NodeInfo.Network)
+
+	clientID := t.connection.ConnectionParams().NeutronClientID
+	clientState, err := t.neutronChain.ChainProvider.QueryClientState(ctx, 0, clientID)
+	if err != nil {
+		return fmt.Errorf("could not fetch client state for ClientId=%s: %w", clientID, err)
+	}
+	clientRevision := clientState.GetLatestHeight().GetRevisionNumber()
+	neutronmetrics.SetRevisions(t.connectionID, targetRevision, clientRevision)
+
+	if previous := t.revision.Swap(clientRevision); previous != clientRevision {
+		t.logger.Info("switched to the revision of Neutron's client of the target chain",
+			zap.String("client_id", clientID),
+			zap.Uint64("previous_revision", previous),
+			zap.Uint64("revision", clientRevision))
+	}
+	if targetRevision != clientRevision {
+		t.logger.Warn("target chain revision differs from the revision of Neutron's client, the client must be upgraded to relay the queries",
+			zap.String("client_id", clientID),
+			zap.String("target_chain_id", status.NodeInfo.Network),
+			zap.Uint64("target_revision", targetRevision),
+			zap.Uint64("client_revision", clientRevision))
+	}
+	return nil
+}
+
+// switchChainID switches the light blocks provider to the given chain ID if it has changed.
+func (t *Tracker) switchChainID(chainID string) error {
+	if chainID == t.chainID {
+		return nil
+	}
+	if err := t.lightProvider.switchTo(chainID); err != nil {
+		return fmt.Errorf("failed to create light provider for chain %s: %w", chainID, err)
+	}
+
+	t.logger.Info("target chain ID changed, light provider switched",
+		zap.String("previous_chain_id", t.chainID),
+		zap.String("chain_id", chainID))
+	t.chainID = chainID
+	return nil
+}
@@ -0,0 +1,106 @@
+package revision_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/p2p"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	ibcexported "github.com/cosmos/ibc-go/v7/modules/core/exported"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	"github.com/cosmos/relayer/v2/relayer"
+	"github.com/cosmos/relayer/v2/relayer/chains/cosmos"
+	"github.com/cosmos/relayer/v2/relayer/provider"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	"github.com/neutron-org/neutron-query-relayer/internal/revision"
+)
+
+// chains serves the chain ID of the target chain and the revision of Neutron's client of it.
+type chains struct {
+	mutex          sync.Mutex
+	chainID        string
+	clientRevision uint64
+}
+
+func (c *chains) set(chainID string, clientRevision uint64) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.chainID = chainID
+	c.clientRevision = clientRevision
+}
+
+// targetRPC is the rpc client of the target chain, only Status is implemented.
+type targetRPC struct {
+	rpcclient.Client
+	chains *chains
+}
+
+func (r targetRPC) Status(context.Context) (*ctypes.ResultStatus, error) {
+	r.chains.mutex.Lock()
+	defer r.chains.mutex.Unlock()
+
+	return &ctypes.ResultStatus{NodeInfo: p2p.DefaultNodeInfo{Network: r.chains.chainID}}, nil
+}
+
+// neutronProvider is the chain provider of Neutron, only QueryClientState is implemented.
+type neutronProvider struct {
+	provider.ChainProvider
+	chains *chains
+}
+
+func (p neutronProvider) QueryClientState(context.Context, int64, string) (ibcexported.ClientState, error) {
+	p.chains.mutex.Lock()
+	defer p.chains.mutex.Unlock()
+
+	return &tmclient.ClientState{LatestHeight: clienttypes.NewHeight(p.chains.clientRevision, 100)}, nil
+}
+
+type staticConnection relay.ConnectionParams
+
+func (c staticConnection) ConnectionParams() relay.ConnectionParams {
+	return relay.ConnectionParams(c)
+}
+
+func TestTrackerFollowsChainIDChanges(t *testing.T) {
+	state := &chains{chainID: "target-1", clientRevision: 1}
+	targetProvider := &cosmos.CosmosProvider{
+		PCfg:      cosmos.CosmosProviderConfig{ChainID: "target-1", RPCAddr: "http://127.0.0.1:26657"},
+		RPCClient: targetRPC{chains: state},
+	}
+	targetChain := &relayer.Chain{ChainProvider: targetProvider}
+	neutronChain := &relayer.Chain{
+		ChainProvider: neutronProvider{chains: state},
+		PathEnd:       &relayer.PathEnd{ConnectionID: "connection-0"},
+	}
+
+	tracker, err := revision.NewTracker(neutronChain, targetChain, staticConnection{NeutronClientID: "07-tendermint-0"},
+		10*time.Millisecond, zap.NewNop())
+	require.NoError(t, err)
+	lightProvider := targetProvider.LightProvider
+	assert.Equal(t, "target-1", lightProvider.ChainID())
+	assert.Equal(t, uint64(1), tracker.Revision())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go tracker.Run(ctx)
+
+	// the light provider is switched as soon as the target chain changes its chain ID, the revision is kept
+	// until Neutron's client is upgraded
+	state.set("target-2", 1)
+	require.Eventually(t, func() bool { return lightProvider.ChainID() == "target-2" }, 5*time.Second, 10*time.Millisecond)
+	assert.Same(t, lightProvider, targetProvider.LightProvider, "the light provider in use must be switched in place")
+	assert.Equal(t, uint64(1), tracker.Revision())
+
+	state.set("target-2", 2)
+	require.Eventually(t, func() bool { return tracker.Revision() == 2 }, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "target-2", lightProvider.ChainID())
+}
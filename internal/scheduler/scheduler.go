@@ -0,0 +1,266 @@
+package scheduler
+
+import (
+	"container/heap"
+	"context"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// defaultWeight is the priority weight of queries whose owners have no weight configured.
+const defaultWeight = 1.0
+
+// Scheduler is an implementation of relay.TaskQueue that orders the tasks by their deadlines:
+// the query which was supposed to be updated earlier is served first. The deadline of a query is
+// LastSubmittedResultLocalHeight + UpdatePeriod/weight, where weight is the priority weight of the
+// query owner, so owners with a bigger weight are served as if their queries had shorter update
+// periods. Tasks with equal deadlines are served in FIFO order.
+//
+// The Scheduler holds at most one task per query ID: pushing a query which is already queued
+// replaces the queued query parameters with the newer ones and keeps the earlier of two deadlines.
+type Scheduler struct {
+	mutex        sync.Mutex
+	connectionID string
+	tasks        taskHeap
+	byID         map[uint64]*task
+	seq          uint64
+	weights      map[string]float64
+
+	// slots limits the number of tasks in the queue: a slot is taken on Push and freed on Pop.
+	slots chan struct{}
+	// notify wakes up a Pop call waiting for new tasks.
+	notify chan struct{}
+}
+
+// NewScheduler creates a new Scheduler for the queries of the given connection which holds at most
+// capacity tasks. ownerWeights is an optional map of query owner addresses to their priority weights.
+func NewScheduler(connectionID string, capacity int, ownerWeights map[string]float64) *Scheduler {
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	weights := make(map[string]float64, len(ownerWeights))
+	for owner, weight := range ownerWeights {
+		if weight > 0 {
+			weights[owner] = weight
+		}
+	}
+
+	return &Scheduler{
+		connectionID: connectionID,
+		byID:         make(map[uint64]*task),
+		weights:      weights,
+		slots:        make(chan struct{}, capacity),
+		notify:       make(chan struct{}, 1),
+	}
+}
+
+// Push adds a task for the query to the queue. If a task for the same query is already queued,
+// the tasks are coalesced into one. Otherwise Push blocks while the queue is full.
+func (s *Scheduler) Push(ctx context.Context, query neutrontypes.RegisteredQuery, currentHeight uint64) error {
+	if s.coalesce(query, currentHeight) {
+		return nil
+	}
+
+	select {
+	case s.slots <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	s.mutex.Lock()
+	// the same query might have been pushed while we were waiting for a slot
+	if s.coalesceLocked(query, currentHeight) {
+		s.mutex.Unlock()
+		<-s.slots
+		return nil
+	}
+	t := s.newTask(query, currentHeight)
+	heap.Push(&s.tasks, t)
+	s.byID[query.Id] = t
+	s.mutex.Unlock()
+
+	s.wakeUp()
+	return nil
+}
+
+// Pop removes the task with the earliest deadline from the queue and returns its query. It blocks
+// while the queue is empty.
+func (s *Scheduler) Pop(ctx context.Context) (neutrontypes.RegisteredQuery, error) {
+	for {
+		s.mutex.Lock()
+		if s.tasks.Len() > 0 {
+			t := heap.Pop(&s.tasks).(*task)
+			delete(s.byID, t.query.Id)
+			remaining := s.tasks.Len()
+			s.mutex.Unlock()
+
+			<-s.slots
+			// Pass the notification on in case there are other consumers waiting.
+			if remaining > 0 {
+				s.wakeUp()
+			}
+			return t.query, nil
+		}
+		s.mutex.Unlock()
+
+		select {
+		case <-s.notify:
+		case <-ctx.Done():
+			return neutrontypes.RegisteredQuery{}, ctx.Err()
+		}
+	}
+}
+
+// Len returns the number of tasks in the queue.
+func (s *Scheduler) Len() int {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.tasks.Len()
+}
+
+// Tasks returns a snapshot of the queue contents in the order the tasks are going to be served.
+func (s *Scheduler) Tasks() []relay.TaskInfo {
+	s.mutex.Lock()
+	// copy the tasks by value: sorting the heap elements in place would break their indexes
+	tasks := make([]task, 0, len(s.tasks))
+	for _, t := range s.tasks {
+		tasks = append(tasks, *t)
+	}
+	s.mutex.Unlock()
+
+	sort.Slice(tasks, func(i, j int) bool {
+		return tasks[i].before(&tasks[j])
+	})
+
+	out := make([]relay.TaskInfo, 0, len(tasks))
+	for _, t := range tasks {
+		out = append(out, t.info)
+	}
+	return out
+}
+
+// coalesce merges the query into the already queued task for the same query ID, if any.
+func (s *Scheduler) coalesce(query neutrontypes.RegisteredQuery, currentHeight uint64) bool {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	return s.coalesceLocked(query, currentHeight)
+}
+
+func (s *Scheduler) coalesceLocked(query neutrontypes.RegisteredQuery, currentHeight uint64) bool {
+	queued, ok := s.byID[query.Id]
+	if !ok {
+		return false
+	}
+
+	updated := s.newTask(query, currentHeight)
+	if queued.deadline < updated.deadline {
+		updated.deadline = queued.deadline
+		updated.info.DueHeight = queued.info.DueHeight
+		updated.info.OverdueBlocks = queued.info.OverdueBlocks
+	}
+	// keep the place in the FIFO order among the tasks with equal deadlines
+	updated.seq = queued.seq
+	updated.info.EnqueuedAt = queued.info.EnqueuedAt
+
+	queued.query = updated.query
+	queued.deadline = updated.deadline
+	queued.seq = updated.seq
+	queued.info = updated.info
+	heap.Fix(&s.tasks, queued.index)
+
+	metrics.IncCoalescedTasks(s.connectionID)
+	return true
+}
+
+func (s *Scheduler) newTask(query neutrontypes.RegisteredQuery, currentHeight uint64) *task {
+	weight, ok := s.weights[query.Owner]
+	if !ok {
+		weight = defaultWeight
+	}
+
+	dueHeight := query.LastSubmittedResultLocalHeight + query.UpdatePeriod
+	var overdue uint64
+	if currentHeight > dueHeight {
+		overdue = currentHeight - dueHeight
+	}
+
+	s.seq++
+	return &task{
+		query:    query,
+		deadline: float64(query.LastSubmittedResultLocalHeight) + float64(query.UpdatePeriod)/weight,
+		seq:      s.seq,
+		info: relay.TaskInfo{
+			ConnectionID:   s.connectionID,
+			QueryID:        query.Id,
+			QueryType:      query.QueryType,
+			Owner:          query.Owner,
+			DueHeight:      dueHeight,
+			EnqueuedHeight: currentHeight,
+			OverdueBlocks:  overdue,
+			Weight:         weight,
+			EnqueuedAt:     time.Now(),
+		},
+	}
+}
+
+func (s *Scheduler) wakeUp() {
+	select {
+	case s.notify <- struct{}{}:
+	default:
+	}
+}
+
+// task is a single element of the Scheduler queue.
+type task struct {
+	query    neutrontypes.RegisteredQuery
+	deadline float64
+	seq      uint64
+	info     relay.TaskInfo
+	// index is the position of the task in the heap, maintained by taskHeap.
+	index int
+}
+
+// before reports whether the task has to be served before the other one.
+func (t *task) before(other *task) bool {
+	if t.deadline != other.deadline {
+		return t.deadline < other.deadline
+	}
+	return t.seq < other.seq
+}
+
+// taskHeap implements heap.Interface for the tasks ordered by (deadline, seq).
+type taskHeap []*task
+
+func (h taskHeap) Len() int { return len(h) }
+
+func (h taskHeap) Less(i, j int) bool { return h[i].before(h[j]) }
+
+func (h taskHeap) Swap(i, j int) {
+	h[i], h[j] = h[j], h[i]
+	h[i].index = i
+	h[j].index = j
+}
+
+func (h *taskHeap) Push(x interface{}) {
+	t := x.(*task)
+	t.index = len(*h)
+	*h = append(*h, t)
+}
+
+func (h *taskHeap) Pop() interface{} {
+	old := *h
+	n := len(old)
+	t := old[n-1]
+	old[n-1] = nil
+	t.index = -1
+	*h = old[:n-1]
+	return t
+}
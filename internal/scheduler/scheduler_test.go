@@ -0,0 +1,126 @@
+package scheduler_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/scheduler"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+func newQuery(id uint64, owner string, lastHeight uint64, updatePeriod uint64) neutrontypes.RegisteredQuery {
+	return neutrontypes.RegisteredQuery{
+		Id:                             id,
+		Owner:                          owner,
+		QueryType:                      string(neutrontypes.InterchainQueryTypeKV),
+		LastSubmittedResultLocalHeight: lastHeight,
+		UpdatePeriod:                   updatePeriod,
+	}
+}
+
+func popIDs(t *testing.T, s *scheduler.Scheduler, n int) []uint64 {
+	ids := make([]uint64, 0, n)
+	for i := 0; i < n; i++ {
+		query, err := s.Pop(context.Background())
+		require.NoError(t, err)
+		ids = append(ids, query.Id)
+	}
+	return ids
+}
+
+func TestSchedulerServesMostOverdueFirst(t *testing.T) {
+	s := scheduler.NewScheduler("connection-0", 10, nil)
+	ctx := context.Background()
+
+	require.NoError(t, s.Push(ctx, newQuery(1, "owner", 90, 10), 100)) // due at 100
+	require.NoError(t, s.Push(ctx, newQuery(2, "owner", 50, 10), 100)) // due at 60
+	require.NoError(t, s.Push(ctx, newQuery(3, "owner", 80, 10), 100)) // due at 90
+	require.NoError(t, s.Push(ctx, newQuery(4, "owner", 70, 20), 100)) // due at 90, pushed after 3
+
+	tasks := s.Tasks()
+	require.Len(t, tasks, 4)
+	assert.Equal(t, uint64(2), tasks[0].QueryID)
+	assert.Equal(t, uint64(40), tasks[0].OverdueBlocks)
+	assert.Equal(t, uint64(60), tasks[0].DueHeight)
+
+	assert.Equal(t, []uint64{2, 3, 4, 1}, popIDs(t, s, 4))
+	assert.Equal(t, 0, s.Len())
+}
+
+func TestSchedulerOwnerWeights(t *testing.T) {
+	s := scheduler.NewScheduler("connection-0", 10, map[string]float64{"vip": 4})
+	ctx := context.Background()
+
+	require.NoError(t, s.Push(ctx, newQuery(1, "regular", 100, 10), 110)) // deadline 110
+	require.NoError(t, s.Push(ctx, newQuery(2, "vip", 100, 40), 110))     // deadline 100 + 40/4 = 110, pushed later
+	require.NoError(t, s.Push(ctx, newQuery(3, "vip", 100, 20), 110))     // deadline 100 + 20/4 = 105
+
+	assert.Equal(t, []uint64{3, 1, 2}, popIDs(t, s, 3))
+}
+
+func TestSchedulerPopBlocksUntilPush(t *testing.T) {
+	s := scheduler.NewScheduler("connection-0", 10, nil)
+
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		assert.NoError(t, s.Push(context.Background(), newQuery(1, "owner", 0, 1), 1))
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	query, err := s.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), query.Id)
+}
+
+func TestSchedulerRespectsContext(t *testing.T) {
+	s := scheduler.NewScheduler("connection-0", 1, nil)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	_, err := s.Pop(ctx)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+
+	// the queue is full, so the second push has to wait until the context is done
+	require.NoError(t, s.Push(context.Background(), newQuery(1, "owner", 0, 1), 1))
+	ctx, cancel = context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	err = s.Push(ctx, newQuery(2, "owner", 0, 1), 1)
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Equal(t, 1, s.Len())
+}
+
+func TestSchedulerCoalescesTasksByQueryID(t *testing.T) {
+	s := scheduler.NewScheduler("connection-0", 2, nil)
+	ctx := context.Background()
+
+	require.NoError(t, s.Push(ctx, newQuery(1, "owner", 90, 10), 100)) // due at 100
+	require.NoError(t, s.Push(ctx, newQuery(2, "owner", 85, 10), 100)) // due at 95
+	// the queue is full, but the pushes below are coalesced with the queued tasks
+	require.NoError(t, s.Push(ctx, newQuery(1, "owner", 95, 5), 101))  // due at 100, newer params
+	require.NoError(t, s.Push(ctx, newQuery(2, "owner", 95, 10), 101)) // due at 105, earlier deadline kept
+
+	assert.Equal(t, 2, s.Len())
+	tasks := s.Tasks()
+	require.Len(t, tasks, 2)
+	assert.Equal(t, uint64(2), tasks[0].QueryID)
+	assert.Equal(t, uint64(95), tasks[0].DueHeight)
+
+	first, err := s.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(2), first.Id)
+	assert.Equal(t, uint64(95), first.LastSubmittedResultLocalHeight)
+
+	second, err := s.Pop(ctx)
+	require.NoError(t, err)
+	assert.Equal(t, uint64(1), second.Id)
+	assert.Equal(t, uint64(5), second.UpdatePeriod)
+
+	// once popped, the query can be queued again
+	require.NoError(t, s.Push(ctx, newQuery(1, "owner", 100, 5), 102))
+	assert.Equal(t, 1, s.Len())
+}
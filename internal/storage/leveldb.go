@@ -12,20 +12,28 @@ import (
 	"github.com/neutron-org/neutron-query-relayer/internal/relay"
 
 	"github.com/syndtr/goleveldb/leveldb"
+	leveldbstorage "github.com/syndtr/goleveldb/leveldb/storage"
 )
 
 const (
 	SubmittedTxStatusPrefix    = "submitted_txs"
 	UnsuccessfulTxStatusPrefix = "unsuccessful_txs"
 	CachedTxs                  = "cached_txs"
+	QueryFailuresPrefix        = "query_failures"
+	CriticalTxErrorsPrefix     = "critical_tx_errors"
 )
 
 // LevelDBStorage Basically has a simple structure inside: we have 2 maps
 // first one : map of queryID -> last block this query has been processed
 // second one: map of queryID+txHash -> status of sent tx
+//
+// A storage may be namespaced (see WithNamespace), in which case all its keys are prefixed with the namespace.
 type LevelDBStorage struct {
-	mutex sync.Mutex
-	db    *leveldb.DB
+	mutex     *sync.Mutex
+	db        *leveldb.DB
+	namespace []byte
+	// view is true if the storage shares the database with its parent, see WithNamespace
+	view bool
 }
 
 func NewLevelDBStorage(path string) (*LevelDBStorage, error) {
@@ -34,14 +42,42 @@ func NewLevelDBStorage(path string) (*LevelDBStorage, error) {
 		return nil, fmt.Errorf("failed to initialize new stirage: %w", err)
 	}
 
-	return &LevelDBStorage{db: database}, nil
+	return &LevelDBStorage{mutex: &sync.Mutex{}, db: database}, nil
+}
+
+// NewLevelDBMemStorage creates a storage which keeps its data in memory only, the data is lost once it's closed.
+func NewLevelDBMemStorage() (*LevelDBStorage, error) {
+	database, err := leveldb.Open(leveldbstorage.NewMemStorage(), nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize new in-memory storage: %w", err)
+	}
+
+	return &LevelDBStorage{mutex: &sync.Mutex{}, db: database}, nil
+}
+
+// ConnectionNamespace returns the storage namespace for the data of the given connection.
+func ConnectionNamespace(connectionID string) string {
+	return "connections/" + connectionID + "/"
+}
+
+// WithNamespace returns a view of the storage which keeps its data separately from the other
+// namespaces. The view shares the database with the parent storage, so closing the view does
+// nothing, the database is closed along with the parent storage. An empty namespace is the root
+// one, which keeps the data of the primary connection.
+func (s *LevelDBStorage) WithNamespace(namespace string) *LevelDBStorage {
+	return &LevelDBStorage{
+		mutex:     s.mutex,
+		db:        s.db,
+		namespace: []byte(namespace),
+		view:      true,
+	}
 }
 
 func (s *LevelDBStorage) GetAllPendingTxs() ([]*relay.PendingSubmittedTxInfo, error) {
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	iterator := s.db.NewIterator(util.BytesPrefix([]byte(SubmittedTxStatusPrefix)), nil)
+	iterator := s.db.NewIterator(util.BytesPrefix(s.key([]byte(SubmittedTxStatusPrefix))), nil)
 	defer iterator.Release()
 	var txs []*relay.PendingSubmittedTxInfo
 	for iterator.Next() {
@@ -61,7 +97,7 @@ func (s *LevelDBStorage) GetAllUnsuccessfulTxs() ([]*relay.UnsuccessfulTxInfo, e
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	iterator := s.db.NewIterator(util.BytesPrefix([]byte(UnsuccessfulTxStatusPrefix)), nil)
+	iterator := s.db.NewIterator(util.BytesPrefix(s.key([]byte(UnsuccessfulTxStatusPrefix))), nil)
 	defer iterator.Release()
 	// use `make` to avoid printing empty value in json as `null`
 	var txs = make([]*relay.UnsuccessfulTxInfo, 0)
@@ -83,7 +119,7 @@ func (s *LevelDBStorage) GetCachedTx(queryID uint64, hash string) (*relay.Transa
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	key := constructCacheTxKey(queryID, hash)
+	key := s.key(constructCacheTxKey(queryID, hash))
 	data, err := s.db.Get(key, nil)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get Transaction for query_id + hash {%d %s}: %w", queryID, hash, err)
@@ -109,7 +145,7 @@ func (s *LevelDBStorage) GetLastQueryHeight(queryID uint64) (block uint64, found
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	data, err := s.db.Get(uintToBytes(queryID), nil)
+	data, err := s.db.Get(s.key(uintToBytes(queryID)), nil)
 	if err != nil {
 		if err == leveldb.ErrNotFound {
 			return 0, false, nil
@@ -132,6 +168,9 @@ func (s *LevelDBStorage) GetLastQueryHeight(queryID uint64) (block uint64, found
 //     2.a) failed to commit tx into the block - relay.ErrorOnCommit
 //     2.b) tx successfully committed - relay.Committed
 //
+// In the dry run mode txs get the relay.DryRun status, which is final and is only used to skip the txs already processed
+// by the current run: the dry run storage is an in-memory one (see NewLevelDBMemStorage).
+//
 // To convert status from "2" to either "2.a" or "2.b" we use additional SubmittedTxStatusPrefix storage to track txs
 func (s *LevelDBStorage) SetTxStatus(queryID uint64, hash string, neutronHash string, txInfo relay.SubmittedTxInfo, processedTx *relay.Transaction) (err error) {
 	s.mutex.Lock()
@@ -148,13 +187,13 @@ func (s *LevelDBStorage) SetTxStatus(queryID uint64, hash string, neutronHash st
 		return fmt.Errorf("failed to Marshal SubmittedTxInfo: %w", err)
 	}
 
-	err = t.Put(constructTxStatusKey(queryID, hash), data, nil)
+	err = t.Put(s.key(constructTxStatusKey(queryID, hash)), data, nil)
 	if err != nil {
 		return fmt.Errorf("failed to set tx txInfo: %w", err)
 	}
 
 	if processedTx != nil {
-		err = cacheProcessedTx(t, queryID, hash, processedTx)
+		err = s.cacheProcessedTx(t, queryID, hash, processedTx)
 		if err != nil {
 			return fmt.Errorf("failed to cache processed tx: %w", err)
 		}
@@ -166,12 +205,12 @@ func (s *LevelDBStorage) SetTxStatus(queryID uint64, hash string, neutronHash st
 			SubmittedTxHash: hash,
 			NeutronHash:     neutronHash,
 		}
-		err = saveIntoPendingQueue(t, neutronHash, pendingTxInfo)
+		err = s.saveIntoPendingQueue(t, neutronHash, pendingTxInfo)
 		if err != nil {
 			return fmt.Errorf("failed to save txInfo into pending queue: %w", err)
 		}
 	} else if txInfo.Status == relay.Committed || txInfo.Status == relay.ErrorOnCommit {
-		err = removeFromPendingQueue(t, neutronHash)
+		err = s.removeFromPendingQueue(t, neutronHash)
 		if err != nil {
 			return fmt.Errorf("failed to remove txInfo from pending queue: %w", err)
 		}
@@ -186,19 +225,19 @@ func (s *LevelDBStorage) SetTxStatus(queryID uint64, hash string, neutronHash st
 			Status:          txInfo.Status,
 			Message:         txInfo.Message,
 		}
-		err = saveIntoUnsuccessfulQueue(t, queryID, hash, unsuccessfulTxInfo)
+		err = s.saveIntoUnsuccessfulQueue(t, queryID, hash, unsuccessfulTxInfo)
 		if err != nil {
 			return fmt.Errorf("failed to save unsuccessfulTxInfo into Unsuccessful queue: %w", err)
 		}
 	}
 
 	if txInfo.Status == relay.Committed {
-		err = removeFromUnsuccessfulQueue(t, queryID, hash)
+		err = s.removeFromUnsuccessfulQueue(t, queryID, hash)
 		if err != nil {
 			return fmt.Errorf("failed to remove txInfo from UnsuccessfulQueue: %w", err)
 		}
 
-		err = removeCachedTx(t, queryID, hash)
+		err = s.removeCachedTx(t, queryID, hash)
 		if err != nil {
 			return fmt.Errorf("failed to remove cachedTxData from the cached queue: %w", err)
 		}
@@ -213,7 +252,7 @@ func (s *LevelDBStorage) TxExists(queryID uint64, hash string) (exists bool, err
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	exists, err = s.db.Has(constructTxStatusKey(queryID, hash), nil)
+	exists, err = s.db.Has(s.key(constructTxStatusKey(queryID, hash)), nil)
 	if err != nil {
 		return false, fmt.Errorf("failed to get if storage has key: %w", err)
 	}
@@ -226,7 +265,7 @@ func (s *LevelDBStorage) SetLastQueryHeight(queryID uint64, block uint64) error
 	s.mutex.Lock()
 	defer s.mutex.Unlock()
 
-	err := s.db.Put(uintToBytes(queryID), uintToBytes(block), nil)
+	err := s.db.Put(s.key(uintToBytes(queryID)), uintToBytes(block), nil)
 	if err != nil {
 		return fmt.Errorf("failed to save last query height to storage: %w", err)
 	}
@@ -234,7 +273,142 @@ func (s *LevelDBStorage) SetLastQueryHeight(queryID uint64, block uint64) error
 	return nil
 }
 
+// GetQueryFailure returns the consecutive failures info of the query
+func (s *LevelDBStorage) GetQueryFailure(queryID uint64) (info *relay.QueryFailureInfo, found bool, err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := s.db.Get(s.key(constructQueryFailureKey(queryID)), nil)
+	if err != nil {
+		if err == leveldb.ErrNotFound {
+			return nil, false, nil
+		}
+		return nil, false, fmt.Errorf("failed getting data from db: %w", err)
+	}
+
+	var failureInfo relay.QueryFailureInfo
+	err = json.Unmarshal(data, &failureInfo)
+	if err != nil {
+		return nil, false, fmt.Errorf("failed to unmarshal data into QueryFailureInfo: %w", err)
+	}
+
+	return &failureInfo, true, nil
+}
+
+// SetQueryFailure saves the consecutive failures info of the query
+func (s *LevelDBStorage) SetQueryFailure(info relay.QueryFailureInfo) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal QueryFailureInfo: %w", err)
+	}
+
+	err = s.db.Put(s.key(constructQueryFailureKey(info.QueryID)), data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save query failure info for queryID=%d: %w", info.QueryID, err)
+	}
+
+	return nil
+}
+
+// RemoveQueryFailure removes the consecutive failures info of the query, e.g. after the query was processed
+// successfully or released from quarantine
+func (s *LevelDBStorage) RemoveQueryFailure(queryID uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.db.Delete(s.key(constructQueryFailureKey(queryID)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to remove query failure info for queryID=%d: %w", queryID, err)
+	}
+
+	return nil
+}
+
+// GetAllQuarantinedQueries returns failures info of all the queries in quarantine
+func (s *LevelDBStorage) GetAllQuarantinedQueries() ([]*relay.QueryFailureInfo, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	iterator := s.db.NewIterator(util.BytesPrefix(s.key([]byte(QueryFailuresPrefix))), nil)
+	defer iterator.Release()
+	// use `make` to avoid printing empty value in json as `null`
+	var queries = make([]*relay.QueryFailureInfo, 0)
+	for iterator.Next() {
+		value := iterator.Value()
+		var failureInfo relay.QueryFailureInfo
+		err := json.Unmarshal(value, &failureInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data into QueryFailureInfo: %w", err)
+		}
+
+		if failureInfo.Quarantined {
+			queries = append(queries, &failureInfo)
+		}
+	}
+	return queries, nil
+}
+
+// SetCriticalTxError saves the latest critical tx submission error of the query
+func (s *LevelDBStorage) SetCriticalTxError(info relay.CriticalTxErrorInfo) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	data, err := json.Marshal(info)
+	if err != nil {
+		return fmt.Errorf("failed to marshal CriticalTxErrorInfo: %w", err)
+	}
+
+	err = s.db.Put(s.key(constructCriticalTxErrorKey(info.QueryID)), data, nil)
+	if err != nil {
+		return fmt.Errorf("failed to save critical tx error info for queryID=%d: %w", info.QueryID, err)
+	}
+
+	return nil
+}
+
+// RemoveCriticalTxError removes the critical tx submission error of the query
+func (s *LevelDBStorage) RemoveCriticalTxError(queryID uint64) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	err := s.db.Delete(s.key(constructCriticalTxErrorKey(queryID)), nil)
+	if err != nil {
+		return fmt.Errorf("failed to remove critical tx error info for queryID=%d: %w", queryID, err)
+	}
+
+	return nil
+}
+
+// GetAllCriticalTxErrors returns the latest critical tx submission errors of all the queries
+func (s *LevelDBStorage) GetAllCriticalTxErrors() ([]*relay.CriticalTxErrorInfo, error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	iterator := s.db.NewIterator(util.BytesPrefix(s.key([]byte(CriticalTxErrorsPrefix))), nil)
+	defer iterator.Release()
+	// use `make` to avoid printing empty value in json as `null`
+	var errs = make([]*relay.CriticalTxErrorInfo, 0)
+	for iterator.Next() {
+		value := iterator.Value()
+		var errInfo relay.CriticalTxErrorInfo
+		err := json.Unmarshal(value, &errInfo)
+		if err != nil {
+			return nil, fmt.Errorf("failed to unmarshal data into CriticalTxErrorInfo: %w", err)
+		}
+
+		errs = append(errs, &errInfo)
+	}
+	return errs, nil
+}
+
 func (s *LevelDBStorage) Close() error {
+	if s.view {
+		return nil
+	}
+
 	err := s.db.Close()
 	if err != nil {
 		return fmt.Errorf("failed to close db: %w", err)
@@ -242,8 +416,8 @@ func (s *LevelDBStorage) Close() error {
 	return nil
 }
 
-func saveIntoPendingQueue(t *leveldb.Transaction, neutronTXHash string, txInfo relay.PendingSubmittedTxInfo) error {
-	key := constructPendingQueueKey(neutronTXHash)
+func (s *LevelDBStorage) saveIntoPendingQueue(t *leveldb.Transaction, neutronTXHash string, txInfo relay.PendingSubmittedTxInfo) error {
+	key := s.key(constructPendingQueueKey(neutronTXHash))
 	data, err := json.Marshal(txInfo)
 	if err != nil {
 		return fmt.Errorf("failed to marshal PendingSubmittedTxInfo: %w", err)
@@ -257,8 +431,8 @@ func saveIntoPendingQueue(t *leveldb.Transaction, neutronTXHash string, txInfo r
 	return nil
 }
 
-func removeFromPendingQueue(t *leveldb.Transaction, neutronTXHash string) error {
-	key := constructPendingQueueKey(neutronTXHash)
+func (s *LevelDBStorage) removeFromPendingQueue(t *leveldb.Transaction, neutronTXHash string) error {
+	key := s.key(constructPendingQueueKey(neutronTXHash))
 	err := t.Delete(key, nil)
 	if err != nil {
 		return fmt.Errorf("failed to remove PendingSubmittedTxInfo with neuton tx hash=%s: %w", neutronTXHash, err)
@@ -267,8 +441,8 @@ func removeFromPendingQueue(t *leveldb.Transaction, neutronTXHash string) error
 	return nil
 }
 
-func saveIntoUnsuccessfulQueue(t *leveldb.Transaction, queryID uint64, tXHash string, txInfo relay.UnsuccessfulTxInfo) error {
-	key := constructUnsuccessfulQueueKey(queryID, tXHash)
+func (s *LevelDBStorage) saveIntoUnsuccessfulQueue(t *leveldb.Transaction, queryID uint64, tXHash string, txInfo relay.UnsuccessfulTxInfo) error {
+	key := s.key(constructUnsuccessfulQueueKey(queryID, tXHash))
 	data, err := json.Marshal(txInfo)
 	if err != nil {
 		return fmt.Errorf("failed to marshal UnsuccessfulTxInfo: %w", err)
@@ -282,8 +456,8 @@ func saveIntoUnsuccessfulQueue(t *leveldb.Transaction, queryID uint64, tXHash st
 	return nil
 }
 
-func removeFromUnsuccessfulQueue(t *leveldb.Transaction, queryID uint64, tXHash string) error {
-	key := constructUnsuccessfulQueueKey(queryID, tXHash)
+func (s *LevelDBStorage) removeFromUnsuccessfulQueue(t *leveldb.Transaction, queryID uint64, tXHash string) error {
+	key := s.key(constructUnsuccessfulQueueKey(queryID, tXHash))
 	err := t.Delete(key, nil)
 	if err != nil {
 		return fmt.Errorf("failed to remove UnsuccessfulTxInfo with queryID=%d hash=%s: %w", queryID, tXHash, err)
@@ -292,8 +466,8 @@ func removeFromUnsuccessfulQueue(t *leveldb.Transaction, queryID uint64, tXHash
 	return nil
 }
 
-func removeCachedTx(t *leveldb.Transaction, queryID uint64, tXHash string) error {
-	key := constructCacheTxKey(queryID, tXHash)
+func (s *LevelDBStorage) removeCachedTx(t *leveldb.Transaction, queryID uint64, tXHash string) error {
+	key := s.key(constructCacheTxKey(queryID, tXHash))
 	err := t.Delete(key, nil)
 	if err != nil {
 		return fmt.Errorf("failed to remove cached tx under with queryID=%d hash=%s: %w", queryID, tXHash, err)
@@ -302,8 +476,8 @@ func removeCachedTx(t *leveldb.Transaction, queryID uint64, tXHash string) error
 	return nil
 }
 
-func cacheProcessedTx(t *leveldb.Transaction, queryID uint64, tXHash string, tx *relay.Transaction) error {
-	key := constructCacheTxKey(queryID, tXHash)
+func (s *LevelDBStorage) cacheProcessedTx(t *leveldb.Transaction, queryID uint64, tXHash string, tx *relay.Transaction) error {
+	key := s.key(constructCacheTxKey(queryID, tXHash))
 	data, err := json.Marshal(tx)
 	if err != nil {
 		return fmt.Errorf("failed to marshal relay.Transaction: %w", err)
@@ -317,6 +491,14 @@ func cacheProcessedTx(t *leveldb.Transaction, queryID uint64, tXHash string, tx
 	return nil
 }
 
+// key prefixes the key with the storage namespace.
+func (s *LevelDBStorage) key(key []byte) []byte {
+	if len(s.namespace) == 0 {
+		return key
+	}
+	return append(append([]byte{}, s.namespace...), key...)
+}
+
 func constructCacheTxKey(queryID uint64, tXHash string) []byte {
 	return append([]byte(CachedTxs), constructTxStatusKey(queryID, tXHash)...)
 }
@@ -339,6 +521,14 @@ func constructUnsuccessfulQueueKey(queryID uint64, neutronTXHash string) []byte
 	return key
 }
 
+func constructQueryFailureKey(queryID uint64) []byte {
+	return append([]byte(QueryFailuresPrefix), uintToBytes(queryID)...)
+}
+
+func constructCriticalTxErrorKey(queryID uint64) []byte {
+	return append([]byte(CriticalTxErrorsPrefix), uintToBytes(queryID)...)
+}
+
 func constructPendingQueueKey(neutronTXHash string) []byte {
 	key := []byte(SubmittedTxStatusPrefix + neutronTXHash)
 	return key
@@ -5,22 +5,46 @@ import (
 	"fmt"
 
 	sdk "github.com/cosmos/cosmos-sdk/types"
+	"go.uber.org/zap"
 
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
 	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
 )
 
+// txSender is the transaction transport of the SubmitterImpl, implemented by TxSender.
+type txSender interface {
+	Send(ctx context.Context, connectionID string, msgs []sdk.Msg) (string, error)
+	SendWithGasLimit(ctx context.Context, connectionID string, msgs []sdk.Msg, gasLimit uint64) (string, error)
+	SenderAddr() (string, error)
+}
+
 // SubmitterImpl can submit proofs using `sender` as the transaction transport mechanism
 type SubmitterImpl struct {
-	sender           *TxSender
+	sender           txSender
+	connectionID     string
 	allowKVCallbacks bool
-	clientID         string
+	// connection provides the ID of Neutron's client the results are verified with
+	connection relay.ConnectionParamsProvider
+	// kvBatchMaxGas limits the gas of a transaction with several KV query results, 0 means no limit
+	// except for the sender's one
+	kvBatchMaxGas uint64
+	logger        *zap.Logger
 }
 
-func NewSubmitterImpl(sender *TxSender, allowKVCallbacks bool, clientID string) *SubmitterImpl {
-	return &SubmitterImpl{sender: sender, allowKVCallbacks: allowKVCallbacks, clientID: clientID}
+func NewSubmitterImpl(sender *TxSender, connectionID string, allowKVCallbacks bool, connection relay.ConnectionParamsProvider, kvBatchMaxGas uint64, logger *zap.Logger) *SubmitterImpl {
+	return &SubmitterImpl{
+		sender:           sender,
+		connectionID:     connectionID,
+		allowKVCallbacks: allowKVCallbacks,
+		connection:       connection,
+		kvBatchMaxGas:    kvBatchMaxGas,
+		logger:           logger,
+	}
 }
 
-// SubmitKVProof submits query with proof back to Neutron chain
+// SubmitKVProof submits query with proof back to Neutron chain. updateClientMsg is nil if Neutron's
+// client already has the consensus state to verify the proof.
 func (si *SubmitterImpl) SubmitKVProof(
 	ctx context.Context,
 	height, revision, queryId uint64,
@@ -32,11 +56,90 @@ func (si *SubmitterImpl) SubmitKVProof(
 		return fmt.Errorf("could not build proof msg: %w", err)
 	}
 
-	msgs = append([]sdk.Msg{updateClientMsg}, msgs...)
-	_, err = si.sender.Send(ctx, msgs)
+	if updateClientMsg != nil {
+		msgs = append([]sdk.Msg{updateClientMsg}, msgs...)
+	}
+	_, err = si.sender.Send(ctx, si.connectionID, msgs)
 	return err
 }
 
+// SubmitKVProofs submits the results of several KV queries in a single transaction with a single
+// MsgUpdateClient, if any. If the transaction can't be built or sent, e.g. its simulation fails or it needs
+// more gas than allowed, the batch is split in two halves which are submitted the same way, so a single
+// failing result doesn't fail the others.
+func (si *SubmitterImpl) SubmitKVProofs(
+	ctx context.Context,
+	height, revision uint64,
+	proofs []relay.KVProof,
+	updateClientMsg sdk.Msg,
+) []error {
+	errs := make([]error, len(proofs))
+	msgs := make([]sdk.Msg, 0, len(proofs))
+	positions := make([]int, 0, len(proofs))
+	for i, proof := range proofs {
+		proofMsgs, err := si.buildProofMsg(height, revision, proof.QueryID, si.allowKVCallbacks, proof.Proof)
+		if err != nil {
+			errs[i] = fmt.Errorf("could not build proof msg: %w", err)
+			continue
+		}
+		msgs = append(msgs, proofMsgs[0])
+		positions = append(positions, i)
+	}
+
+	if len(msgs) == 0 {
+		return errs
+	}
+	neutronmetrics.ObserveKVBatchSize(si.connectionID, len(msgs))
+
+	batch := kvBatch{height: height, updateClientMsg: updateClientMsg}
+	batchErrs := si.submitKVBatch(ctx, &batch, msgs)
+	for i, err := range batchErrs {
+		errs[positions[i]] = err
+	}
+	return errs
+}
+
+// kvBatch is the state shared by the parts of a batch of KV query results.
+type kvBatch struct {
+	height uint64
+	// updateClientMsg is the MsgUpdateClient to submit along with the results. It's reset once a transaction
+	// with it succeeds, since the following parts of the batch don't need it anymore.
+	updateClientMsg sdk.Msg
+}
+
+// submitKVBatch submits the proof msgs in a single transaction and, if it fails, splits them in two halves
+// and submits each of them the same way. It returns the submission error of each msg by its position.
+func (si *SubmitterImpl) submitKVBatch(ctx context.Context, batch *kvBatch, msgs []sdk.Msg) []error {
+	txMsgs := msgs
+	if batch.updateClientMsg != nil {
+		txMsgs = append([]sdk.Msg{batch.updateClientMsg}, msgs...)
+	}
+
+	var err error
+	if len(msgs) == 1 {
+		// a single result is submitted even if it needs more gas than a batch is allowed to
+		_, err = si.sender.Send(ctx, si.connectionID, txMsgs)
+	} else {
+		_, err = si.sender.SendWithGasLimit(ctx, si.connectionID, txMsgs, si.kvBatchMaxGas)
+	}
+	if err == nil {
+		batch.updateClientMsg = nil
+	}
+	if err == nil || len(msgs) == 1 || ctx.Err() != nil {
+		errs := make([]error, len(msgs))
+		for i := range errs {
+			errs[i] = err
+		}
+		return errs
+	}
+
+	si.logger.Warn("failed to submit batch of KV query results, splitting it in halves",
+		zap.Int("batch_size", len(msgs)), zap.Uint64("height", batch.height), zap.Error(err))
+	neutronmetrics.IncKVBatchFallbacks(si.connectionID)
+	half := len(msgs) / 2
+	return append(si.submitKVBatch(ctx, batch, msgs[:half]), si.submitKVBatch(ctx, batch, msgs[half:])...)
+}
+
 // SubmitTxProof submits tx query with proof back to Neutron chain
 func (si *SubmitterImpl) SubmitTxProof(ctx context.Context, queryId uint64, proof *neutrontypes.Block) (string, error) {
 	msgs, err := si.buildTxProofMsg(queryId, proof)
@@ -44,7 +147,13 @@ func (si *SubmitterImpl) SubmitTxProof(ctx context.Context, queryId uint64, proo
 		return "", fmt.Errorf("could not build tx proof msg: %w", err)
 	}
 
-	return si.sender.Send(ctx, msgs)
+	return si.sender.Send(ctx, si.connectionID, msgs)
+}
+
+// SubmitClientUpdate submits MsgUpdateClient alone to Neutron chain
+func (si *SubmitterImpl) SubmitClientUpdate(ctx context.Context, updateClientMsg sdk.Msg) error {
+	_, err := si.sender.Send(ctx, si.connectionID, []sdk.Msg{updateClientMsg})
+	return err
 }
 
 func (si *SubmitterImpl) buildProofMsg(height, revision, queryId uint64, allowKVCallbacks bool, proof []*neutrontypes.StorageValue) ([]sdk.Msg, error) {
@@ -60,7 +169,7 @@ func (si *SubmitterImpl) buildProofMsg(height, revision, queryId uint64, allowKV
 		AllowKvCallbacks: allowKVCallbacks,
 	}
 
-	msg := neutrontypes.MsgSubmitQueryResult{QueryId: queryId, Sender: senderAddr, Result: &queryResult, ClientId: si.clientID}
+	msg := neutrontypes.MsgSubmitQueryResult{QueryId: queryId, Sender: senderAddr, Result: &queryResult, ClientId: si.connection.ConnectionParams().NeutronClientID}
 
 	err = msg.ValidateBasic()
 	if err != nil {
@@ -81,7 +190,7 @@ func (si *SubmitterImpl) buildTxProofMsg(queryId uint64, proof *neutrontypes.Blo
 		KvResults: nil,
 		Block:     proof,
 	}
-	msg := neutrontypes.MsgSubmitQueryResult{QueryId: queryId, Sender: senderAddr, Result: &queryResult, ClientId: si.clientID}
+	msg := neutrontypes.MsgSubmitQueryResult{QueryId: queryId, Sender: senderAddr, Result: &queryResult, ClientId: si.connection.ConnectionParams().NeutronClientID}
 
 	err = msg.ValidateBasic()
 	if err != nil {
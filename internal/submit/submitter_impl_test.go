@@ -0,0 +1,131 @@
+package submit
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+var errResultRejected = errors.New("query result rejected")
+
+// failingSender fails the transactions with the results of the failing queries and records every
+// transaction as "[U,]<query ids>[ limited]", where U stands for a MsgUpdateClient.
+type failingSender struct {
+	failing map[uint64]bool
+	txs     []string
+}
+
+func (s *failingSender) Send(_ context.Context, _ string, msgs []sdk.Msg) (string, error) {
+	return s.send(msgs, "")
+}
+
+func (s *failingSender) SendWithGasLimit(_ context.Context, _ string, msgs []sdk.Msg, _ uint64) (string, error) {
+	return s.send(msgs, " limited")
+}
+
+func (s *failingSender) SenderAddr() (string, error) {
+	return "neutron1sender", nil
+}
+
+func (s *failingSender) send(msgs []sdk.Msg, suffix string) (string, error) {
+	var (
+		items []string
+		err   error
+	)
+	for _, msg := range msgs {
+		switch msg := msg.(type) {
+		case *clienttypes.MsgUpdateClient:
+			items = append(items, "U")
+		case *neutrontypes.MsgSubmitQueryResult:
+			items = append(items, fmt.Sprint(msg.QueryId))
+			if s.failing[msg.QueryId] {
+				err = errResultRejected
+			}
+		}
+	}
+	s.txs = append(s.txs, strings.Join(items, ",")+suffix)
+	return "hash", err
+}
+
+func TestSubmitKVBatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		queries   []uint64
+		failing   []uint64
+		txs       []string
+		failedIdx []int
+	}{
+		{
+			name:    "whole batch succeeds",
+			queries: []uint64{1, 2, 3, 4},
+			txs:     []string{"U,1,2,3,4 limited"},
+		},
+		{
+			name:      "only the failing half is split again",
+			queries:   []uint64{1, 2, 3, 4},
+			failing:   []uint64{3},
+			txs:       []string{"U,1,2,3,4 limited", "U,1,2 limited", "3,4 limited", "3", "4"},
+			failedIdx: []int{2},
+		},
+		{
+			name:      "client update is kept until a transaction succeeds",
+			queries:   []uint64{1, 2, 3, 4},
+			failing:   []uint64{1},
+			txs:       []string{"U,1,2,3,4 limited", "U,1,2 limited", "U,1", "U,2", "3,4 limited"},
+			failedIdx: []int{0},
+		},
+		{
+			name:      "single result is not split",
+			queries:   []uint64{1},
+			failing:   []uint64{1},
+			txs:       []string{"U,1"},
+			failedIdx: []int{0},
+		},
+		{
+			name:      "every result fails",
+			queries:   []uint64{1, 2, 3},
+			failing:   []uint64{1, 2, 3},
+			txs:       []string{"U,1,2,3 limited", "U,1", "U,2,3 limited", "U,2", "U,3"},
+			failedIdx: []int{0, 1, 2},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sender := &failingSender{failing: make(map[uint64]bool)}
+			for _, id := range tt.failing {
+				sender.failing[id] = true
+			}
+			si := &SubmitterImpl{sender: sender, connectionID: "connection-0", kvBatchMaxGas: 1000000, logger: zap.NewNop()}
+
+			msgs := make([]sdk.Msg, 0, len(tt.queries))
+			for _, id := range tt.queries {
+				msgs = append(msgs, &neutrontypes.MsgSubmitQueryResult{QueryId: id})
+			}
+			batch := kvBatch{height: 100, updateClientMsg: &clienttypes.MsgUpdateClient{ClientId: "07-tendermint-0"}}
+
+			errs := si.submitKVBatch(context.Background(), &batch, msgs)
+			assert.Equal(t, tt.txs, sender.txs)
+			assert.Len(t, errs, len(tt.queries))
+			failed := make([]int, 0)
+			for i, err := range errs {
+				if err != nil {
+					assert.ErrorIs(t, err, errResultRejected)
+					failed = append(failed, i)
+				}
+			}
+			assert.ElementsMatch(t, tt.failedIdx, failed)
+			assert.Equal(t, len(tt.failing) < len(tt.queries), batch.updateClientMsg == nil,
+				"the client update must be reset once a transaction with it succeeds")
+		})
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"strings"
 	"sync"
+	"time"
 
 	tmtypes "github.com/cometbft/cometbft/types"
 	"go.uber.org/zap"
@@ -24,6 +25,8 @@ import (
 	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
 
 	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
 )
 
 const (
@@ -45,6 +48,8 @@ type TxSender struct {
 	gasPrices     string
 	gasLimit      uint64
 	logger        *zap.Logger
+	// dryRunRecorder is set in the dry run mode, in which transactions are built but never broadcast
+	dryRunRecorder relay.DryRunRecorder
 }
 
 func TestKeybase(chainID string, keyringRootDir string, cdc codec.Codec) (keyring.Keyring, error) {
@@ -64,6 +69,7 @@ func NewTxSender(
 	cfg config.NeutronChainConfig,
 	logger *zap.Logger,
 	neutronChainID string,
+	dryRunRecorder relay.DryRunRecorder,
 ) (*TxSender, error) {
 	txConfig := authtxtypes.NewTxConfig(marshaller, authtxtypes.DefaultSignModes)
 	baseTxf := tx.Factory{}.
@@ -85,6 +91,8 @@ func NewTxSender(
 		gasPrices:   cfg.GasPrices,
 		gasLimit:    cfg.GasLimit,
 		logger:      logger,
+
+		dryRunRecorder: dryRunRecorder,
 	}
 	err := txs.refreshAccountInfo(ctx)
 	if err != nil {
@@ -109,8 +117,19 @@ func (txs *TxSender) refreshAccountInfo(ctx context.Context) error {
 	return nil
 }
 
-// Send builds transaction with calculated input msgs, calculated gas and fees, signs it and submits to chain
-func (txs *TxSender) Send(ctx context.Context, msgs []sdk.Msg) (string, error) {
+// Send builds transaction with calculated input msgs, calculated gas and fees, signs it and submits to chain.
+// In the dry run mode the transaction is recorded for the Neutron's side connectionID instead of being submitted.
+func (txs *TxSender) Send(ctx context.Context, connectionID string, msgs []sdk.Msg) (string, error) {
+	return txs.SendWithGasLimit(ctx, connectionID, msgs, 0)
+}
+
+// SendWithGasLimit does the same as Send, but fails if the transaction needs more than gasLimit gas.
+// The sender's gas limit is applied as well, gasLimit = 0 means that only the sender's gas limit is applied.
+func (txs *TxSender) SendWithGasLimit(ctx context.Context, connectionID string, msgs []sdk.Msg, gasLimit uint64) (string, error) {
+	if gasLimit == 0 || txs.gasLimit > 0 && txs.gasLimit < gasLimit {
+		gasLimit = txs.gasLimit
+	}
+
 	txs.lock.Lock()
 	defer txs.lock.Unlock()
 
@@ -129,11 +148,14 @@ func (txs *TxSender) Send(ctx context.Context, msgs []sdk.Msg) (string, error) {
 			}
 			txs.logger.Info("sender reinitialized successfully (account sequence reset)")
 		}
+		txs.recordDryRun(connectionID, msgs, "", 0, err)
 		return "", fmt.Errorf("error calculating gas: %w", err)
 	}
 
-	if txs.gasLimit > 0 && gasNeeded > txs.gasLimit {
-		return "", fmt.Errorf("exceeds gas limit: gas needed %d, gas limit %d", gasNeeded, txs.gasLimit)
+	if gasLimit > 0 && gasNeeded > gasLimit {
+		err = fmt.Errorf("exceeds gas limit: gas needed %d, gas limit %d", gasNeeded, gasLimit)
+		txs.recordDryRun(connectionID, msgs, "", gasNeeded, err)
+		return "", err
 	}
 
 	txf = txf.
@@ -145,6 +167,13 @@ func (txs *TxSender) Send(ctx context.Context, msgs []sdk.Msg) (string, error) {
 		return "", fmt.Errorf("could not sign and build tx bz: %w", err)
 	}
 
+	if txs.dryRunRecorder != nil {
+		// the sequence is not incremented since the tx never gets to the chain
+		hash := hex.EncodeToString(tmtypes.Tx(bz).Hash())
+		txs.recordDryRun(connectionID, msgs, hash, gasNeeded, nil)
+		return hash, nil
+	}
+
 	res, err := txs.rpcClient.BroadcastTxSync(ctx, bz)
 	if err != nil {
 		return "", fmt.Errorf("error broadcasting sync transaction: %w", err)
@@ -165,6 +194,41 @@ func (txs *TxSender) Send(ctx context.Context, msgs []sdk.Msg) (string, error) {
 	return "", fmt.Errorf("error broadcasting sync transaction: log=%s", res.Log)
 }
 
+// recordDryRun records the result of a tx built in the dry run mode. It does nothing if the dry run mode is off.
+func (txs *TxSender) recordDryRun(connectionID string, msgs []sdk.Msg, hash string, gas uint64, err error) {
+	if txs.dryRunRecorder == nil {
+		return
+	}
+
+	result := relay.DryRunResult{
+		Time:         time.Now(),
+		TxHash:       hash,
+		ConnectionID: connectionID,
+		QueryIDs:     make([]uint64, 0),
+		MsgTypes:     make([]string, 0, len(msgs)),
+		GasWanted:    gas,
+	}
+	for _, msg := range msgs {
+		result.MsgTypes = append(result.MsgTypes, sdk.MsgTypeURL(msg))
+		if submitMsg, ok := msg.(*neutrontypes.MsgSubmitQueryResult); ok {
+			result.QueryIDs = append(result.QueryIDs, submitMsg.QueryId)
+			result.ClientID = submitMsg.ClientId
+		}
+	}
+	if err != nil {
+		result.Error = err.Error()
+	}
+
+	txs.dryRunRecorder.Record(result)
+	txs.logger.Info("dry run: tx is built but not broadcast",
+		zap.String("tx_hash", hash),
+		zap.String("connection_id", connectionID),
+		zap.String("client_id", result.ClientID),
+		zap.Uint64s("query_ids", result.QueryIDs),
+		zap.Uint64("gas_wanted", gas),
+		zap.Error(err))
+}
+
 func (txs *TxSender) SenderAddr() (string, error) {
 	info, err := txs.keybase.Key(txs.signKeyName)
 	if err != nil {
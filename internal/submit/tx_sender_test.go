@@ -0,0 +1,133 @@
+package submit_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cometbft/cometbft/types"
+	"github.com/cosmos/cosmos-sdk/codec"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/crypto/hd"
+	"github.com/cosmos/cosmos-sdk/crypto/keyring"
+	sdk "github.com/cosmos/cosmos-sdk/types"
+	txtypes "github.com/cosmos/cosmos-sdk/types/tx"
+	authtypes "github.com/cosmos/cosmos-sdk/x/auth/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	"github.com/neutron-org/neutron-query-relayer/internal/dryrun"
+	"github.com/neutron-org/neutron-query-relayer/internal/raw"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	"github.com/neutron-org/neutron-query-relayer/internal/submit"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+// neutronRPC serves the account of the sender and the tx simulations, every simulated tx uses gasUsed gas.
+type neutronRPC struct {
+	rpcclient.Client
+	gasUsed uint64
+
+	mutex      sync.Mutex
+	broadcasts int
+}
+
+func (c *neutronRPC) ABCIQueryWithOptions(_ context.Context, path string, _ bytes.HexBytes, _ rpcclient.ABCIQueryOptions) (*ctypes.ResultABCIQuery, error) {
+	var (
+		value []byte
+		err   error
+	)
+	switch path {
+	case "/cosmos.auth.v1beta1.Query/Account":
+		var account *codectypes.Any
+		account, err = codectypes.NewAnyWithValue(&authtypes.BaseAccount{AccountNumber: 1, Sequence: 10})
+		if err != nil {
+			return nil, err
+		}
+		value, err = (&authtypes.QueryAccountResponse{Account: account}).Marshal()
+	case "/cosmos.tx.v1beta1.Service/Simulate":
+		value, err = (&txtypes.SimulateResponse{GasInfo: &sdk.GasInfo{GasUsed: c.gasUsed}}).Marshal()
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ctypes.ResultABCIQuery{Response: abci.ResponseQuery{Value: value}}, nil
+}
+
+func (c *neutronRPC) BroadcastTxSync(_ context.Context, tx types.Tx) (*ctypes.ResultBroadcastTx, error) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+
+	c.broadcasts++
+	return &ctypes.ResultBroadcastTx{Hash: tx.Hash()}, nil
+}
+
+func newTestTxSender(t *testing.T, rpc *neutronRPC, recorder *dryrun.Recorder) *submit.TxSender {
+	cdc := raw.MakeCodecDefault()
+	keybase := keyring.NewInMemory(codec.NewProtoCodec(cdc.InterfaceRegistry))
+	_, _, err := keybase.NewMnemonic("relayer", keyring.English, sdk.FullFundraiserPath, keyring.DefaultBIP39Passphrase, hd.Secp256k1)
+	require.NoError(t, err)
+
+	cfg := config.NeutronChainConfig{SignKeyName: "relayer", GasPrices: "0.5untrn", GasAdjustment: 1}
+	var dryRunRecorder relay.DryRunRecorder
+	if recorder != nil {
+		dryRunRecorder = recorder
+	}
+	sender, err := submit.NewTxSender(context.Background(), rpc, cdc.Marshaller, keybase, cfg, zap.NewNop(), "neutron-1", dryRunRecorder)
+	require.NoError(t, err)
+	return sender
+}
+
+func submitResultMsgs(queryIDs ...uint64) []sdk.Msg {
+	msgs := make([]sdk.Msg, 0, len(queryIDs))
+	for _, id := range queryIDs {
+		msgs = append(msgs, &neutrontypes.MsgSubmitQueryResult{QueryId: id, ClientId: "07-tendermint-0"})
+	}
+	return msgs
+}
+
+func TestDryRunSend(t *testing.T) {
+	rpc := &neutronRPC{gasUsed: 1000}
+	recorder := dryrun.NewRecorder(2)
+	sender := newTestTxSender(t, rpc, recorder)
+	ctx := context.Background()
+
+	hash, err := sender.Send(ctx, "connection-0", submitResultMsgs(1))
+	require.NoError(t, err)
+	assert.NotEmpty(t, hash)
+	_, err = sender.SendWithGasLimit(ctx, "connection-0", submitResultMsgs(2, 3), 500)
+	assert.ErrorContains(t, err, "exceeds gas limit")
+	_, err = sender.SendWithGasLimit(ctx, "connection-1", submitResultMsgs(4, 5), 5000)
+	require.NoError(t, err)
+
+	assert.Zero(t, rpc.broadcasts, "nothing must be broadcast in the dry run mode")
+
+	// the oldest result is evicted
+	results := recorder.Results()
+	require.Len(t, results, 2)
+	assert.Equal(t, []uint64{2, 3}, results[0].QueryIDs)
+	assert.Equal(t, "connection-0", results[0].ConnectionID)
+	assert.Equal(t, uint64(1000), results[0].GasWanted)
+	assert.Contains(t, results[0].Error, "exceeds gas limit")
+	assert.Empty(t, results[0].TxHash)
+	assert.Equal(t, []uint64{4, 5}, results[1].QueryIDs)
+	assert.Equal(t, "connection-1", results[1].ConnectionID)
+	assert.Equal(t, "07-tendermint-0", results[1].ClientID)
+	assert.Empty(t, results[1].Error)
+	assert.NotEmpty(t, results[1].TxHash)
+}
+
+func TestSendBroadcasts(t *testing.T) {
+	rpc := &neutronRPC{gasUsed: 1000}
+	sender := newTestTxSender(t, rpc, nil)
+
+	_, err := sender.Send(context.Background(), "connection-0", submitResultMsgs(1))
+	require.NoError(t, err)
+	assert.Equal(t, 1, rpc.broadcasts)
+}
@@ -8,6 +8,7 @@ import (
 
 type RpcHttpClient interface {
 	Start() error
+	Stop() error
 	Subscribe(ctx context.Context, subscriber string, query string, outCapacity ...int) (out <-chan ctypes.ResultEvent, err error)
 	Status(ctx context.Context) (*ctypes.ResultStatus, error)
 	Unsubscribe(ctx context.Context, subscriber, query string) error
@@ -0,0 +1,93 @@
+package subscriber
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	instrumenters "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+// PollingSubscriber is an implementation of relay.Subscriber for Neutron rpc nodes which don't serve the
+// websocket. Instead of subscribing to events it polls the rpc status for new blocks and reloads the
+// registered queries periodically, so the queries changes are picked up with the delay of up to the
+// queries poll period.
+type PollingSubscriber struct {
+	*Subscriber
+	blockPollPeriod   time.Duration
+	queriesPollPeriod time.Duration
+}
+
+// NewPollingSubscriber creates a new PollingSubscriber instance. The rpc client is only used to fetch the
+// status and isn't started, so no websocket connection is made. The websocket related fields of cfg are ignored.
+func NewPollingSubscriber(
+	cfg *Config,
+	rpcClient RpcHttpClient,
+	restClient RestHttpQuery,
+	blockPollPeriod time.Duration,
+	queriesPollPeriod time.Duration,
+	logger *zap.Logger,
+) *PollingSubscriber {
+	return &PollingSubscriber{
+		Subscriber:        newSubscriber(cfg, rpcClient, restClient, logger),
+		blockPollPeriod:   blockPollPeriod,
+		queriesPollPeriod: queriesPollPeriod,
+	}
+}
+
+// Subscribe polls Neutron for new blocks every block poll period and pushes the queries which need to be
+// updated to the tasks queue. The registered queries are reloaded every queries poll period.
+func (p *PollingSubscriber) Subscribe(ctx context.Context, tasks relay.TaskQueue) error {
+	queries, err := p.getNeutronRegisteredQueries(ctx)
+	if err != nil {
+		return fmt.Errorf("could not getNeutronRegisteredQueries: %w", err)
+	}
+	p.activeQueries = queries
+	instrumenters.SetQueriesToProcessNumElements(p.connectionID, len(p.activeQueries))
+
+	blockTicker := time.NewTicker(p.blockPollPeriod)
+	defer blockTicker.Stop()
+	queriesTicker := time.NewTicker(p.queriesPollPeriod)
+	defer queriesTicker.Stop()
+
+	var lastHeight uint64
+	for {
+		select {
+		case <-ctx.Done():
+			p.logger.Info("Context cancelled, shutting down polling subscriber...")
+			return nil
+		case <-queriesTicker.C:
+			// reloading is the only way the polling subscriber learns of the queries changes, so they are no drift
+			if err := p.reloadQueries(ctx, false); err != nil && ctx.Err() == nil {
+				// the queries are reloaded on the next tick, the current ones are still served
+				p.logger.Warn("failed to reload registered queries", zap.Error(err))
+			}
+		case <-blockTicker.C:
+			status, err := p.rpcClient.Status(ctx)
+			if err != nil {
+				if ctx.Err() == nil {
+					p.logger.Warn("failed to get Status", zap.Error(err))
+				}
+				continue
+			}
+
+			height := uint64(status.SyncInfo.LatestBlockHeight)
+			if height <= lastHeight {
+				continue
+			}
+			lastHeight = height
+
+			p.logger.Debug("new block polled", zap.Uint64("height", height))
+			if err := p.scheduleQueries(ctx, tasks, height); err != nil {
+				if ctx.Err() != nil {
+					p.logger.Info("Context cancelled, shutting down polling subscriber...")
+					return nil
+				}
+				return fmt.Errorf("failed to scheduleQueries: %w", err)
+			}
+		}
+	}
+}
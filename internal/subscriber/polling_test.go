@@ -0,0 +1,85 @@
+package subscriber_test
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/neutron-org/neutron-query-relayer/internal/registry"
+	"github.com/neutron-org/neutron-query-relayer/internal/scheduler"
+	"github.com/neutron-org/neutron-query-relayer/internal/subscriber"
+	"github.com/neutron-org/neutron-query-relayer/internal/subscriber/querier/client/query"
+	mock_subscriber "github.com/neutron-org/neutron-query-relayer/testutil/mocks/subscriber"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/mock/gomock"
+	"go.uber.org/zap"
+)
+
+func TestPollingSubscriberPicksUpNewQueries(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	logger, err := zap.NewProductionConfig().Build()
+	require.NoError(t, err)
+
+	rpcClient := mock_subscriber.NewMockRpcHttpClient(ctrl)
+	restQuery := mock_subscriber.NewMockRestHttpQuery(ctrl)
+
+	// a new block on every poll, no websocket subscriptions are expected
+	var height atomic.Int64
+	height.Store(1000)
+	rpcClient.EXPECT().Status(gomock.Any()).DoAndReturn(func(context.Context) (*ctypes.ResultStatus, error) {
+		return &ctypes.ResultStatus{SyncInfo: ctypes.SyncInfo{LatestBlockHeight: height.Add(1)}}, nil
+	}).AnyTimes()
+
+	registeredQueries := func(ids ...string) *query.NeutronInterchainQueriesRegisteredQueriesOK {
+		res := &query.NeutronInterchainQueriesRegisteredQueriesOK{
+			Payload: &query.NeutronInterchainQueriesRegisteredQueriesOKBody{
+				Pagination: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyPagination{},
+			},
+		}
+		for _, id := range ids {
+			res.Payload.RegisteredQueries = append(res.Payload.RegisteredQueries,
+				&query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0{
+					ID:                             id,
+					Owner:                          "owner",
+					QueryType:                      "kv",
+					UpdatePeriod:                   "1000",
+					LastSubmittedResultLocalHeight: "0",
+					LastSubmittedResultRemoteHeight: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0LastSubmittedResultRemoteHeight{
+						RevisionHeight: "0",
+						RevisionNumber: "0",
+					},
+				})
+		}
+		return res
+	}
+	// query 2 is registered after the polling subscriber has started
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Return(registeredQueries("1"), nil)
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Return(registeredQueries("1", "2"), nil).AnyTimes()
+
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
+	cfg := subscriber.Config{
+		ConnectionID: "",
+		WatchedTypes: []neutrontypes.InterchainQueryType{"kv"},
+		Registry:     registry.New(&registry.RegistryConfig{Addresses: make([]string, 0)}),
+	}
+	s := subscriber.NewPollingSubscriber(&cfg, rpcClient, restQuery, 10*time.Millisecond, 50*time.Millisecond, logger)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// the update period is long enough for each query to be scheduled once
+		assert.Equal(t, uint64(1), popTask(t, queriesTasksQueue).Id)
+		assert.Equal(t, uint64(2), popTask(t, queriesTasksQueue).Id)
+
+		// should terminate Subscribe() function
+		cancel()
+	}()
+
+	err = s.Subscribe(ctx, queriesTasksQueue)
+	assert.Equal(t, err, nil)
+}
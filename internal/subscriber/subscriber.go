@@ -1,7 +1,9 @@
 package subscriber
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"strconv"
 	"sync"
@@ -25,8 +27,37 @@ import (
 
 var (
 	unsubscribeTimeout = time.Second * 5
+	// reconnectInitialDelay is the delay before the first reconnect attempt, it doubles with every next attempt
+	// until the subscriptions are alive again.
+	reconnectInitialDelay = time.Second
 )
 
+// Reasons of reconnecting to Neutron.
+const (
+	// reconnectReasonClosed means an events channel was closed.
+	reconnectReasonClosed = "closed"
+	// reconnectReasonHeartbeat means no new block events were received within the heartbeat timeout.
+	reconnectReasonHeartbeat = "heartbeat"
+	// reconnectReasonStatus means the rpc node failed to report its status on a new block.
+	reconnectReasonStatus = "status"
+	// reconnectReasonFailed means the previous reconnect attempt failed.
+	reconnectReasonFailed = "failed_reconnect"
+)
+
+// deadSubscriptionsError is returned when the Subscriber needs to reconnect to keep receiving events.
+type deadSubscriptionsError struct {
+	reason string
+	err    error
+}
+
+func (e *deadSubscriptionsError) Error() string {
+	return fmt.Sprintf("subscriptions died (%s): %s", e.reason, e.err)
+}
+
+func (e *deadSubscriptionsError) Unwrap() error {
+	return e.err
+}
+
 // Config contains configurable fields for the Subscriber.
 type Config struct {
 	// ConnectionID is the Neutron's side connection ID used to filter out queries.
@@ -36,6 +67,17 @@ type Config struct {
 	// Registry is a watch list registry. It contains a list of addresses and a list of queryIDs, and the Subscriber only
 	// works with interchain queries and events that are under ownership of these addresses and match the queryIDs.
 	Registry *rg.Registry
+	// HeartbeatTimeout is the longest time without new block events after which the subscriptions are considered
+	// dead. Zero disables the check.
+	HeartbeatTimeout time.Duration
+	// ReconcilePeriod is how often the active queries are reconciled with the ones registered on Neutron, in case
+	// some query events were missed or malformed. Zero disables the reconciliation.
+	ReconcilePeriod time.Duration
+	// MaxReconnectDelay is the upper limit of the delay between reconnect attempts.
+	MaxReconnectDelay time.Duration
+	// NewRPCClient creates a new rpc client to replace the one the subscriptions died on. The same client is
+	// resubscribed if it's nil.
+	NewRPCClient func() (RpcHttpClient, error)
 }
 
 func NewDefaultSubscriber(cfg config.NeutronQueryRelayerConfig, logRegistry *nlogger.Registry) (relay.Subscriber, error) {
@@ -56,16 +98,25 @@ func NewDefaultSubscriber(cfg config.NeutronQueryRelayerConfig, logRegistry *nlo
 		return nil, fmt.Errorf("failed to get NewRESTClient for Subscriber: %w", err)
 	}
 
-	sub, err := NewSubscriber(
-		&Config{
-			ConnectionID: cfg.NeutronChain.ConnectionID,
-			WatchedTypes: watchedMsgTypes,
-			Registry:     registry.New(cfg.Registry),
+	subCfg := &Config{
+		ConnectionID:      cfg.NeutronChain.ConnectionID,
+		WatchedTypes:      watchedMsgTypes,
+		Registry:          registry.New(cfg.Registry),
+		HeartbeatTimeout:  cfg.SubscriberHeartbeatTimeout,
+		ReconcilePeriod:   cfg.SubscriberReconcilePeriod,
+		MaxReconnectDelay: cfg.SubscriberMaxReconnectDelay,
+		NewRPCClient: func() (RpcHttpClient, error) {
+			return NewRPCClient(cfg.NeutronChain.RPCAddr, cfg.NeutronChain.Timeout)
 		},
-		rpcClient,
-		restClient.Query,
-		logRegistry.Get(app.SubscriberContext),
-	)
+	}
+	logger := logRegistry.Get(app.SubscriberContext)
+
+	if cfg.SubscriberMode == config.SubscriberModePolling {
+		return NewPollingSubscriber(subCfg, rpcClient, restClient.Query, cfg.SubscriberBlockPollPeriod,
+			cfg.SubscriberQueriesPollPeriod, logger), nil
+	}
+
+	sub, err := NewSubscriber(subCfg, rpcClient, restClient.Query, logger)
 	if err != nil {
 		return nil, fmt.Errorf("failed to create a NewSubscriber: %s", err)
 	}
@@ -84,6 +135,16 @@ func NewSubscriber(
 		return nil, fmt.Errorf("could not start tendermint rpcClient: %w", err)
 	}
 
+	return newSubscriber(cfg, rpcClient, restClient, logger), nil
+}
+
+// newSubscriber creates a new Subscriber instance without starting the rpc client.
+func newSubscriber(
+	cfg *Config,
+	rpcClient RpcHttpClient,
+	restClient RestHttpQuery,
+	logger *zap.Logger,
+) *Subscriber {
 	// Contains the types of queries that we are ready to serve (KV / TX).
 	watchedTypesMap := make(map[neutrontypes.InterchainQueryType]struct{})
 	for _, queryType := range cfg.WatchedTypes {
@@ -99,8 +160,13 @@ func NewSubscriber(
 		logger:       logger,
 		watchedTypes: watchedTypesMap,
 
+		heartbeatTimeout:  cfg.HeartbeatTimeout,
+		reconcilePeriod:   cfg.ReconcilePeriod,
+		maxReconnectDelay: cfg.MaxReconnectDelay,
+		newRPCClient:      cfg.NewRPCClient,
+
 		activeQueries: map[string]*neutrontypes.RegisteredQuery{},
-	}, nil
+	}
 }
 
 // Subscriber is responsible for subscribing on chain's ICQ events. It parses incoming events,
@@ -114,69 +180,172 @@ type Subscriber struct {
 	logger          *zap.Logger
 	watchedTypes    map[neutrontypes.InterchainQueryType]struct{}
 
+	heartbeatTimeout  time.Duration
+	reconcilePeriod   time.Duration
+	maxReconnectDelay time.Duration
+	newRPCClient      func() (RpcHttpClient, error)
+	// reconnectDelay is the delay before the next reconnect attempt, it's reset once new blocks are received
+	reconnectDelay time.Duration
+
 	activeQueries map[string]*neutrontypes.RegisteredQuery
 }
 
 // Subscribe subscribes to 3 types of events: 1. a new block was created, 2. a query was updated (created / updated),
 // 3. a query was removed.
-func (s *Subscriber) Subscribe(ctx context.Context, tasks chan neutrontypes.RegisteredQuery) error {
+//
+// If the subscriptions die, i.e. an events channel is closed or no new block events are received within the
+// heartbeat timeout, the Subscriber reconnects with an increasing delay, resubscribes and reloads the registered
+// queries, since the events emitted while it was disconnected are lost. The active queries are also reconciled
+// with the registered ones every reconcile period, so a missed or malformed event doesn't make them diverge.
+func (s *Subscriber) Subscribe(ctx context.Context, tasks relay.TaskQueue) error {
 	queries, err := s.getNeutronRegisteredQueries(ctx)
 	if err != nil {
 		return fmt.Errorf("could not getNeutronRegisteredQueries: %w", err)
 	}
 	s.activeQueries = queries
-	instrumenters.SetQueriesToProcessNumElements(len(s.activeQueries))
+	instrumenters.SetQueriesToProcessNumElements(s.connectionID, len(s.activeQueries))
+
+	for reconnect := false; ; reconnect = true {
+		err := s.serve(ctx, tasks, reconnect)
+		if ctx.Err() != nil {
+			s.logger.Info("Context cancelled, shutting down subscriber...")
+			return nil
+		}
+
+		var deadErr *deadSubscriptionsError
+		if !errors.As(err, &deadErr) {
+			return err
+		}
+
+		s.reconnectDelay = min(max(2*s.reconnectDelay, reconnectInitialDelay), s.maxReconnectDelay)
+		instrumenters.IncSubscriberReconnects(s.connectionID, deadErr.reason)
+		s.logger.Warn("Neutron events subscriptions died, reconnecting",
+			zap.String("reason", deadErr.reason),
+			zap.Duration("delay", s.reconnectDelay),
+			zap.Error(deadErr.err))
+
+		select {
+		case <-time.After(s.reconnectDelay):
+		case <-ctx.Done():
+			s.logger.Info("Context cancelled, shutting down subscriber...")
+			return nil
+		}
+	}
+}
+
+// serve subscribes to the events and handles them until ctx is done or the subscriptions die. On reconnect
+// the rpc client is replaced and the active queries are reloaded once the Subscriber is resubscribed.
+func (s *Subscriber) serve(ctx context.Context, tasks relay.TaskQueue, reconnect bool) error {
+	if reconnect {
+		if err := s.replaceRPCClient(); err != nil {
+			return &deadSubscriptionsError{reason: reconnectReasonFailed, err: err}
+		}
+	}
 
 	// Make sure we try to unsubscribe from events if an error occurs.
 	defer s.unsubscribe()
 
-	updateEvents, err := s.rpcClient.Subscribe(ctx, s.subscriberName(), s.getQueryUpdatedSubscription())
+	updateEvents, removeEvents, blockEvents, err := s.subscribe(ctx)
 	if err != nil {
-		return fmt.Errorf("could not subscribe to events: %w", err)
+		if reconnect {
+			return &deadSubscriptionsError{reason: reconnectReasonFailed, err: err}
+		}
+		return err
 	}
 
-	removeEvents, err := s.rpcClient.Subscribe(ctx, s.subscriberName(), s.getQueryRemovedSubscription())
-	if err != nil {
-		return fmt.Errorf("could not subscribe to events: %w", err)
+	if reconnect {
+		if err := s.reloadQueries(ctx, true); err != nil {
+			return &deadSubscriptionsError{reason: reconnectReasonFailed, err: err}
+		}
 	}
 
-	blockEvents, err := s.rpcClient.Subscribe(ctx, s.subscriberName(), s.getNewBlockHeaderSubscription())
-	if err != nil {
-		return fmt.Errorf("could not subscribe to events: %w", err)
+	// the heartbeat is not checked if the timeout is not set, reads from a nil channel block forever
+	var (
+		heartbeatTimer *time.Timer
+		heartbeat      <-chan time.Time
+	)
+	if s.heartbeatTimeout > 0 {
+		heartbeatTimer = time.NewTimer(s.heartbeatTimeout)
+		defer heartbeatTimer.Stop()
+		heartbeat = heartbeatTimer.C
+	}
+
+	var reconcile <-chan time.Time
+	if s.reconcilePeriod > 0 {
+		reconcileTicker := time.NewTicker(s.reconcilePeriod)
+		defer reconcileTicker.Stop()
+		reconcile = reconcileTicker.C
 	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			s.logger.Info("Context cancelled, shutting down subscriber...")
 			return nil
-		case event := <-blockEvents:
+		case <-reconcile:
+			if err := s.reloadQueries(ctx, true); err != nil && ctx.Err() == nil {
+				// the queries are reconciled on the next tick, the current ones are still served
+				s.logger.Warn("failed to reconcile active queries", zap.Error(err))
+			}
+		case <-heartbeat:
+			return &deadSubscriptionsError{
+				reason: reconnectReasonHeartbeat,
+				err:    fmt.Errorf("no new block events received for %s", s.heartbeatTimeout),
+			}
+		case event, ok := <-blockEvents:
+			if !ok {
+				return &deadSubscriptionsError{reason: reconnectReasonClosed, err: fmt.Errorf("new block events channel closed")}
+			}
 			s.logger.Debug("new block event", zap.String("query", event.Query))
 			if err := s.processBlockEvent(ctx, tasks); err != nil {
+				if ctx.Err() != nil {
+					return nil
+				}
 				return fmt.Errorf("failed to processBlockEvent: %w", err)
 			}
-		case event := <-updateEvents:
+			s.reconnectDelay = 0
+			if heartbeatTimer != nil {
+				if !heartbeatTimer.Stop() {
+					<-heartbeatTimer.C
+				}
+				heartbeatTimer.Reset(s.heartbeatTimeout)
+			}
+		case event, ok := <-updateEvents:
+			if !ok {
+				return &deadSubscriptionsError{reason: reconnectReasonClosed, err: fmt.Errorf("query updated events channel closed")}
+			}
 			s.logger.Debug("new update event", zap.String("query", event.Query))
-			if err = s.processUpdateEvent(ctx, event); err != nil {
-				return fmt.Errorf("failed to processUpdateEvent: %w", err)
+			if err := s.processUpdateEvent(ctx, event); err != nil {
+				// the missed update is applied by the next reconciliation
+				s.logger.Error("failed to processUpdateEvent, skipping the event", zap.Error(err))
+			}
+		case event, ok := <-removeEvents:
+			if !ok {
+				return &deadSubscriptionsError{reason: reconnectReasonClosed, err: fmt.Errorf("query removed events channel closed")}
 			}
-		case event := <-removeEvents:
 			s.logger.Debug("new remove event", zap.String("query", event.Query))
-			if err = s.processRemoveEvent(event); err != nil {
-				return fmt.Errorf("failed to processRemoveEvent: %w", err)
+			if err := s.processRemoveEvent(event); err != nil {
+				// the missed removal is applied by the next reconciliation
+				s.logger.Error("failed to processRemoveEvent, skipping the event", zap.Error(err))
 			}
 		}
 	}
 }
 
-func (s *Subscriber) processBlockEvent(ctx context.Context, tasks chan neutrontypes.RegisteredQuery) error {
+// processBlockEvent schedules the queries which need to be updated at the latest Neutron height. A failure to
+// get the height makes the Subscriber reconnect, only a failure to push the tasks is fatal.
+func (s *Subscriber) processBlockEvent(ctx context.Context, tasks relay.TaskQueue) error {
 	// Get last block height.
 	status, err := s.rpcClient.Status(ctx)
 	if err != nil {
-		return fmt.Errorf("failed to get Status: %w", err)
+		// the rpc node is most likely unreachable, so are the subscriptions
+		return &deadSubscriptionsError{reason: reconnectReasonStatus, err: fmt.Errorf("failed to get Status: %w", err)}
 	}
-	currentHeight := uint64(status.SyncInfo.LatestBlockHeight)
 
+	return s.scheduleQueries(ctx, tasks, uint64(status.SyncInfo.LatestBlockHeight))
+}
+
+// scheduleQueries pushes the active queries which need to be updated at the current height to the tasks queue.
+func (s *Subscriber) scheduleQueries(ctx context.Context, tasks relay.TaskQueue, currentHeight uint64) error {
 	for _, activeQuery := range s.activeQueries {
 		// Skip the ActiveQuery if we didn't reach the update time.
 		if currentHeight < (activeQuery.LastSubmittedResultLocalHeight + activeQuery.UpdatePeriod) {
@@ -184,8 +353,10 @@ func (s *Subscriber) processBlockEvent(ctx context.Context, tasks chan neutronty
 		}
 
 		// Send the query to the tasks queue.
-		tasks <- *activeQuery
-		instrumenters.SetSubscriberTaskQueueNumElements(len(tasks))
+		if err := tasks.Push(ctx, *activeQuery, currentHeight); err != nil {
+			return fmt.Errorf("failed to push query to the tasks queue: %w", err)
+		}
+		instrumenters.SetSubscriberTaskQueueNumElements(s.connectionID, tasks.Len())
 
 		// Set the LastSubmittedResultLocalHeight to the current height.
 		activeQuery.LastSubmittedResultLocalHeight = currentHeight
@@ -220,7 +391,8 @@ func (s *Subscriber) processUpdateEvent(ctx context.Context, event tmtypes.Resul
 		}
 		queryIDNumber, err := strconv.ParseUint(queryID, 10, 64)
 		if err != nil {
-			return fmt.Errorf("failed to parse queryID: %w", err)
+			s.logger.Error("Skipping query (failed to parse queryID)", zap.String("query_id", queryID), zap.Error(err))
+			continue
 		}
 
 		if !s.isWatchedQueryID(queryIDNumber) {
@@ -244,7 +416,7 @@ func (s *Subscriber) processUpdateEvent(ctx context.Context, event tmtypes.Resul
 
 		// Save the updated query information to memory.
 		s.activeQueries[queryID] = neutronQuery
-		instrumenters.SetQueriesToProcessNumElements(len(s.activeQueries))
+		instrumenters.SetQueriesToProcessNumElements(s.connectionID, len(s.activeQueries))
 		s.logger.Debug("Query updated(created)", zap.String("query_id", queryID), zap.Int("total_queries_number", len(s.activeQueries)))
 	}
 
@@ -271,13 +443,118 @@ func (s *Subscriber) processRemoveEvent(event tmtypes.ResultEvent) error {
 
 		// Delete the query from the active queries list.
 		delete(s.activeQueries, queryID)
-		instrumenters.SetQueriesToProcessNumElements(len(s.activeQueries))
+		instrumenters.SetQueriesToProcessNumElements(s.connectionID, len(s.activeQueries))
 		s.logger.Debug("Query removed", zap.String("query_id", queryID), zap.Int("total_queries_number", len(s.activeQueries)))
 	}
 
 	return nil
 }
 
+// reloadQueries replaces the active queries with the ones registered on Neutron, so the query updates and
+// removals the Subscriber has no events of are applied. The local heights of the queries already known are
+// kept, otherwise the queries scheduled recently would be scheduled again. The changes are recorded as
+// the queries drift if trackDrift is true, i.e. if the Subscriber was expected to get the events of them.
+func (s *Subscriber) reloadQueries(ctx context.Context, trackDrift bool) error {
+	queries, err := s.getNeutronRegisteredQueries(ctx)
+	if err != nil {
+		return fmt.Errorf("could not getNeutronRegisteredQueries: %w", err)
+	}
+
+	var added, removed, updated int
+	for queryID, neutronQuery := range queries {
+		activeQuery, ok := s.activeQueries[queryID]
+		if !ok {
+			added++
+			s.logger.Debug("Query added on reload", zap.String("query_id", queryID))
+			continue
+		}
+		if queryParamsChanged(activeQuery, neutronQuery) {
+			updated++
+			s.logger.Debug("Query updated on reload", zap.String("query_id", queryID))
+		}
+		neutronQuery.LastSubmittedResultLocalHeight = max(neutronQuery.LastSubmittedResultLocalHeight,
+			activeQuery.LastSubmittedResultLocalHeight)
+	}
+	for queryID := range s.activeQueries {
+		if _, ok := queries[queryID]; !ok {
+			removed++
+			s.logger.Debug("Query removed on reload", zap.String("query_id", queryID))
+		}
+	}
+
+	s.activeQueries = queries
+	instrumenters.SetQueriesToProcessNumElements(s.connectionID, len(s.activeQueries))
+	if trackDrift {
+		instrumenters.AddQueriesDrift(s.connectionID, added, removed, updated)
+	}
+
+	log := s.logger.Debug
+	if added > 0 || removed > 0 || updated > 0 {
+		log = s.logger.Info
+	}
+	log("active queries reloaded",
+		zap.Int("added", added),
+		zap.Int("removed", removed),
+		zap.Int("updated", updated),
+		zap.Int("total_queries_number", len(s.activeQueries)))
+	return nil
+}
+
+// queryParamsChanged returns true if the params of the query which can be changed by its owner differ.
+func queryParamsChanged(a, b *neutrontypes.RegisteredQuery) bool {
+	if a.UpdatePeriod != b.UpdatePeriod || a.TransactionsFilter != b.TransactionsFilter || len(a.Keys) != len(b.Keys) {
+		return true
+	}
+	for i := range a.Keys {
+		if a.Keys[i].Path != b.Keys[i].Path || !bytes.Equal(a.Keys[i].Key, b.Keys[i].Key) {
+			return true
+		}
+	}
+	return false
+}
+
+// subscribe subscribes to the query updated, query removed and new block events.
+func (s *Subscriber) subscribe(ctx context.Context) (updateEvents, removeEvents, blockEvents <-chan tmtypes.ResultEvent, err error) {
+	updateEvents, err = s.rpcClient.Subscribe(ctx, s.subscriberName(), s.getQueryUpdatedSubscription())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not subscribe to events: %w", err)
+	}
+
+	removeEvents, err = s.rpcClient.Subscribe(ctx, s.subscriberName(), s.getQueryRemovedSubscription())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not subscribe to events: %w", err)
+	}
+
+	blockEvents, err = s.rpcClient.Subscribe(ctx, s.subscriberName(), s.getNewBlockHeaderSubscription())
+	if err != nil {
+		return nil, nil, nil, fmt.Errorf("could not subscribe to events: %w", err)
+	}
+
+	return updateEvents, removeEvents, blockEvents, nil
+}
+
+// replaceRPCClient starts a new rpc client and stops the current one. The current client is kept if the
+// Subscriber can't create new ones.
+func (s *Subscriber) replaceRPCClient() error {
+	if s.newRPCClient == nil {
+		return nil
+	}
+
+	rpcClient, err := s.newRPCClient()
+	if err != nil {
+		return fmt.Errorf("could not create new tendermint rpcClient: %w", err)
+	}
+	if err := rpcClient.Start(); err != nil {
+		return fmt.Errorf("could not start tendermint rpcClient: %w", err)
+	}
+
+	if err := s.rpcClient.Stop(); err != nil {
+		s.logger.Debug("failed to stop the previous tendermint rpcClient", zap.Error(err))
+	}
+	s.rpcClient = rpcClient
+	return nil
+}
+
 // unsubscribes from all previously registered subscriptions. Please note that
 // this method does not return an error and does not panic.
 func (s *Subscriber) unsubscribe() {
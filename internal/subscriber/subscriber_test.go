@@ -4,10 +4,13 @@ import (
 	"context"
 	"fmt"
 	"sort"
+	"sync"
 	"testing"
+	"time"
 
 	ctypes "github.com/cometbft/cometbft/rpc/core/types"
 	"github.com/neutron-org/neutron-query-relayer/internal/registry"
+	"github.com/neutron-org/neutron-query-relayer/internal/scheduler"
 	"github.com/neutron-org/neutron-query-relayer/internal/subscriber"
 	"github.com/neutron-org/neutron-query-relayer/internal/subscriber/querier/client/query"
 	mock_subscriber "github.com/neutron-org/neutron-query-relayer/testutil/mocks/subscriber"
@@ -49,7 +52,7 @@ func TestDoneShouldEndSubscribe(t *testing.T) {
 		},
 	}, nil)
 
-	queriesTasksQueue := make(chan neutrontypes.RegisteredQuery, 100)
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
 	cfg := subscriber.Config{
 		ConnectionID: "",
 		WatchedTypes: nil,
@@ -111,7 +114,7 @@ func TestSubscribeContinuesOnMissingQuery(t *testing.T) {
 		},
 	}, nil)
 
-	queriesTasksQueue := make(chan neutrontypes.RegisteredQuery, 100)
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
 	cfg := subscriber.Config{
 		ConnectionID: "",
 		WatchedTypes: nil,
@@ -205,7 +208,7 @@ func TestSubscribeWithEmptyQueryIDs(t *testing.T) {
 		},
 	}, nil)
 
-	queriesTasksQueue := make(chan neutrontypes.RegisteredQuery, 100)
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
 	cfg := subscriber.Config{
 		ConnectionID: "",
 		WatchedTypes: []neutrontypes.InterchainQueryType{
@@ -240,8 +243,8 @@ func TestSubscribeWithEmptyQueryIDs(t *testing.T) {
 			// in this block we are going to check queriesTasksQueue are exactly 1 and 2
 			generateNewBlock()
 			queries := []neutrontypes.RegisteredQuery{
-				<-queriesTasksQueue,
-				<-queriesTasksQueue,
+				popTask(t, queriesTasksQueue),
+				popTask(t, queriesTasksQueue),
 			}
 			// reorder the queries by id, as Subscriber.activeQueries is a map, it does not guarantee the order of the iteration
 			sort.Slice(queries, func(i, j int) bool {
@@ -249,7 +252,7 @@ func TestSubscribeWithEmptyQueryIDs(t *testing.T) {
 			})
 			assert.Equal(t, uint64(1), queries[0].Id)
 			assert.Equal(t, uint64(2), queries[1].Id)
-			assert.Equal(t, 0, len(queriesTasksQueue))
+			assert.Equal(t, 0, queriesTasksQueue.Len())
 		}
 
 		{
@@ -285,9 +288,9 @@ func TestSubscribeWithEmptyQueryIDs(t *testing.T) {
 
 			generateNewBlock()
 			queries := []neutrontypes.RegisteredQuery{
-				<-queriesTasksQueue,
-				<-queriesTasksQueue,
-				<-queriesTasksQueue,
+				popTask(t, queriesTasksQueue),
+				popTask(t, queriesTasksQueue),
+				popTask(t, queriesTasksQueue),
 			}
 			// reorder the queries by id, as Subscriber.activeQueries is a map, it does not guarantee the order of the iteration
 			sort.Slice(queries, func(i, j int) bool {
@@ -296,7 +299,7 @@ func TestSubscribeWithEmptyQueryIDs(t *testing.T) {
 			assert.Equal(t, uint64(1), queries[0].Id)
 			assert.Equal(t, uint64(2), queries[1].Id)
 			assert.Equal(t, uint64(3), queries[2].Id)
-			assert.Equal(t, 0, len(queriesTasksQueue))
+			assert.Equal(t, 0, queriesTasksQueue.Len())
 		}
 
 		// should terminate Subscribe() function
@@ -374,7 +377,7 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 		},
 	}, nil)
 
-	queriesTasksQueue := make(chan neutrontypes.RegisteredQuery, 100)
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
 	cfg := subscriber.Config{
 		ConnectionID: "",
 		WatchedTypes: []neutrontypes.InterchainQueryType{
@@ -419,8 +422,8 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 			// in this block we are going to check queriesTasksQueue are exactly 1 and 3
 			generateNewBlock()
 			queries := []neutrontypes.RegisteredQuery{
-				<-queriesTasksQueue,
-				<-queriesTasksQueue,
+				popTask(t, queriesTasksQueue),
+				popTask(t, queriesTasksQueue),
 			}
 			// reorder the queries by id, as Subscriber.activeQueries is a map, it does not guarantee the order of the iteration
 			sort.Slice(queries, func(i, j int) bool {
@@ -428,7 +431,7 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 			})
 			assert.Equal(t, uint64(1), queries[0].Id)
 			assert.Equal(t, uint64(3), queries[1].Id)
-			assert.Equal(t, 0, len(queriesTasksQueue))
+			assert.Equal(t, 0, queriesTasksQueue.Len())
 		}
 
 		{
@@ -436,8 +439,8 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 			emitUpdateEventForQueryID("4")
 			generateNewBlock()
 			queries := []neutrontypes.RegisteredQuery{
-				<-queriesTasksQueue,
-				<-queriesTasksQueue,
+				popTask(t, queriesTasksQueue),
+				popTask(t, queriesTasksQueue),
 			}
 			// reorder the queries by id, as Subscriber.activeQueries is a map, it does not guarantee the order of the iteration
 			sort.Slice(queries, func(i, j int) bool {
@@ -445,7 +448,7 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 			})
 			assert.Equal(t, uint64(1), queries[0].Id)
 			assert.Equal(t, uint64(3), queries[1].Id)
-			assert.Equal(t, 0, len(queriesTasksQueue))
+			assert.Equal(t, 0, queriesTasksQueue.Len())
 		}
 
 		{
@@ -473,9 +476,9 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 
 			generateNewBlock()
 			queries := []neutrontypes.RegisteredQuery{
-				<-queriesTasksQueue,
-				<-queriesTasksQueue,
-				<-queriesTasksQueue,
+				popTask(t, queriesTasksQueue),
+				popTask(t, queriesTasksQueue),
+				popTask(t, queriesTasksQueue),
 			}
 			// reorder the queries by id, as Subscriber.activeQueries is a map, it does not guarantee the order of the iteration
 			sort.Slice(queries, func(i, j int) bool {
@@ -484,7 +487,7 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 			assert.Equal(t, uint64(1), queries[0].Id)
 			assert.Equal(t, uint64(3), queries[1].Id)
 			assert.Equal(t, uint64(5), queries[2].Id)
-			assert.Equal(t, 0, len(queriesTasksQueue))
+			assert.Equal(t, 0, queriesTasksQueue.Len())
 		}
 
 		// should terminate Subscribe() function
@@ -494,3 +497,332 @@ func TestSubscribeWithSpecifiedQueryIDs(t *testing.T) {
 	err = s.Subscribe(ctx, queriesTasksQueue)
 	assert.Equal(t, err, nil)
 }
+
+func TestSubscribeReloadsQueriesOnReconnect(t *testing.T) {
+	// Create a new controller
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfgLogger := zap.NewProductionConfig()
+	logger, err := cfgLogger.Build()
+	require.NoError(t, err)
+
+	rpcClient := mock_subscriber.NewMockRpcHttpClient(ctrl)
+	restQuery := mock_subscriber.NewMockRestHttpQuery(ctrl)
+
+	// the subscriptions die right away, the new ones are alive
+	deadBlockEvents := make(chan ctypes.ResultEvent)
+	close(deadBlockEvents)
+	blockEvents := make(chan ctypes.ResultEvent)
+	rpcClient.EXPECT().Start()
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(deadBlockEvents, nil)
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(blockEvents, nil)
+	rpcClient.EXPECT().Unsubscribe(gomock.Any(), gomock.Any(), gomock.Any()).Times(6)
+
+	registeredQuery := func(id string) *query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0 {
+		return &query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0{
+			ID:                             id,
+			Owner:                          "owner",
+			QueryType:                      "kv",
+			UpdatePeriod:                   "1",
+			LastSubmittedResultLocalHeight: "0",
+			LastSubmittedResultRemoteHeight: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0LastSubmittedResultRemoteHeight{
+				RevisionHeight: "0",
+				RevisionNumber: "0",
+			},
+		}
+	}
+	registeredQueries := func(ids ...string) *query.NeutronInterchainQueriesRegisteredQueriesOK {
+		res := &query.NeutronInterchainQueriesRegisteredQueriesOK{
+			Payload: &query.NeutronInterchainQueriesRegisteredQueriesOKBody{
+				Pagination: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyPagination{},
+			},
+		}
+		for _, id := range ids {
+			res.Payload.RegisteredQueries = append(res.Payload.RegisteredQueries, registeredQuery(id))
+		}
+		return res
+	}
+	// query 2 is removed and query 3 is created while the Subscriber is disconnected
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Return(registeredQueries("1", "2"), nil)
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Return(registeredQueries("1", "3"), nil)
+
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
+	cfg := subscriber.Config{
+		ConnectionID: "",
+		WatchedTypes: []neutrontypes.InterchainQueryType{"kv"},
+		Registry:     registry.New(&registry.RegistryConfig{Addresses: make([]string, 0)}),
+	}
+	s, err := subscriber.NewSubscriber(&cfg, rpcClient, restQuery, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		rpcClient.EXPECT().Status(gomock.Any()).Return(&ctypes.ResultStatus{
+			SyncInfo: ctypes.SyncInfo{LatestBlockHeight: 1},
+		}, nil)
+		blockEvents <- ctypes.ResultEvent{}
+
+		queries := []neutrontypes.RegisteredQuery{
+			popTask(t, queriesTasksQueue),
+			popTask(t, queriesTasksQueue),
+		}
+		sort.Slice(queries, func(i, j int) bool {
+			return queries[i].Id < queries[j].Id
+		})
+		assert.Equal(t, uint64(1), queries[0].Id)
+		assert.Equal(t, uint64(3), queries[1].Id)
+		assert.Equal(t, 0, queriesTasksQueue.Len())
+
+		// should terminate Subscribe() function
+		cancel()
+	}()
+
+	err = s.Subscribe(ctx, queriesTasksQueue)
+	assert.Equal(t, err, nil)
+}
+
+func TestSubscribeReconcilesQueriesAfterMalformedEvent(t *testing.T) {
+	// Create a new controller
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfgLogger := zap.NewProductionConfig()
+	logger, err := cfgLogger.Build()
+	require.NoError(t, err)
+
+	rpcClient := mock_subscriber.NewMockRpcHttpClient(ctrl)
+	restQuery := mock_subscriber.NewMockRestHttpQuery(ctrl)
+
+	removeEvents := make(chan ctypes.ResultEvent)
+	blockEvents := make(chan ctypes.ResultEvent)
+	rpcClient.EXPECT().Start()
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(removeEvents, nil)
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(blockEvents, nil)
+	rpcClient.EXPECT().Unsubscribe(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+
+	registeredQueries := func(ids ...string) *query.NeutronInterchainQueriesRegisteredQueriesOK {
+		res := &query.NeutronInterchainQueriesRegisteredQueriesOK{
+			Payload: &query.NeutronInterchainQueriesRegisteredQueriesOKBody{
+				Pagination: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyPagination{},
+			},
+		}
+		for _, id := range ids {
+			res.Payload.RegisteredQueries = append(res.Payload.RegisteredQueries,
+				&query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0{
+					ID:                             id,
+					Owner:                          "owner",
+					QueryType:                      "kv",
+					UpdatePeriod:                   "1",
+					LastSubmittedResultLocalHeight: "0",
+					LastSubmittedResultRemoteHeight: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0LastSubmittedResultRemoteHeight{
+						RevisionHeight: "0",
+						RevisionNumber: "0",
+					},
+				})
+		}
+		return res
+	}
+	// query 2 is removed, but the remove event is malformed
+	reconciled := make(chan struct{})
+	var reconciledOnce sync.Once
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Return(registeredQueries("1", "2"), nil)
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Do(func(*query.NeutronInterchainQueriesRegisteredQueriesParams, ...query.ClientOption) {
+		reconciledOnce.Do(func() { close(reconciled) })
+	}).Return(registeredQueries("1"), nil).AnyTimes()
+
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
+	cfg := subscriber.Config{
+		ConnectionID:    "",
+		WatchedTypes:    []neutrontypes.InterchainQueryType{"kv"},
+		Registry:        registry.New(&registry.RegistryConfig{Addresses: make([]string, 0)}),
+		ReconcilePeriod: 50 * time.Millisecond,
+	}
+	s, err := subscriber.NewSubscriber(&cfg, rpcClient, restQuery, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		events := make(map[string][]string)
+		events[subscriber.QueryIdAttr] = []string{"2"}
+		events[subscriber.ConnectionIdAttr] = []string{"kek"}
+		removeEvents <- ctypes.ResultEvent{Events: events}
+
+		<-reconciled
+		rpcClient.EXPECT().Status(gomock.Any()).Return(&ctypes.ResultStatus{
+			SyncInfo: ctypes.SyncInfo{LatestBlockHeight: 1},
+		}, nil)
+		blockEvents <- ctypes.ResultEvent{}
+
+		assert.Equal(t, uint64(1), popTask(t, queriesTasksQueue).Id)
+		assert.Equal(t, 0, queriesTasksQueue.Len())
+
+		// should terminate Subscribe() function
+		cancel()
+	}()
+
+	err = s.Subscribe(ctx, queriesTasksQueue)
+	assert.Equal(t, err, nil)
+}
+
+func TestSubscribeSkipsUpdateEventWithoutOwner(t *testing.T) {
+	// Create a new controller
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfgLogger := zap.NewProductionConfig()
+	logger, err := cfgLogger.Build()
+	require.NoError(t, err)
+
+	rpcClient := mock_subscriber.NewMockRpcHttpClient(ctrl)
+	restQuery := mock_subscriber.NewMockRestHttpQuery(ctrl)
+
+	updateEvents := make(chan ctypes.ResultEvent)
+	blockEvents := make(chan ctypes.ResultEvent)
+	rpcClient.EXPECT().Start()
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(updateEvents, nil)
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(blockEvents, nil)
+	rpcClient.EXPECT().Unsubscribe(gomock.Any(), gomock.Any(), gomock.Any()).Times(3)
+
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Return(&query.NeutronInterchainQueriesRegisteredQueriesOK{
+		Payload: &query.NeutronInterchainQueriesRegisteredQueriesOKBody{
+			Pagination: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyPagination{},
+			RegisteredQueries: []*query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0{
+				{
+					ID:                             "1",
+					Owner:                          "owner",
+					QueryType:                      "kv",
+					UpdatePeriod:                   "1",
+					LastSubmittedResultLocalHeight: "0",
+					LastSubmittedResultRemoteHeight: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0LastSubmittedResultRemoteHeight{
+						RevisionHeight: "0",
+						RevisionNumber: "0",
+					},
+				},
+			},
+		},
+	}, nil)
+
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
+	cfg := subscriber.Config{
+		ConnectionID: "",
+		WatchedTypes: []neutrontypes.InterchainQueryType{"kv"},
+		Registry:     registry.New(&registry.RegistryConfig{Addresses: make([]string, 0)}),
+	}
+	s, err := subscriber.NewSubscriber(&cfg, rpcClient, restQuery, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// the owner attribute is missing, the event is skipped without fetching the query
+		events := make(map[string][]string)
+		events[subscriber.QueryIdAttr] = []string{"2"}
+		events[subscriber.ConnectionIdAttr] = []string{"kek"}
+		events[subscriber.KvKeyAttr] = []string{"kek"}
+		events[subscriber.TransactionsFilterAttr] = []string{"kek"}
+		events[subscriber.TypeAttr] = []string{"kek"}
+		updateEvents <- ctypes.ResultEvent{Events: events}
+
+		rpcClient.EXPECT().Status(gomock.Any()).Return(&ctypes.ResultStatus{
+			SyncInfo: ctypes.SyncInfo{LatestBlockHeight: 1},
+		}, nil)
+		blockEvents <- ctypes.ResultEvent{}
+
+		assert.Equal(t, uint64(1), popTask(t, queriesTasksQueue).Id)
+		assert.Equal(t, 0, queriesTasksQueue.Len())
+
+		// should terminate Subscribe() function
+		cancel()
+	}()
+
+	err = s.Subscribe(ctx, queriesTasksQueue)
+	assert.Equal(t, err, nil)
+}
+
+func TestSubscribeReconnectsOnStatusFailure(t *testing.T) {
+	// Create a new controller
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	cfgLogger := zap.NewProductionConfig()
+	logger, err := cfgLogger.Build()
+	require.NoError(t, err)
+
+	rpcClient := mock_subscriber.NewMockRpcHttpClient(ctrl)
+	restQuery := mock_subscriber.NewMockRestHttpQuery(ctrl)
+
+	deadBlockEvents := make(chan ctypes.ResultEvent)
+	blockEvents := make(chan ctypes.ResultEvent)
+	rpcClient.EXPECT().Start()
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(deadBlockEvents, nil)
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any())
+	rpcClient.EXPECT().Subscribe(gomock.Any(), gomock.Any(), gomock.Any()).Return(blockEvents, nil)
+	rpcClient.EXPECT().Unsubscribe(gomock.Any(), gomock.Any(), gomock.Any()).Times(6)
+
+	restQuery.EXPECT().NeutronInterchainQueriesRegisteredQueries(gomock.Any()).Return(&query.NeutronInterchainQueriesRegisteredQueriesOK{
+		Payload: &query.NeutronInterchainQueriesRegisteredQueriesOKBody{
+			Pagination: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyPagination{},
+			RegisteredQueries: []*query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0{
+				{
+					ID:                             "1",
+					Owner:                          "owner",
+					QueryType:                      "kv",
+					UpdatePeriod:                   "1",
+					LastSubmittedResultLocalHeight: "0",
+					LastSubmittedResultRemoteHeight: &query.NeutronInterchainQueriesRegisteredQueriesOKBodyRegisteredQueriesItems0LastSubmittedResultRemoteHeight{
+						RevisionHeight: "0",
+						RevisionNumber: "0",
+					},
+				},
+			},
+		},
+	}, nil).Times(2)
+
+	queriesTasksQueue := scheduler.NewScheduler("connection-0", 100, nil)
+	cfg := subscriber.Config{
+		ConnectionID: "",
+		WatchedTypes: []neutrontypes.InterchainQueryType{"kv"},
+		Registry:     registry.New(&registry.RegistryConfig{Addresses: make([]string, 0)}),
+	}
+	s, err := subscriber.NewSubscriber(&cfg, rpcClient, restQuery, logger)
+	assert.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		// the node connection drops while the block is processed
+		rpcClient.EXPECT().Status(gomock.Any()).Return(nil, fmt.Errorf("connection refused"))
+		deadBlockEvents <- ctypes.ResultEvent{}
+
+		rpcClient.EXPECT().Status(gomock.Any()).Return(&ctypes.ResultStatus{
+			SyncInfo: ctypes.SyncInfo{LatestBlockHeight: 1},
+		}, nil)
+		blockEvents <- ctypes.ResultEvent{}
+
+		assert.Equal(t, uint64(1), popTask(t, queriesTasksQueue).Id)
+
+		// should terminate Subscribe() function
+		cancel()
+	}()
+
+	err = s.Subscribe(ctx, queriesTasksQueue)
+	assert.Equal(t, err, nil)
+}
+
+// popTask pops a task from the queue failing the test if the queue stays empty for too long.
+func popTask(t *testing.T, queue *scheduler.Scheduler) neutrontypes.RegisteredQuery {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	query, err := queue.Pop(ctx)
+	assert.NoError(t, err)
+	return query
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/url"
+	"strconv"
 	"time"
 
 	tmhttp "github.com/cometbft/cometbft/rpc/client/http"
@@ -71,6 +72,20 @@ func (s *Subscriber) getNeutronRegisteredQuery(ctx context.Context, queryId stri
 	return neutronQuery, nil
 }
 
+// RegisteredQuery retrieves a registered query from Neutron and checks that it's watched by the Subscriber.
+func (s *Subscriber) RegisteredQuery(ctx context.Context, queryID uint64) (*neutrontypes.RegisteredQuery, error) {
+	neutronQuery, err := s.getNeutronRegisteredQuery(ctx, strconv.FormatUint(queryID, 10))
+	if err != nil {
+		return nil, err
+	}
+
+	if neutronQuery.ConnectionId != s.connectionID || !s.isWatchedMsgType(neutronQuery.QueryType) ||
+		!s.isWatchedAddress(neutronQuery.Owner) || !s.isWatchedQueryID(neutronQuery.Id) {
+		return nil, fmt.Errorf("query with id=%d is not watched on connection %s", queryID, s.connectionID)
+	}
+	return neutronQuery, nil
+}
+
 // getNeutronRegisteredQueries retrieves the list of registered queries filtered by owner, connection, query type, and queryID.
 func (s *Subscriber) getNeutronRegisteredQueries(ctx context.Context) (map[string]*neutrontypes.RegisteredQuery, error) {
 	var out = map[string]*neutrontypes.RegisteredQuery{}
@@ -128,6 +143,7 @@ func (s *Subscriber) checkEvents(event tmtypes.ResultEvent) (bool, error) {
 
 	if len(events[KvKeyAttr]) != icqEventsCount ||
 		len(events[TransactionsFilterAttr]) != icqEventsCount ||
+		len(events[OwnerAttr]) != icqEventsCount ||
 		len(events[QueryIdAttr]) != icqEventsCount ||
 		len(events[TypeAttr]) != icqEventsCount {
 		return false, fmt.Errorf("events attributes length does not match for events=%v", events)
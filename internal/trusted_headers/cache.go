@@ -0,0 +1,106 @@
+package trusted_headers
+
+import (
+	"context"
+	"time"
+
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+)
+
+// names of the caches in metrics
+const (
+	headersCacheName        = "headers"
+	trustedHeightsCacheName = "trusted_heights"
+)
+
+// trustedHeightKey is the target chain height the consensus state is chosen as trusted for in a scope.
+type trustedHeightKey struct {
+	scope  indexScope
+	height uint64
+}
+
+// trustedHeight is the consensus state chosen as trusted for a target chain height along with the time
+// the consensus state leaves the trusting period (minus submissionMarginPeriod).
+type trustedHeight struct {
+	state     consensusStateInfo
+	expiresAt time.Time
+}
+
+// lightSignedHeader returns the light signed header with the validator set of the target chain at the
+// given height. The returned header is shared with the cache and must not be modified.
+func (thf *TrustedHeaderFetcher) lightSignedHeader(ctx context.Context, height clienttypes.Height) (*tmclient.Header, error) {
+	if thf.headers != nil {
+		cached, ok := thf.headers.Get(height)
+		neutronmetrics.IncTrustedHeadersCache(thf.connectionID, headersCacheName, ok)
+		if ok {
+			return cached.(*tmclient.Header), nil
+		}
+	}
+
+	header, err := thf.retryGetLightSignedHeaderAtHeight(ctx, height.RevisionHeight)
+	if err != nil {
+		return nil, err
+	}
+
+	if thf.headers != nil {
+		thf.headers.Add(height, header)
+	}
+	return header, nil
+}
+
+// cachedTrustedHeight returns the trusted consensus state previously chosen for the given height if
+// it's still within the trusting period.
+func (thf *TrustedHeaderFetcher) cachedTrustedHeight(scope indexScope, height uint64) (consensusStateInfo, bool) {
+	if thf.trustedHeights == nil {
+		return consensusStateInfo{}, false
+	}
+
+	key := trustedHeightKey{scope: scope, height: height}
+	cached, ok := thf.trustedHeights.Get(key)
+	if ok && time.Now().After(cached.(trustedHeight).expiresAt) {
+		thf.trustedHeights.Remove(key)
+		ok = false
+	}
+	neutronmetrics.IncTrustedHeadersCache(thf.connectionID, trustedHeightsCacheName, ok)
+	if !ok {
+		return consensusStateInfo{}, false
+	}
+
+	return cached.(trustedHeight).state, true
+}
+
+func (thf *TrustedHeaderFetcher) cacheTrustedHeight(scope indexScope, height uint64, state consensusStateInfo, expiresAt time.Time) {
+	if thf.trustedHeights == nil {
+		return
+	}
+
+	thf.trustedHeights.Add(trustedHeightKey{scope: scope, height: height}, trustedHeight{state: state, expiresAt: expiresAt})
+}
+
+// forgetTrustedHeights removes the trusted heights which consensus state is at the given height from the cache.
+func (thf *TrustedHeaderFetcher) forgetTrustedHeights(scope indexScope, csHeight uint64) {
+	if thf.trustedHeights == nil {
+		return
+	}
+
+	for _, key := range thf.trustedHeights.Keys() {
+		cached, ok := thf.trustedHeights.Peek(key)
+		if ok && key.(trustedHeightKey).scope == scope && cached.(trustedHeight).state.height == csHeight {
+			thf.trustedHeights.Remove(key)
+		}
+	}
+}
+
+// forgetHeaders removes the headers from the cache, so they are fetched again next time.
+func (thf *TrustedHeaderFetcher) forgetHeaders(revision uint64, heights ...uint64) {
+	if thf.headers == nil {
+		return
+	}
+
+	for _, height := range heights {
+		thf.headers.Remove(clienttypes.NewHeight(revision, height))
+	}
+}
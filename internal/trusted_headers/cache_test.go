@@ -0,0 +1,93 @@
+package trusted_headers
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	"github.com/cosmos/relayer/v2/relayer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+)
+
+const (
+	testClientID = "07-tendermint-0"
+	// testChainID is the target chain ID of revision 1
+	testChainID = "target-1"
+)
+
+var testScope = indexScope{clientID: testClientID, revision: 1}
+
+func newTestFetcher(cacheSize int) *TrustedHeaderFetcher {
+	neutronChain := &relayer.Chain{PathEnd: &relayer.PathEnd{ConnectionID: "connection-0"}}
+	return NewTrustedHeaderFetcher(neutronChain, nil, zap.NewNop(), cacheSize, time.Minute, nil, nil)
+}
+
+func TestTrustedHeightsCacheEviction(t *testing.T) {
+	now := time.Now()
+	thf := newTestFetcher(2)
+
+	for _, height := range []uint64{100, 200, 300} {
+		thf.cacheTrustedHeight(testScope, height, consensusStateInfo{height: height - 1}, now.Add(time.Hour))
+	}
+
+	tests := []struct {
+		name   string
+		scope  indexScope
+		height uint64
+		found  bool
+	}{
+		{name: "least recently used height is evicted", scope: testScope, height: 100},
+		{name: "recent height is kept", scope: testScope, height: 200, found: true},
+		{name: "latest height is kept", scope: testScope, height: 300, found: true},
+		{name: "height of another client", scope: indexScope{clientID: "07-tendermint-1", revision: 1}, height: 300},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, ok := thf.cachedTrustedHeight(tt.scope, tt.height)
+			assert.Equal(t, tt.found, ok)
+			if tt.found {
+				assert.Equal(t, tt.height-1, state.height)
+			}
+		})
+	}
+}
+
+func TestTrustedHeightsCacheDropsExpiredHeights(t *testing.T) {
+	thf := newTestFetcher(2)
+	thf.cacheTrustedHeight(testScope, 100, consensusStateInfo{height: 99}, time.Now().Add(-time.Second))
+
+	_, ok := thf.cachedTrustedHeight(testScope, 100)
+	assert.False(t, ok)
+	assert.Equal(t, 0, thf.trustedHeights.Len(), "expired height must be removed from the cache")
+}
+
+func TestHeadersCacheEviction(t *testing.T) {
+	thf := newTestFetcher(2)
+	for _, height := range []uint64{100, 200, 300} {
+		thf.headers.Add(clienttypes.NewHeight(1, height), &tmclient.Header{})
+	}
+
+	assert.False(t, thf.headers.Contains(clienttypes.NewHeight(1, 100)))
+	for _, height := range []uint64{200, 300} {
+		// the target chain is not set, so the header can only come from the cache
+		header, err := thf.lightSignedHeader(context.Background(), clienttypes.NewHeight(1, height))
+		require.NoError(t, err)
+		assert.NotNil(t, header)
+	}
+
+	thf.forgetHeaders(1, 200, 300)
+	assert.Equal(t, 0, thf.headers.Len())
+}
+
+func TestCachesDisabled(t *testing.T) {
+	thf := newTestFetcher(0)
+	thf.cacheTrustedHeight(testScope, 100, consensusStateInfo{height: 99}, time.Now().Add(time.Hour))
+
+	_, ok := thf.cachedTrustedHeight(testScope, 100)
+	assert.False(t, ok)
+	assert.Nil(t, thf.headers)
+}
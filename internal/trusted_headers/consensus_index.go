@@ -0,0 +1,227 @@
+package trusted_headers
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+)
+
+// consensusStateInfo is the part of a consensus state of Neutron's client needed to choose and verify
+// trusted headers.
+type consensusStateInfo struct {
+	height             uint64
+	timestamp          time.Time
+	nextValidatorsHash []byte
+	// trackedAt is the time the consensus state was tracked from a client update submitted by the relayer,
+	// it's zero once Neutron's client is known to have the consensus state
+	trackedAt time.Time
+}
+
+// indexScope is a Neutron's client of the target chain and a revision of the target chain the consensus
+// states are indexed in.
+type indexScope struct {
+	clientID string
+	revision uint64
+}
+
+// consensusStatesIndex is a local copy of the consensus states of a Neutron's client in a revision of
+// the target chain, sorted by height, along with the client state.
+type consensusStatesIndex struct {
+	mutex       sync.RWMutex
+	scope       indexScope
+	states      []consensusStateInfo
+	clientState *tmclient.ClientState
+	// refreshedAt is the time of the latest refresh
+	refreshedAt time.Time
+}
+
+// reset replaces the index content with the given consensus states in the scope and client state. The
+// tracked consensus states missing in the given ones are kept if the scope is the same, so they can still
+// be confirmed.
+func (i *consensusStatesIndex) reset(scope indexScope, states []consensusStateInfo, clientState *tmclient.ClientState, now time.Time) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	i.replace(scope, states, clientState, now)
+}
+
+// merge puts the given consensus states of the scope into the index, replaces the client state and removes
+// the consensus states which are out of the trusting period, since Neutron's client prunes them as well.
+// The index is reset instead if it's of another scope.
+func (i *consensusStatesIndex) merge(scope indexScope, states []consensusStateInfo, clientState *tmclient.ClientState, now time.Time) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if scope != i.scope {
+		i.replace(scope, states, clientState, now)
+		return
+	}
+
+	for _, state := range states {
+		i.put(state)
+	}
+	actual := i.states[:0]
+	for _, state := range i.states {
+		if state.timestamp.Add(clientState.TrustingPeriod).After(now) {
+			actual = append(actual, state)
+		}
+	}
+	i.states = actual
+	i.clientState = clientState
+	i.refreshedAt = now
+}
+
+// replace does the reset. The caller must hold the write lock.
+func (i *consensusStatesIndex) replace(scope indexScope, states []consensusStateInfo, clientState *tmclient.ClientState, now time.Time) {
+	if scope == i.scope {
+		states = append(states, i.tracked(states)...)
+	}
+	sort.Slice(states, func(a, b int) bool { return states[a].height < states[b].height })
+
+	i.scope = scope
+	i.states = states
+	i.clientState = clientState
+	i.refreshedAt = now
+}
+
+// latestConfirmed returns the height of the latest consensus state of the scope Neutron's client is known
+// to have. ok is false if there is no such consensus state.
+func (i *consensusStatesIndex) latestConfirmed(scope indexScope) (height uint64, ok bool) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if scope != i.scope {
+		return 0, false
+	}
+	for n := len(i.states) - 1; n >= 0; n-- {
+		if i.states[n].trackedAt.IsZero() {
+			return i.states[n].height, true
+		}
+	}
+	return 0, false
+}
+
+// trackedStates returns the tracked consensus states of the scope along with the time of the latest refresh.
+func (i *consensusStatesIndex) trackedStates(scope indexScope) ([]consensusStateInfo, time.Time) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	if scope != i.scope {
+		return nil, i.refreshedAt
+	}
+	return i.tracked(nil), i.refreshedAt
+}
+
+// confirm marks the tracked consensus state at the height as the one Neutron's client is known to have.
+func (i *consensusStatesIndex) confirm(scope indexScope, height uint64) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if pos, ok := i.search(scope, height); ok {
+		i.states[pos].trackedAt = time.Time{}
+	}
+}
+
+// remove removes the consensus state at the height from the index.
+func (i *consensusStatesIndex) remove(scope indexScope, height uint64) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if pos, ok := i.search(scope, height); ok {
+		i.states = append(i.states[:pos], i.states[pos+1:]...)
+	}
+}
+
+// client returns the client state as of the latest refresh, nil if the index has never been refreshed.
+func (i *consensusStatesIndex) client() *tmclient.ClientState {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	return i.clientState
+}
+
+// add puts a consensus state into the index keeping it sorted. A consensus state of another client or revision
+// is ignored, the index gets the consensus states of the new ones on the next reset. A tracked consensus
+// state doesn't replace the confirmed one at the same height.
+func (i *consensusStatesIndex) add(scope indexScope, state consensusStateInfo) {
+	i.mutex.Lock()
+	defer i.mutex.Unlock()
+
+	if scope != i.scope {
+		return
+	}
+	if pos, ok := i.search(scope, state.height); ok && !state.trackedAt.IsZero() && i.states[pos].trackedAt.IsZero() {
+		return
+	}
+	i.put(state)
+}
+
+// put puts a consensus state into the index keeping it sorted, the consensus state at the same height is
+// replaced. The caller must hold the write lock.
+func (i *consensusStatesIndex) put(state consensusStateInfo) {
+	pos := sort.Search(len(i.states), func(n int) bool { return i.states[n].height >= state.height })
+	if pos < len(i.states) && i.states[pos].height == state.height {
+		i.states[pos] = state
+		return
+	}
+	i.states = append(i.states, consensusStateInfo{})
+	copy(i.states[pos+1:], i.states[pos:])
+	i.states[pos] = state
+}
+
+// search returns the position of the consensus state at the height. The caller must hold the lock.
+func (i *consensusStatesIndex) search(scope indexScope, height uint64) (int, bool) {
+	if scope != i.scope {
+		return 0, false
+	}
+	pos := sort.Search(len(i.states), func(n int) bool { return i.states[n].height >= height })
+	return pos, pos < len(i.states) && i.states[pos].height == height
+}
+
+// tracked returns the tracked consensus states of the index which heights are missing in the given states.
+// The caller must hold the lock.
+func (i *consensusStatesIndex) tracked(states []consensusStateInfo) []consensusStateInfo {
+	known := make(map[uint64]struct{}, len(states))
+	for _, state := range states {
+		known[state.height] = struct{}{}
+	}
+
+	tracked := make([]consensusStateInfo, 0)
+	for _, state := range i.states {
+		if _, ok := known[state.height]; !ok && !state.trackedAt.IsZero() {
+			tracked = append(tracked, state)
+		}
+	}
+	return tracked
+}
+
+// find returns the latest consensus state with a height < the given height and the time it leaves the
+// trusting period (minus submissionMarginPeriod). ok is false if the index is of another scope, there
+// is no such consensus state or it's already out of the trusting period. Since the timestamps grow with
+// the heights, the older consensus states are out of the trusting period as well in the latter case.
+func (i *consensusStatesIndex) find(scope indexScope, height uint64, now time.Time) (state consensusStateInfo, expiresAt time.Time, ok bool) {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	pos := sort.Search(len(i.states), func(n int) bool { return i.states[n].height >= height })
+	if pos == 0 || i.clientState == nil || scope != i.scope {
+		return consensusStateInfo{}, time.Time{}, false
+	}
+
+	state = i.states[pos-1]
+	expiresAt = state.timestamp.Add(i.clientState.TrustingPeriod).Add(-submissionMarginPeriod)
+	if !expiresAt.After(now) {
+		return consensusStateInfo{}, time.Time{}, false
+	}
+	return state, expiresAt, true
+}
+
+// len returns the number of the consensus states in the index.
+func (i *consensusStatesIndex) len() int {
+	i.mutex.RLock()
+	defer i.mutex.RUnlock()
+
+	return len(i.states)
+}
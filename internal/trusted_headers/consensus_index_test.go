@@ -0,0 +1,301 @@
+package trusted_headers
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"testing"
+	"time"
+
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	codectypes "github.com/cosmos/cosmos-sdk/codec/types"
+	"github.com/cosmos/cosmos-sdk/types/query"
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	lru "github.com/hashicorp/golang-lru"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+)
+
+func testClientState() *tmclient.ClientState {
+	return &tmclient.ClientState{ChainId: testChainID, TrustingPeriod: 24 * time.Hour}
+}
+
+// newTestIndex returns an index of the consensus states at the given heights created an hour ago.
+func newTestIndex(now time.Time, heights ...uint64) *consensusStatesIndex {
+	states := make([]consensusStateInfo, 0, len(heights))
+	for _, height := range heights {
+		states = append(states, consensusStateInfo{height: height, timestamp: now.Add(-time.Hour)})
+	}
+
+	index := &consensusStatesIndex{}
+	index.reset(testScope, states, testClientState(), now)
+	return index
+}
+
+func TestConsensusStatesIndexFind(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name   string
+		scope  indexScope
+		height uint64
+		// found is the height of the expected consensus state, 0 if none is expected
+		found uint64
+	}{
+		{name: "exact height", scope: testScope, height: 20, found: 10},
+		{name: "height between two entries", scope: testScope, height: 25, found: 20},
+		{name: "height above the maximum", scope: testScope, height: 100, found: 30},
+		{name: "height of the minimum", scope: testScope, height: 10},
+		{name: "height below the minimum", scope: testScope, height: 5},
+		{name: "another client", scope: indexScope{clientID: "07-tendermint-1", revision: 1}, height: 25},
+		{name: "another revision", scope: indexScope{clientID: testClientID, revision: 2}, height: 25},
+	}
+
+	// the states are sorted on reset
+	index := newTestIndex(now, 30, 10, 20)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			state, expiresAt, ok := index.find(tt.scope, tt.height, now)
+			if tt.found == 0 {
+				assert.False(t, ok)
+				return
+			}
+
+			require.True(t, ok)
+			assert.Equal(t, tt.found, state.height)
+			assert.Equal(t, now.Add(23*time.Hour).Add(-submissionMarginPeriod), expiresAt)
+		})
+	}
+}
+
+func TestConsensusStatesIndexFindSkipsExpiredStates(t *testing.T) {
+	now := time.Now()
+	index := newTestIndex(now, 10, 20)
+
+	_, _, ok := index.find(testScope, 25, now.Add(23*time.Hour))
+	assert.False(t, ok, "consensus state within the submission margin of the trusting period end must not be used")
+
+	state, _, ok := index.find(testScope, 25, now.Add(23*time.Hour).Add(-submissionMarginPeriod).Add(-time.Second))
+	require.True(t, ok)
+	assert.Equal(t, uint64(20), state.height)
+}
+
+func TestTrackClientUpdate(t *testing.T) {
+	now := time.Now()
+	newHeader := func(chainID string, height int64, nextValidatorsHash []byte) *tmclient.Header {
+		return &tmclient.Header{SignedHeader: &cmtproto.SignedHeader{Header: &cmtproto.Header{
+			ChainID:            chainID,
+			Height:             height,
+			Time:               now,
+			NextValidatorsHash: nextValidatorsHash,
+		}}}
+	}
+
+	tests := []struct {
+		name   string
+		update *tmclient.Header
+		// found is the height of the consensus state expected to be found for each height
+		found map[uint64]uint64
+	}{
+		{
+			name:   "new height between two entries",
+			update: newHeader(testChainID, 25, []byte("25")),
+			found:  map[uint64]uint64{25: 20, 26: 25, 30: 25, 31: 30},
+		},
+		{
+			name:   "new maximum height",
+			update: newHeader(testChainID, 40, []byte("40")),
+			found:  map[uint64]uint64{40: 30, 41: 40},
+		},
+		{
+			name:   "new minimum height",
+			update: newHeader(testChainID, 5, []byte("5")),
+			found:  map[uint64]uint64{6: 5, 10: 5, 11: 10},
+		},
+		{
+			name:   "update of another revision is ignored",
+			update: newHeader("target-2", 25, []byte("25")),
+			found:  map[uint64]uint64{26: 20},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			thf := &TrustedHeaderFetcher{index: newTestIndex(now, 10, 20, 30)}
+			thf.TrackClientUpdate(testClientID, tt.update)
+
+			for height, found := range tt.found {
+				state, _, ok := thf.index.find(testScope, height, now)
+				require.True(t, ok, "height %d", height)
+				assert.Equal(t, found, state.height, "height %d", height)
+			}
+		})
+	}
+
+	t.Run("update of an existing height keeps the confirmed consensus state", func(t *testing.T) {
+		thf := &TrustedHeaderFetcher{index: newTestIndex(now, 10, 20, 30)}
+		thf.TrackClientUpdate(testClientID, newHeader(testChainID, 20, []byte("20")))
+
+		assert.Equal(t, 3, thf.index.len())
+		state, _, ok := thf.index.find(testScope, 21, now)
+		require.True(t, ok)
+		assert.Nil(t, state.nextValidatorsHash)
+		assert.True(t, state.trackedAt.IsZero())
+	})
+
+	t.Run("update of another client is ignored", func(t *testing.T) {
+		thf := &TrustedHeaderFetcher{index: newTestIndex(now, 10, 20, 30)}
+		thf.TrackClientUpdate("07-tendermint-1", newHeader(testChainID, 25, []byte("25")))
+
+		assert.Equal(t, 3, thf.index.len())
+	})
+}
+
+func TestConsensusStatesIndexRefresh(t *testing.T) {
+	now := time.Now()
+	heights := func(index *consensusStatesIndex) []uint64 {
+		heights := make([]uint64, 0)
+		for _, state := range index.states {
+			heights = append(heights, state.height)
+		}
+		return heights
+	}
+
+	index := newTestIndex(now, 10, 20, 30)
+	index.add(testScope, consensusStateInfo{height: 25, timestamp: now, trackedAt: now})
+	index.add(testScope, consensusStateInfo{height: 40, timestamp: now, trackedAt: now})
+	latest, ok := index.latestConfirmed(testScope)
+	require.True(t, ok)
+	assert.Equal(t, uint64(30), latest, "tracked consensus states are not confirmed")
+
+	// the consensus states out of the trusting period are dropped by a merge, the fetched ones are confirmed
+	index.merge(testScope, []consensusStateInfo{
+		{height: 30, timestamp: now.Add(-time.Hour)},
+		{height: 40, timestamp: now},
+		{height: 50, timestamp: now},
+	}, testClientState(), now.Add(23*time.Hour+30*time.Minute))
+	assert.Equal(t, []uint64{25, 40, 50}, heights(index))
+	latest, _ = index.latestConfirmed(testScope)
+	assert.Equal(t, uint64(50), latest)
+	tracked, refreshedAt := index.trackedStates(testScope)
+	require.Len(t, tracked, 1)
+	assert.Equal(t, uint64(25), tracked[0].height)
+	assert.Equal(t, now.Add(23*time.Hour+30*time.Minute), refreshedAt)
+
+	// a reset keeps the tracked consensus states of the same scope only
+	index.reset(testScope, []consensusStateInfo{{height: 60, timestamp: now}}, testClientState(), now)
+	assert.Equal(t, []uint64{25, 60}, heights(index))
+	otherScope := indexScope{clientID: "07-tendermint-1", revision: 1}
+	index.reset(otherScope, []consensusStateInfo{{height: 60, timestamp: now}}, testClientState(), now)
+	assert.Equal(t, []uint64{60}, heights(index))
+	_, ok = index.latestConfirmed(testScope)
+	assert.False(t, ok)
+}
+
+// consensusStatesQuerier serves the consensus states of Neutron's client, only ConsensusStates and ConsensusState
+// are implemented.
+type consensusStatesQuerier struct {
+	clienttypes.QueryClient
+	// heights are the heights of the consensus states in revision 1 of testClientID
+	heights []uint64
+	// keys are the pagination keys of the ConsensusStates requests
+	keys []string
+}
+
+func (q *consensusStatesQuerier) ConsensusStates(_ context.Context, req *clienttypes.QueryConsensusStatesRequest,
+	_ ...grpc.CallOption) (*clienttypes.QueryConsensusStatesResponse, error) {
+	q.keys = append(q.keys, string(req.Pagination.Key))
+
+	// the consensus states are stored by the string keys
+	keys := make([]string, 0, len(q.heights))
+	for _, height := range q.heights {
+		keys = append(keys, clienttypes.NewHeight(1, height).String())
+	}
+	sort.Strings(keys)
+
+	states := make([]clienttypes.ConsensusStateWithHeight, 0)
+	for _, key := range keys {
+		if key < string(req.Pagination.Key) {
+			continue
+		}
+		height := clienttypes.MustParseHeight(key)
+		cs, err := codectypes.NewAnyWithValue(&tmclient.ConsensusState{Timestamp: time.Now()})
+		if err != nil {
+			return nil, err
+		}
+		states = append(states, clienttypes.ConsensusStateWithHeight{Height: height, ConsensusState: cs})
+	}
+	return &clienttypes.QueryConsensusStatesResponse{ConsensusStates: states, Pagination: &query.PageResponse{}}, nil
+}
+
+func (q *consensusStatesQuerier) ConsensusState(_ context.Context, req *clienttypes.QueryConsensusStateRequest,
+	_ ...grpc.CallOption) (*clienttypes.QueryConsensusStateResponse, error) {
+	for _, height := range q.heights {
+		if req.ClientId == testClientID && req.RevisionNumber == 1 && req.RevisionHeight == height {
+			return &clienttypes.QueryConsensusStateResponse{}, nil
+		}
+	}
+	return nil, errors.New("consensus state not found")
+}
+
+func TestFetchConsensusStatesFromKey(t *testing.T) {
+	qc := &consensusStatesQuerier{heights: []uint64{9, 95, 99, 100, 120, 150}}
+	thf := &TrustedHeaderFetcher{index: &consensusStatesIndex{}, logger: zap.NewNop()}
+
+	fetched := func(key string) []uint64 {
+		states, err := thf.fetchConsensusStates(context.Background(), qc, testScope, []byte(key))
+		require.NoError(t, err)
+		heights := make([]uint64, 0)
+		for _, state := range states {
+			heights = append(heights, state.height)
+		}
+		sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+		return heights
+	}
+
+	assert.Equal(t, []uint64{9, 95, 99, 100, 120, 150}, fetched(""))
+	// all the heights with the same number of digits as the key one are fetched, the shorter ones may be as well
+	assert.Equal(t, []uint64{9, 95, 99, 120, 150}, fetched("1-120"))
+	assert.Equal(t, []uint64{9, 95, 99, 100, 120, 150}, fetched("1-100"))
+}
+
+func TestCheckTrackedStates(t *testing.T) {
+	now := time.Now()
+	qc := &consensusStatesQuerier{heights: []uint64{10, 20, 25}}
+	thf := &TrustedHeaderFetcher{index: newTestIndex(now, 10, 20), logger: zap.NewNop()}
+	trustedHeights, err := lru.New(10)
+	require.NoError(t, err)
+	thf.trustedHeights = trustedHeights
+
+	// 25 is committed, 26 has failed, 27 is tracked after the previous refresh and may be still in the mempool
+	refreshedAt := now.Add(-time.Minute)
+	for height, trackedAt := range map[uint64]time.Time{25: now.Add(-2 * time.Minute), 26: now.Add(-2 * time.Minute), 27: now} {
+		thf.TrackClientUpdate(testClientID, &tmclient.Header{SignedHeader: &cmtproto.SignedHeader{Header: &cmtproto.Header{
+			ChainID: testChainID,
+			Height:  int64(height),
+			Time:    now,
+		}}})
+		thf.index.states[sort.Search(len(thf.index.states), func(n int) bool { return thf.index.states[n].height >= height })].trackedAt = trackedAt
+	}
+	state, expiresAt, ok := thf.index.find(testScope, 27, now)
+	require.True(t, ok)
+	require.Equal(t, uint64(26), state.height)
+	thf.cacheTrustedHeight(testScope, 27, state, expiresAt)
+
+	tracked, _ := thf.index.trackedStates(testScope)
+	thf.checkTrackedStates(context.Background(), qc, testScope, tracked, refreshedAt)
+
+	tracked, _ = thf.index.trackedStates(testScope)
+	require.Len(t, tracked, 1)
+	assert.Equal(t, uint64(27), tracked[0].height)
+	latest, _ := thf.index.latestConfirmed(testScope)
+	assert.Equal(t, uint64(25), latest)
+	_, ok = thf.cachedTrustedHeight(testScope, 27)
+	assert.False(t, ok, "trusted height chosen by the dropped consensus state must be forgotten")
+	state, _, ok = thf.index.find(testScope, 27, now)
+	require.True(t, ok)
+	assert.Equal(t, uint64(25), state.height)
+}
@@ -4,23 +4,25 @@ import (
 	"context"
 	"fmt"
 	"github.com/avast/retry-go/v4"
-	ibcexported "github.com/cosmos/ibc-go/v7/modules/core/exported"
 	"github.com/cosmos/relayer/v2/relayer/provider"
+	"strconv"
 	"time"
 
 	"github.com/cosmos/relayer/v2/relayer/chains/cosmos"
 
 	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
 
 	"github.com/cosmos/cosmos-sdk/types/query"
 	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
 	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
 	"github.com/cosmos/relayer/v2/relayer"
+	lru "github.com/hashicorp/golang-lru"
 	"go.uber.org/zap"
 )
 
 // consensusPageSize is how many consensusStates to retrieve for each page in `qc.ConsensusStates(...)` call
-const consensusPageSize = 10
+const consensusPageSize = 100
 
 // submissionMarginPeriod is a lag period, because we need consensusState to be valid until we approve it on the chain
 const submissionMarginPeriod = time.Minute * 5
@@ -38,19 +40,53 @@ var (
 // - included in the block (inclusion proof)
 // - successfully executed (delivery proof)
 type TrustedHeaderFetcher struct {
-	neutronChain   *relayer.Chain
-	targetChain    *relayer.Chain
-	logger         *zap.Logger
-	revisionNumber uint64
+	neutronChain *relayer.Chain
+	targetChain  *relayer.Chain
+	connectionID string
+	logger       *zap.Logger
+	// revision is the revision of the target chain the headers are fetched for
+	revision relay.TargetRevision
+	// connection provides the ID of Neutron's client the trusted heights are chosen in
+	connection relay.ConnectionParamsProvider
+	// headers are the light signed headers with validator sets of the target chain by revision and height
+	headers *lru.Cache
+	// trustedHeights are the heights of the consensus states chosen as trusted by client, target chain revision and height
+	trustedHeights *lru.Cache
+	// index is the local copy of the client's consensus states
+	index              *consensusStatesIndex
+	indexRefreshPeriod time.Duration
 }
 
-// NewTrustedHeaderFetcher constructs a new TrustedHeaderFetcher
-func NewTrustedHeaderFetcher(neutronChain *relayer.Chain, targetChain *relayer.Chain, logger *zap.Logger) *TrustedHeaderFetcher {
+// NewTrustedHeaderFetcher constructs a new TrustedHeaderFetcher which keeps up to cacheSize headers and
+// trusted heights in memory (0 disables the caches) and refreshes its index of the client's consensus states
+// every indexRefreshPeriod once started with Run
+func NewTrustedHeaderFetcher(
+	neutronChain *relayer.Chain,
+	targetChain *relayer.Chain,
+	logger *zap.Logger,
+	cacheSize int,
+	indexRefreshPeriod time.Duration,
+	revision relay.TargetRevision,
+	connection relay.ConnectionParamsProvider,
+) *TrustedHeaderFetcher {
+	var headers, trustedHeights *lru.Cache
+	if cacheSize > 0 {
+		// the only error is returned for a non-positive size
+		headers, _ = lru.New(cacheSize)
+		trustedHeights, _ = lru.New(cacheSize)
+	}
+
 	return &TrustedHeaderFetcher{
-		neutronChain:   neutronChain,
-		targetChain:    targetChain,
-		logger:         logger,
-		revisionNumber: clienttypes.ParseChainID(targetChain.ChainID()),
+		neutronChain:       neutronChain,
+		targetChain:        targetChain,
+		connectionID:       neutronChain.PathEnd.ConnectionID,
+		logger:             logger,
+		revision:           revision,
+		connection:         connection,
+		headers:            headers,
+		trustedHeights:     trustedHeights,
+		index:              &consensusStatesIndex{},
+		indexRefreshPeriod: indexRefreshPeriod,
 	}
 }
 
@@ -59,21 +95,27 @@ func NewTrustedHeaderFetcher(neutronChain *relayer.Chain, targetChain *relayer.C
 // `height` - remote chain block height X = transaction with such block height
 func (thf *TrustedHeaderFetcher) Fetch(ctx context.Context, height uint64) (header *tmclient.Header, err error) {
 	start := time.Now()
+	scope := thf.currentScope()
 
-	// tries to find height of the closest consensus state height that is less or equal than provided height
-	trustedHeight, err := thf.getTrustedHeight(ctx, height)
+	// tries to find the closest consensus state which height is less than provided height
+	trustedCS, err := thf.getTrustedHeight(ctx, scope, height)
 	if err != nil {
 		err = fmt.Errorf("no satisfying consensus state found: %w", err)
 		return
 	}
-	thf.logger.Debug("Found suitable consensus state with trusted height", zap.Uint64("height", trustedHeight.RevisionHeight))
+	thf.logger.Debug("Found suitable consensus state with trusted height", zap.Uint64("height", trustedCS.height))
 
-	header, err = thf.trustedHeaderAtHeight(ctx, trustedHeight, height)
+	trustedHeight := clienttypes.NewHeight(scope.revision, trustedCS.height)
+	header, err = thf.trustedHeaderAtHeight(ctx, &trustedHeight, height)
 	if err != nil {
 		err = fmt.Errorf("failed to get header for src chain: %w", err)
 		return
 	}
 
+	if err = thf.verifyHeader(header, trustedCS); err != nil {
+		return nil, err
+	}
+
 	neutronmetrics.RecordActionDuration("TrustedHeaderFetcher", time.Since(start).Seconds())
 
 	return
@@ -93,7 +135,7 @@ func (thf *TrustedHeaderFetcher) Fetch(ctx context.Context, height uint64) (head
 // `trustedHeight` - height of any consensus state that's height < supplied height
 // `height` - remote chain height for a header
 func (thf *TrustedHeaderFetcher) trustedHeaderAtHeight(ctx context.Context, trustedHeight *clienttypes.Height, height uint64) (*tmclient.Header, error) {
-	header, err := thf.retryGetLightSignedHeaderAtHeight(ctx, height)
+	lightHeader, err := thf.lightSignedHeader(ctx, clienttypes.NewHeight(trustedHeight.RevisionNumber, height))
 	if err != nil {
 		return nil, fmt.Errorf("could not get light header: %w", err)
 	}
@@ -101,82 +143,209 @@ func (thf *TrustedHeaderFetcher) trustedHeaderAtHeight(ctx context.Context, trus
 	// NOTE: We need to get validators from the source chain at height: trustedHeight+1
 	// since the last trusted validators for a header at height h is the NextValidators
 	// at h+1 committed to in header h by NextValidatorsHash
-	nextHeader, err := thf.retryGetLightSignedHeaderAtHeight(ctx, trustedHeight.RevisionHeight+1)
+	nextHeader, err := thf.lightSignedHeader(ctx, clienttypes.NewHeight(trustedHeight.RevisionNumber, trustedHeight.RevisionHeight+1))
 	if err != nil {
 		return nil, fmt.Errorf("could not get next light header: %w", err)
 	}
 
-	// inject TrustedHeight and TrustedValidators into header
+	// inject TrustedHeight and TrustedValidators into a copy of the header, the cached one stays intact
+	header := *lightHeader
 	header.TrustedHeight = *trustedHeight
 	header.TrustedValidators = nextHeader.ValidatorSet
 
-	return header, nil
+	return &header, nil
 }
 
-// getTrustedHeight tries to find height of any consensusState within trusting period with a height < supplied height
-// To do this, it simply iterates over all consensus states and checks each that it's within trusting period AND < supplied_height
-// Note that we cannot optimize this search due to consensus states being stored in a tree with *STRING* key `RevisionNumber-RevisionHeight`
+// getTrustedHeight finds the height of the latest consensusState within trusting period with a height < supplied height
+// in the local index of the client's consensus states. The index is refreshed from Neutron only if it has no suitable
+// consensus state, e.g. before the first refresh by Run or after the client or the target chain revision has changed.
 //
 // Arguments:
-// `height` - found consensus state will be with a height <= than it
-func (thf *TrustedHeaderFetcher) getTrustedHeight(ctx context.Context, height uint64) (*clienttypes.Height, error) {
+// `scope` - client and revision of the target chain the consensus state is searched in
+// `height` - found consensus state will be with a height < than it
+func (thf *TrustedHeaderFetcher) getTrustedHeight(ctx context.Context, scope indexScope, height uint64) (consensusStateInfo, error) {
+	if trustedCS, ok := thf.cachedTrustedHeight(scope, height); ok {
+		return trustedCS, nil
+	}
+
+	trustedCS, expiresAt, ok := thf.index.find(scope, height, time.Now())
+	if !ok {
+		if err := thf.refreshIndex(ctx); err != nil {
+			return consensusStateInfo{}, fmt.Errorf("failed to refresh consensus states index: %w", err)
+		}
+		trustedCS, expiresAt, ok = thf.index.find(scope, height, time.Now())
+	}
+	if !ok {
+		return consensusStateInfo{}, fmt.Errorf("could not find any trusted consensus state for height=%d in revision %d of client %s",
+			height, scope.revision, scope.clientID)
+	}
+
+	thf.cacheTrustedHeight(scope, height, trustedCS, expiresAt)
+	return trustedCS, nil
+}
+
+// Run refreshes the local index of the client's consensus states every indexRefreshPeriod until ctx is done.
+func (thf *TrustedHeaderFetcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(thf.indexRefreshPeriod)
+	defer ticker.Stop()
+
+	for {
+		if err := thf.refreshIndex(ctx); err != nil && ctx.Err() == nil {
+			thf.logger.Warn("failed to refresh consensus states index", zap.Error(err))
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			thf.logger.Info("Context cancelled, shutting down TrustedHeaderFetcher...")
+			return
+		}
+	}
+}
+
+// TrackClientUpdate puts the consensus state created by a submitted MsgUpdateClient with the header into the
+// local index, so it can be used as trusted without waiting for the next refresh. The consensus state is
+// dropped by a later refresh if Neutron's client doesn't have it, e.g. the tx of the update has failed.
+func (thf *TrustedHeaderFetcher) TrackClientUpdate(clientID string, header *tmclient.Header) {
+	height := header.GetHeight()
+	thf.index.add(indexScope{clientID: clientID, revision: height.GetRevisionNumber()}, consensusStateInfo{
+		height:             height.GetRevisionHeight(),
+		timestamp:          header.GetTime(),
+		nextValidatorsHash: header.Header.NextValidatorsHash,
+		trackedAt:          time.Now(),
+	})
+}
+
+// refreshIndex updates the local index with the consensus states of the current client in the current target chain
+// revision. Note that the consensus states can't be fetched in the height order since they are stored in a tree with
+// *STRING* key `RevisionNumber-RevisionHeight`. Though the keys of the heights with the same number of digits are
+// in the height order, so while the latest height of the client has as many digits as the latest indexed one, only
+// the consensus states starting from the latest indexed one are fetched. Otherwise, all of them are fetched.
+func (thf *TrustedHeaderFetcher) refreshIndex(ctx context.Context) error {
 	// Without this hack it doesn't want to work with NewQueryClient
 	neutronProvider, ok := thf.neutronChain.ChainProvider.(*cosmos.CosmosProvider)
 	if !ok {
-		return nil, fmt.Errorf("failed to cast ChainProvider to concrete type (cosmos.CosmosProvider)")
+		return fmt.Errorf("failed to cast ChainProvider to concrete type (cosmos.CosmosProvider)")
 	}
 
 	qc := clienttypes.NewQueryClient(neutronProvider)
+	scope := thf.currentScope()
 
-	trustingPeriod, err := thf.fetchTrustingPeriod(ctx)
+	clientState, err := thf.fetchClientState(ctx, scope.clientID)
 	if err != nil {
-		return nil, fmt.Errorf("failed to fetch trusting period: %w", err)
+		return fmt.Errorf("failed to fetch client state: %w", err)
 	}
-	thf.logger.Debug("fetched trusting period", zap.Float64("trusting_period_hours", trustingPeriod.Hours()))
+	thf.logger.Debug("fetched trusting period", zap.Float64("trusting_period_hours", clientState.TrustingPeriod.Hours()))
 
 	nextKey := make([]byte, 0)
+	latestHeight, incremental := thf.index.latestConfirmed(scope)
+	if incremental && digits(latestHeight) == digits(clientState.LatestHeight.RevisionHeight) {
+		nextKey = []byte(clienttypes.NewHeight(scope.revision, latestHeight).String())
+	} else {
+		incremental = false
+	}
+	tracked, refreshedAt := thf.index.trackedStates(scope)
 
+	states, err := thf.fetchConsensusStates(ctx, qc, scope, nextKey)
+	if err != nil {
+		return err
+	}
+
+	if incremental {
+		thf.index.merge(scope, states, clientState, time.Now())
+	} else {
+		thf.index.reset(scope, states, clientState, time.Now())
+	}
+	thf.checkTrackedStates(ctx, qc, scope, tracked, refreshedAt)
+	thf.logger.Debug("consensus states index refreshed",
+		zap.String("client_id", scope.clientID), zap.Uint64("revision", scope.revision), zap.Bool("incremental", incremental),
+		zap.Int("fetched_consensus_states", len(states)), zap.Int("consensus_states", thf.index.len()))
+	return nil
+}
+
+// fetchConsensusStates fetches the consensus states of the client in the revision of the scope starting from the key
+// (all of them for an empty key).
+func (thf *TrustedHeaderFetcher) fetchConsensusStates(ctx context.Context, qc clienttypes.QueryClient, scope indexScope, nextKey []byte) ([]consensusStateInfo, error) {
+	states := make([]consensusStateInfo, 0)
 	for {
-		page, err := qc.ConsensusStates(ctx, requestPage(thf.neutronChain.ClientID(), nextKey))
+		page, err := qc.ConsensusStates(ctx, requestPage(scope.clientID, nextKey))
 		if err != nil {
-			return nil, fmt.Errorf("failed to get consensus states for client ID %s: %w", thf.neutronChain.ClientID(), err)
+			return nil, fmt.Errorf("failed to get consensus states for client ID %s: %w", scope.clientID, err)
 		}
 
 		for _, cs := range page.ConsensusStates {
-			suitable, err := thf.isSuitableCS(cs, trustingPeriod, height)
-			if err != nil {
-				return nil, err
-			} else if suitable {
-				return &cs.Height, nil
+			if cs.Height.RevisionNumber != scope.revision {
+				continue
 			}
+
+			tmCS, ok := cs.ConsensusState.GetCachedValue().(*tmclient.ConsensusState)
+			if !ok {
+				return nil, fmt.Errorf("couldn't cast consensus state value of type %T to *tmclient.ConsensusState", cs.ConsensusState.GetCachedValue())
+			}
+			states = append(states, consensusStateInfo{
+				height:             cs.Height.RevisionHeight,
+				timestamp:          tmCS.Timestamp,
+				nextValidatorsHash: tmCS.NextValidatorsHash,
+			})
 		}
 
 		nextKey = page.GetPagination().NextKey
 
 		if len(nextKey) == 0 {
-			break
+			return states, nil
 		}
 	}
+}
 
-	return nil, fmt.Errorf("could not find any trusted consensus state for height=%d", height)
+// checkTrackedStates checks whether Neutron's client has the consensus states tracked from the client updates by
+// the time of the refresh. The ones found are confirmed, the ones still missing after a whole refresh period (i.e.
+// tracked before refreshedAt) are dropped along with the trusted heights chosen by them.
+func (thf *TrustedHeaderFetcher) checkTrackedStates(ctx context.Context, qc clienttypes.QueryClient, scope indexScope,
+	tracked []consensusStateInfo, refreshedAt time.Time) {
+	for _, state := range tracked {
+		_, err := qc.ConsensusState(ctx, &clienttypes.QueryConsensusStateRequest{
+			ClientId:       scope.clientID,
+			RevisionNumber: scope.revision,
+			RevisionHeight: state.height,
+		})
+		switch {
+		case err == nil:
+			thf.index.confirm(scope, state.height)
+		case ctx.Err() != nil:
+			return
+		case state.trackedAt.Before(refreshedAt):
+			thf.logger.Warn("consensus state of a submitted client update is not found in Neutron's client, dropping it",
+				zap.String("client_id", scope.clientID), zap.Uint64("revision", scope.revision), zap.Uint64("height", state.height), zap.Error(err))
+			thf.index.remove(scope, state.height)
+			thf.forgetTrustedHeights(scope, state.height)
+		}
+	}
 }
 
-// fetchTrustingPeriod fetches trusting period of the client
-func (thf *TrustedHeaderFetcher) fetchTrustingPeriod(ctx context.Context) (time.Duration, error) {
-	clientState, err := thf.neutronChain.ChainProvider.QueryClientState(ctx, 0, thf.neutronChain.PathEnd.ClientID)
+// fetchClientState fetches the client state with the trusting period and the verification parameters of the client
+func (thf *TrustedHeaderFetcher) fetchClientState(ctx context.Context, clientID string) (*tmclient.ClientState, error) {
+	clientState, err := thf.neutronChain.ChainProvider.QueryClientState(ctx, 0, clientID)
 	if err != nil {
-		return 0, fmt.Errorf("could not fetch client state for ClientId=%s: %w", thf.neutronChain.PathEnd.ClientID, err)
+		return nil, fmt.Errorf("could not fetch client state for ClientId=%s: %w", clientID, err)
 	}
 
 	tmClientState, ok := clientState.(*tmclient.ClientState)
 	if !ok {
-		return 0, fmt.Errorf("expected client state of type *tmclient.ClientState, got %T", clientState)
+		return nil, fmt.Errorf("expected client state of type *tmclient.ClientState, got %T", clientState)
 	}
 	if tmClientState.TrustingPeriod == 0 {
-		return 0, fmt.Errorf("got empty TrustingPeriod")
+		return nil, fmt.Errorf("got empty TrustingPeriod")
 	}
 
-	return tmClientState.TrustingPeriod, nil
+	return tmClientState, nil
+}
+
+// currentScope returns the current Neutron's client and revision of the target chain.
+func (thf *TrustedHeaderFetcher) currentScope() indexScope {
+	return indexScope{
+		clientID: thf.connection.ConnectionParams().NeutronClientID,
+		revision: thf.revision.Revision(),
+	}
 }
 
 func (thf *TrustedHeaderFetcher) retryGetLightSignedHeaderAtHeight(ctx context.Context, height uint64) (*tmclient.Header, error) {
@@ -208,20 +377,9 @@ func (thf *TrustedHeaderFetcher) retryGetLightSignedHeaderAtHeight(ctx context.C
 	return tmHeader, nil
 }
 
-// isSuitableCS parses the given consensus state and checks if its height can be used as trusted.
-// The condition for this check is the following:
-//
-// 1. The consensus state height is in the same revision and is less than the given height;
-// 2. The consensus state timestamp is within the trusting period.
-func (thf *TrustedHeaderFetcher) isSuitableCS(cs clienttypes.ConsensusStateWithHeight, trustingPeriod time.Duration, height uint64) (bool, error) {
-	ibcCS, ok := cs.ConsensusState.GetCachedValue().(ibcexported.ConsensusState)
-	if !ok {
-		return false, fmt.Errorf("couldn't cast consensus state value of type %T to ibcexported.ConsensusState", cs.ConsensusState.GetCachedValue())
-	}
-	olderThanHeightInSameRevision := cs.Height.RevisionNumber == thf.revisionNumber && cs.Height.RevisionHeight < height
-	consensusTimestamp := time.Unix(0, int64(ibcCS.GetTimestamp()))
-	inTrustingPeriod := consensusTimestamp.Add(trustingPeriod).Add(-submissionMarginPeriod).After(time.Now())
-	return olderThanHeightInSameRevision && inTrustingPeriod, nil
+// digits returns the number of decimal digits of the height.
+func digits(height uint64) int {
+	return len(strconv.FormatUint(height, 10))
 }
 
 func requestPage(clientID string, nextKey []byte) *clienttypes.QueryConsensusStatesRequest {
@@ -230,7 +388,6 @@ func requestPage(clientID string, nextKey []byte) *clienttypes.QueryConsensusSta
 		Pagination: &query.PageRequest{
 			Key:        nextKey,
 			Limit:      consensusPageSize,
-			Reverse:    true,
 			CountTotal: false,
 		},
 	}
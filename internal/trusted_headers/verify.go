@@ -0,0 +1,78 @@
+package trusted_headers
+
+import (
+	"bytes"
+	"fmt"
+	"time"
+
+	cmtmath "github.com/cometbft/cometbft/libs/math"
+	"github.com/cometbft/cometbft/light"
+	tmtypes "github.com/cometbft/cometbft/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	"go.uber.org/zap"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+// verifyHeader verifies the header with the injected trusted fields against the trusted consensus state
+// the same way Neutron's light client does on MsgUpdateClient: the trusted validators must be the ones
+// committed to in the consensus state, and the header must pass the CometBFT light client verification
+// (adjacent or skipping, with the trust level of the client). It returns relay.ErrHeaderVerification if
+// the header is rejected.
+func (thf *TrustedHeaderFetcher) verifyHeader(header *tmclient.Header, trustedCS consensusStateInfo) error {
+	clientState := thf.index.client()
+	if clientState == nil {
+		return fmt.Errorf("can't verify header: client state is unknown")
+	}
+
+	height := header.GetHeight().GetRevisionHeight()
+	if err := verifyHeader(header, trustedCS, clientState, time.Now()); err != nil {
+		neutronmetrics.IncHeaderVerificationFailures(thf.connectionID)
+		thf.logger.Error("target chain header failed verification, the target RPC node may be faulty or on a fork",
+			zap.Uint64("height", height),
+			zap.Uint64("trusted_height", trustedCS.height),
+			zap.String("chain_id", thf.targetChain.ChainID()),
+			zap.Error(err))
+		// the headers might be fetched from a node which is fixed by the next attempt
+		thf.forgetHeaders(header.TrustedHeight.RevisionNumber, height, trustedCS.height+1)
+		return relay.NewErrHeaderVerification(height, trustedCS.height, err)
+	}
+
+	return nil
+}
+
+func verifyHeader(header *tmclient.Header, trustedCS consensusStateInfo, clientState *tmclient.ClientState, now time.Time) error {
+	trustedVals, err := tmtypes.ValidatorSetFromProto(header.TrustedValidators)
+	if err != nil {
+		return fmt.Errorf("invalid trusted validator set: %w", err)
+	}
+	if !bytes.Equal(trustedVals.Hash(), trustedCS.nextValidatorsHash) {
+		return fmt.Errorf("trusted validators hash %X doesn't match the next validators hash %X of the consensus state",
+			trustedVals.Hash(), trustedCS.nextValidatorsHash)
+	}
+
+	signedHeader, err := tmtypes.SignedHeaderFromProto(header.SignedHeader)
+	if err != nil {
+		return fmt.Errorf("invalid signed header: %w", err)
+	}
+	vals, err := tmtypes.ValidatorSetFromProto(header.ValidatorSet)
+	if err != nil {
+		return fmt.Errorf("invalid validator set: %w", err)
+	}
+
+	// the consensus state holds just the part of the trusted header needed for the verification
+	trustedHeader := tmtypes.SignedHeader{Header: &tmtypes.Header{
+		ChainID:            clientState.ChainId,
+		Height:             int64(trustedCS.height),
+		Time:               trustedCS.timestamp,
+		NextValidatorsHash: trustedCS.nextValidatorsHash,
+	}}
+	trustLevel := cmtmath.Fraction{
+		Numerator:   clientState.TrustLevel.Numerator,
+		Denominator: clientState.TrustLevel.Denominator,
+	}
+
+	return light.Verify(&trustedHeader, trustedVals, signedHeader, vals,
+		clientState.TrustingPeriod, now, clientState.MaxClockDrift, trustLevel)
+}
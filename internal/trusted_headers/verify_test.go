@@ -0,0 +1,130 @@
+package trusted_headers
+
+import (
+	"testing"
+	"time"
+
+	"github.com/cometbft/cometbft/crypto/tmhash"
+	cmtproto "github.com/cometbft/cometbft/proto/tendermint/types"
+	cmtversion "github.com/cometbft/cometbft/proto/tendermint/version"
+	tmtypes "github.com/cometbft/cometbft/types"
+	"github.com/cometbft/cometbft/version"
+	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newSignedHeader returns a header at the given height committed by signers and declaring vals as its validators.
+func newSignedHeader(t *testing.T, height int64, headerTime time.Time, vals *tmtypes.ValidatorSet,
+	signers *tmtypes.ValidatorSet, privVals []tmtypes.PrivValidator) *cmtproto.SignedHeader {
+	header := &tmtypes.Header{
+		Version:            cmtversion.Consensus{Block: version.BlockProtocol},
+		ChainID:            testChainID,
+		Height:             height,
+		Time:               headerTime,
+		ValidatorsHash:     vals.Hash(),
+		NextValidatorsHash: vals.Hash(),
+		ProposerAddress:    vals.Proposer.Address,
+	}
+	blockID := tmtypes.BlockID{
+		Hash:          header.Hash(),
+		PartSetHeader: tmtypes.PartSetHeader{Total: 1, Hash: tmhash.Sum([]byte("parts"))},
+	}
+	voteSet := tmtypes.NewVoteSet(testChainID, height, 0, cmtproto.PrecommitType, signers)
+	commit, err := tmtypes.MakeCommit(blockID, height, 0, voteSet, privVals, headerTime)
+	require.NoError(t, err)
+
+	signedHeader := tmtypes.SignedHeader{Header: header, Commit: commit}
+	return signedHeader.ToProto()
+}
+
+func TestVerifyHeader(t *testing.T) {
+	now := time.Now()
+	trustedTime := now.Add(-time.Hour)
+	headerTime := now.Add(-time.Minute)
+
+	vals, privVals := tmtypes.RandValidatorSet(4, 10)
+	otherVals, otherPrivVals := tmtypes.RandValidatorSet(4, 10)
+	valsProto, err := vals.ToProto()
+	require.NoError(t, err)
+	otherValsProto, err := otherVals.ToProto()
+	require.NoError(t, err)
+
+	trustedCS := consensusStateInfo{height: 10, timestamp: trustedTime, nextValidatorsHash: vals.Hash()}
+	clientState := &tmclient.ClientState{
+		ChainId:        testChainID,
+		TrustLevel:     tmclient.DefaultTrustLevel,
+		TrustingPeriod: 24 * time.Hour,
+		MaxClockDrift:  10 * time.Second,
+	}
+
+	tests := []struct {
+		name   string
+		header *tmclient.Header
+		valid  bool
+	}{
+		{
+			name: "adjacent header",
+			header: &tmclient.Header{
+				SignedHeader:      newSignedHeader(t, 11, headerTime, vals, vals, privVals),
+				ValidatorSet:      valsProto,
+				TrustedValidators: valsProto,
+			},
+			valid: true,
+		},
+		{
+			name: "non-adjacent header",
+			header: &tmclient.Header{
+				SignedHeader:      newSignedHeader(t, 20, headerTime, vals, vals, privVals),
+				ValidatorSet:      valsProto,
+				TrustedValidators: valsProto,
+			},
+			valid: true,
+		},
+		{
+			name: "validator set mismatching the header",
+			header: &tmclient.Header{
+				SignedHeader:      newSignedHeader(t, 11, headerTime, vals, vals, privVals),
+				ValidatorSet:      otherValsProto,
+				TrustedValidators: valsProto,
+			},
+		},
+		{
+			name: "non-adjacent validator set mismatching the header",
+			header: &tmclient.Header{
+				SignedHeader:      newSignedHeader(t, 20, headerTime, vals, vals, privVals),
+				ValidatorSet:      otherValsProto,
+				TrustedValidators: valsProto,
+			},
+		},
+		{
+			name: "header committed by untrusted validators",
+			header: &tmclient.Header{
+				SignedHeader:      newSignedHeader(t, 20, headerTime, otherVals, otherVals, otherPrivVals),
+				ValidatorSet:      otherValsProto,
+				TrustedValidators: valsProto,
+			},
+		},
+		{
+			name: "trusted validators mismatching the consensus state",
+			header: &tmclient.Header{
+				SignedHeader:      newSignedHeader(t, 11, headerTime, vals, vals, privVals),
+				ValidatorSet:      valsProto,
+				TrustedValidators: otherValsProto,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.header.TrustedHeight = clienttypes.NewHeight(1, trustedCS.height)
+			err := verifyHeader(tt.header, trustedCS, clientState, now)
+			if tt.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.Error(t, err)
+			}
+		})
+	}
+}
@@ -14,7 +14,9 @@ import (
 	"go.uber.org/zap"
 
 	clienttypes "github.com/cosmos/ibc-go/v7/modules/core/02-client/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
 
+	"github.com/neutron-org/neutron-query-relayer/internal/config"
 	"github.com/neutron-org/neutron-query-relayer/internal/relay"
 	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
 )
@@ -23,26 +25,39 @@ type TXProcessor struct {
 	trustedHeaderFetcher        relay.TrustedHeaderFetcher
 	storage                     relay.Storage
 	submitter                   relay.Submitter
+	connectionID                string
 	logger                      *zap.Logger
 	checkSubmittedTxStatusDelay time.Duration
 	ignoreErrorsRegexp          *regexp.Regexp
+	dryRun                      bool
+	criticalErrorPolicy         string
+	// connection provides the ID of Neutron's client the proofs are verified with
+	connection relay.ConnectionParamsProvider
 }
 
 func NewTxProcessor(
 	trustedHeaderFetcher relay.TrustedHeaderFetcher,
 	storage relay.Storage,
 	submitter relay.Submitter,
+	connectionID string,
 	logger *zap.Logger,
 	checkSubmittedTxStatusDelay time.Duration,
 	ignoreErrorsRegexp string,
+	dryRun bool,
+	criticalErrorPolicy string,
+	connection relay.ConnectionParamsProvider,
 ) TXProcessor {
 	txProcessor := TXProcessor{
 		trustedHeaderFetcher:        trustedHeaderFetcher,
 		storage:                     storage,
 		submitter:                   submitter,
+		connectionID:                connectionID,
 		logger:                      logger,
 		checkSubmittedTxStatusDelay: checkSubmittedTxStatusDelay,
 		ignoreErrorsRegexp:          regexp.MustCompile(ignoreErrorsRegexp),
+		dryRun:                      dryRun,
+		criticalErrorPolicy:         criticalErrorPolicy,
+		connection:                  connection,
 	}
 
 	return txProcessor
@@ -82,9 +97,23 @@ func (r TXProcessor) submitTxWithProofs(
 	if err != nil {
 		return r.processFailedTxSubmission(err, queryID, hash, neutronTxHash, processedTx, proofStart)
 	}
+	if !r.dryRun {
+		r.trackClientUpdates(block)
+	}
 	return r.processSuccessfulTxSubmission(ctx, queryID, hash, neutronTxHash, processedTx, proofStart, submittedTxsTasksQueue)
 }
 
+// trackClientUpdates lets the trusted header fetcher know about the consensus states created by a submitted tx
+// proof, since Neutron verifies the headers of the proof by updating its client with them.
+func (r TXProcessor) trackClientUpdates(block *neutrontypes.Block) {
+	clientID := r.connection.ConnectionParams().NeutronClientID
+	for _, packedHeader := range []*codectypes.Any{block.Header, block.NextBlockHeader} {
+		if header, ok := packedHeader.GetCachedValue().(*tmclient.Header); ok {
+			r.trustedHeaderFetcher.TrackClientUpdate(clientID, header)
+		}
+	}
+}
+
 // processSuccessfulTxSubmission stores the tx status in the storage and submits the PendingSubmittedTxInfo to
 // submittedTxsTasksQueue.
 func (r *TXProcessor) processSuccessfulTxSubmission(
@@ -97,6 +126,20 @@ func (r *TXProcessor) processSuccessfulTxSubmission(
 	submittedTxsTasksQueue chan relay.PendingSubmittedTxInfo,
 ) error {
 	neutronmetrics.AddSuccessProof(string(neutrontypes.InterchainQueryTypeTX), time.Since(proofStart).Seconds())
+	if r.dryRun {
+		// the tx never gets to Neutron, so there is nothing to check and no reason to keep it for resubmission.
+		// The status only lives in the in-memory dry run storage and skips the tx for the rest of the run.
+		err := r.storage.SetTxStatus(queryID, hash, neutronTxHash, relay.SubmittedTxInfo{
+			Status: relay.DryRun,
+		}, nil)
+		if err != nil {
+			return fmt.Errorf("failed to store tx dry run status: %w", err)
+		}
+
+		r.logger.Info("proof for query_id built in dry run mode", zap.Uint64("query_id", queryID))
+		return nil
+	}
+
 	err := r.storage.SetTxStatus(queryID, hash, neutronTxHash, relay.SubmittedTxInfo{
 		Status: relay.Submitted,
 	}, &tx)
@@ -116,7 +159,8 @@ func (r *TXProcessor) processSuccessfulTxSubmission(
 }
 
 // processFailedTxSubmission checks whether the error is ignored. If it's ignored, it stores the tx status in the
-// storage; otherwise it escalates the error.
+// storage; otherwise it records the critical error and handles it according to the critical error policy: the
+// "mark" policy stores the tx status the same way as for ignored errors, other policies escalate the error.
 func (r *TXProcessor) processFailedTxSubmission(
 	err error,
 	queryID uint64,
@@ -130,7 +174,21 @@ func (r *TXProcessor) processFailedTxSubmission(
 
 	// check error with regexp
 	if !r.ignoreErrorsRegexp.MatchString(err.Error()) {
-		return relay.NewErrSubmitTxProofCritical(err)
+		neutronmetrics.IncCriticalTxErrors(r.connectionID, r.criticalErrorPolicy)
+		errSetCritical := r.storage.SetCriticalTxError(relay.CriticalTxErrorInfo{
+			QueryID:         queryID,
+			SubmittedTxHash: hash,
+			ErrorTime:       time.Now(),
+			Policy:          r.criticalErrorPolicy,
+			Message:         err.Error(),
+		})
+		if errSetCritical != nil {
+			return fmt.Errorf("failed to store critical tx error: %w", errSetCritical)
+		}
+
+		if r.criticalErrorPolicy != config.CriticalTxErrorPolicyMark {
+			return relay.NewErrSubmitTxProofCritical(err)
+		}
 	}
 
 	errSetStatus := r.storage.SetTxStatus(
@@ -155,6 +213,7 @@ func (r TXProcessor) delayedTxStatusCheck(ctx context.Context, tx relay.PendingS
 			NeutronHash:     tx.NeutronHash,
 		}
 	case <-ctx.Done():
+		// the tx is saved as pending in the storage, so the TxSubmitChecker checks it on the next start
 		r.logger.Info("Cancelled PendingSubmittedTxInfo delayed checking",
 			zap.Uint64("query_id", tx.QueryID),
 			zap.String("submitted_tx_hash", tx.SubmittedTxHash))
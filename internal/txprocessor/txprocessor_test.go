@@ -0,0 +1,199 @@
+package txprocessor_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/libs/bytes"
+	rpcclient "github.com/cometbft/cometbft/rpc/client"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	tmclient "github.com/cosmos/ibc-go/v7/modules/light-clients/07-tendermint"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/config"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	"github.com/neutron-org/neutron-query-relayer/internal/storage"
+	"github.com/neutron-org/neutron-query-relayer/internal/txprocessor"
+	"github.com/neutron-org/neutron-query-relayer/internal/txsubmitchecker"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+type headerFetcher struct{}
+
+func (headerFetcher) Fetch(context.Context, uint64) (*tmclient.Header, error) {
+	return &tmclient.Header{}, nil
+}
+
+func (headerFetcher) TrackClientUpdate(string, *tmclient.Header) {}
+
+func (headerFetcher) Run(context.Context) {}
+
+// txProofSubmitter submits every tx proof in a Neutron tx with the given hash unless err is set.
+type txProofSubmitter struct {
+	relay.Submitter
+	neutronHash string
+	err         error
+}
+
+func (s txProofSubmitter) SubmitTxProof(context.Context, uint64, *neutrontypes.Block) (string, error) {
+	if s.err != nil {
+		return "", s.err
+	}
+	return s.neutronHash, nil
+}
+
+// statusStorage records the tx statuses and the critical errors.
+type statusStorage struct {
+	relay.Storage
+	statuses       []relay.SubmittedTxInfo
+	criticalErrors []relay.CriticalTxErrorInfo
+}
+
+func (s *statusStorage) SetTxStatus(_ uint64, _ string, _ string, status relay.SubmittedTxInfo, _ *relay.Transaction) error {
+	s.statuses = append(s.statuses, status)
+	return nil
+}
+
+func (s *statusStorage) SetCriticalTxError(info relay.CriticalTxErrorInfo) error {
+	s.criticalErrors = append(s.criticalErrors, info)
+	return nil
+}
+
+type staticConnection relay.ConnectionParams
+
+func (c staticConnection) ConnectionParams() relay.ConnectionParams {
+	return relay.ConnectionParams(c)
+}
+
+func TestCriticalErrorPolicies(t *testing.T) {
+	const ignoreErrorsRegex = "execute wasm contract failed"
+
+	tests := []struct {
+		name      string
+		policy    string
+		submitErr error
+		// critical is true if the error is expected to be escalated to the relayer as a critical one
+		critical bool
+		// recorded is true if the error is expected to be recorded as a critical one
+		recorded bool
+	}{
+		{
+			name:      "exit",
+			policy:    config.CriticalTxErrorPolicyExit,
+			submitErr: errors.New("insufficient fees"),
+			critical:  true,
+			recorded:  true,
+		},
+		{
+			name:      "pause",
+			policy:    config.CriticalTxErrorPolicyPause,
+			submitErr: errors.New("insufficient fees"),
+			critical:  true,
+			recorded:  true,
+		},
+		{
+			name:      "mark falls through to the ErrorOnSubmit status",
+			policy:    config.CriticalTxErrorPolicyMark,
+			submitErr: errors.New("insufficient fees"),
+			critical:  false,
+			recorded:  true,
+		},
+		{
+			name:      "ignored error",
+			policy:    config.CriticalTxErrorPolicyExit,
+			submitErr: errors.New("execute wasm contract failed"),
+			critical:  false,
+			recorded:  false,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := &statusStorage{}
+			processor := txprocessor.NewTxProcessor(headerFetcher{}, store, txProofSubmitter{err: tt.submitErr}, "connection-0",
+				zap.NewNop(), time.Second, ignoreErrorsRegex, false, tt.policy, staticConnection{NeutronClientID: "07-tendermint-0"})
+
+			tx := relay.Transaction{Tx: &neutrontypes.TxValue{Data: []byte("tx")}, Height: 100}
+			err := processor.ProcessAndSubmit(context.Background(), 1, tx, make(chan relay.PendingSubmittedTxInfo, 1))
+
+			var critErr relay.ErrSubmitTxProofCritical
+			if tt.critical {
+				require.ErrorAs(t, err, &critErr)
+				assert.Empty(t, store.statuses, "the tx must be submitted again once the relayer goes on")
+			} else {
+				require.NoError(t, err)
+				require.Len(t, store.statuses, 1)
+				assert.Equal(t, relay.ErrorOnSubmit, store.statuses[0].Status)
+				assert.Equal(t, tt.submitErr.Error(), store.statuses[0].Message)
+			}
+
+			if tt.recorded {
+				require.Len(t, store.criticalErrors, 1)
+				assert.Equal(t, uint64(1), store.criticalErrors[0].QueryID)
+				assert.Equal(t, tt.policy, store.criticalErrors[0].Policy)
+				assert.Equal(t, tt.submitErr.Error(), store.criticalErrors[0].Message)
+			} else {
+				assert.Empty(t, store.criticalErrors)
+			}
+		})
+	}
+}
+
+// neutronRPC reports every Neutron tx as committed successfully.
+type neutronRPC struct {
+	rpcclient.Client
+}
+
+func (neutronRPC) Tx(_ context.Context, hash []byte, _ bool) (*ctypes.ResultTx, error) {
+	return &ctypes.ResultTx{Hash: bytes.HexBytes(hash), TxResult: abci.ResponseDeliverTx{Code: abci.CodeTypeOK}}, nil
+}
+
+func TestShutdownKeepsSubmittedTxPending(t *testing.T) {
+	store, err := storage.NewLevelDBMemStorage()
+	require.NoError(t, err)
+	defer store.Close()
+
+	// the relayer shuts down before the status of the submitted tx is checked
+	processor := txprocessor.NewTxProcessor(headerFetcher{}, store, txProofSubmitter{neutronHash: "0a0b0c"}, "connection-0",
+		zap.NewNop(), time.Hour, "execute wasm contract failed", false, config.CriticalTxErrorPolicyExit,
+		staticConnection{NeutronClientID: "07-tendermint-0"})
+	submittedTxsTasksQueue := make(chan relay.PendingSubmittedTxInfo, 1)
+	ctx, cancel := context.WithCancel(context.Background())
+	tx := relay.Transaction{Tx: &neutrontypes.TxValue{Data: []byte("tx")}, Height: 100}
+	require.NoError(t, processor.ProcessAndSubmit(ctx, 1, tx, submittedTxsTasksQueue))
+	cancel()
+
+	// the tx is neither submitted again nor lost: it's pending until it's checked on the next start
+	pending, err := store.GetAllPendingTxs()
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, uint64(1), pending[0].QueryID)
+	assert.Equal(t, "0a0b0c", pending[0].NeutronHash)
+	exists, err := store.TxExists(1, pending[0].SubmittedTxHash)
+	require.NoError(t, err)
+	assert.True(t, exists)
+	time.Sleep(10 * time.Millisecond)
+	assert.Empty(t, submittedTxsTasksQueue, "the status check must not be queued once the relayer is shutting down")
+
+	checker := txsubmitchecker.NewTxSubmitChecker("connection-0", store, neutronRPC{}, zap.NewNop())
+	ctx, cancel = context.WithCancel(context.Background())
+	done := make(chan error)
+	go func() {
+		done <- checker.Run(ctx, make(chan relay.PendingSubmittedTxInfo))
+	}()
+	assert.Eventually(t, func() bool {
+		pending, err := store.GetAllPendingTxs()
+		return err == nil && len(pending) == 0
+	}, 5*time.Second, 10*time.Millisecond)
+	cancel()
+	require.NoError(t, <-done)
+
+	unsuccessful, err := store.GetAllUnsuccessfulTxs()
+	require.NoError(t, err)
+	assert.Empty(t, unsuccessful)
+}
@@ -0,0 +1,59 @@
+package txquerier
+
+import (
+	"context"
+	"fmt"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	"github.com/cometbft/cometbft/types"
+	lru "github.com/hashicorp/golang-lru"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+)
+
+// heightResults are the results of the transactions of a block along with their merkle tree.
+type heightResults struct {
+	txsResults  []*abci.ResponseDeliverTx
+	abciResults types.ABCIResults
+}
+
+// blockResultsCache fetches the results of the transactions of the blocks and keeps the ones of the
+// latest heights, since the delivery proofs of the transactions of different queries are usually
+// built from the same blocks.
+type blockResultsCache struct {
+	chainClient relay.ChainClient
+	// cache is nil if the cache is disabled
+	cache *lru.Cache
+}
+
+func newBlockResultsCache(chainClient relay.ChainClient, size int) *blockResultsCache {
+	var cache *lru.Cache
+	if size > 0 {
+		// the only error is returned for a non-positive size
+		cache, _ = lru.New(size)
+	}
+	return &blockResultsCache{chainClient: chainClient, cache: cache}
+}
+
+// get returns the results of the transactions of the block at the given height.
+func (c *blockResultsCache) get(ctx context.Context, height int64) (*heightResults, error) {
+	if c.cache != nil {
+		if cached, ok := c.cache.Get(height); ok {
+			return cached.(*heightResults), nil
+		}
+	}
+
+	results, err := c.chainClient.BlockResults(ctx, &height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block results for height = %d: %w", height, err)
+	}
+
+	res := &heightResults{
+		txsResults:  results.TxsResults,
+		abciResults: types.NewResults(results.TxsResults),
+	}
+	if c.cache != nil {
+		c.cache.Add(height, res)
+	}
+	return res, nil
+}
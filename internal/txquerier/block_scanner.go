@@ -0,0 +1,186 @@
+package txquerier
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmquery "github.com/cometbft/cometbft/libs/pubsub/query"
+	"github.com/cometbft/cometbft/types"
+	"go.uber.org/zap"
+
+	neutronmetrics "github.com/neutron-org/neutron-query-relayer/internal/metrics"
+	"github.com/neutron-org/neutron-query-relayer/internal/relay"
+	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
+)
+
+var (
+	// maxScannedBlocks is the number of blocks scanned by a single search, the rest of the blocks
+	// are left for the next search
+	maxScannedBlocks int64 = 10000
+	// checkpointInterval is the number of blocks after which a checkpoint is sent
+	checkpointInterval int64 = 100
+)
+
+// NewBlockScannerSrv returns a BlockScannerSrv which keeps the block results of blockResultsCacheSize
+// latest heights (0 disables the cache).
+func NewBlockScannerSrv(chainClient relay.ChainClient, blockResultsCacheSize int, connectionID string, logger *zap.Logger) *BlockScannerSrv {
+	return &BlockScannerSrv{
+		chainClient:  chainClient,
+		blockResults: newBlockResultsCache(chainClient, blockResultsCacheSize),
+		connectionID: connectionID,
+		logger:       logger,
+	}
+}
+
+// BlockScannerSrv implementation of relay.TXQuerier interface for the target chain nodes without a tx
+// indexer. It walks the blocks one by one and matches the query against the events of their
+// transactions locally.
+type BlockScannerSrv struct {
+	chainClient  relay.ChainClient
+	blockResults *blockResultsCache
+	connectionID string
+	logger       *zap.Logger
+}
+
+// SearchTransactions gets txs with proofs for query type = 'tx' by scanning the committed blocks
+// matching the tx.height conditions of the query. The blocks pruned by the node are skipped with a
+// warning, their transactions are never going to be submitted. A
+// checkpoint is sent every checkpointInterval blocks.
+func (s *BlockScannerSrv) SearchTransactions(ctx context.Context, query string) (<-chan relay.Transaction, <-chan error) {
+	errs := make(chan error, 1)
+	txs := make(chan relay.Transaction, TxsChanSize)
+
+	go func() {
+		defer close(txs)
+		defer close(errs)
+		if err := s.scanBlocks(ctx, query, txs); err != nil {
+			errs <- err
+		}
+	}()
+
+	return txs, errs
+}
+
+func (s *BlockScannerSrv) scanBlocks(ctx context.Context, query string, txs chan<- relay.Transaction) error {
+	q, err := tmquery.New(query)
+	if err != nil {
+		return fmt.Errorf("invalid tx query %s: %w", query, err)
+	}
+	after, until, err := heightRange(query)
+	if err != nil {
+		return fmt.Errorf("invalid tx query %s: %w", query, err)
+	}
+
+	status, err := s.chainClient.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get latest height: %w", err)
+	}
+	if latestHeight := status.SyncInfo.LatestBlockHeight; latestHeight < until {
+		until = latestHeight
+	}
+	// the blocks below the earliest one have been pruned, the node can't serve them
+	if earliestHeight := status.SyncInfo.EarliestBlockHeight; after < earliestHeight-1 {
+		s.logger.Warn("skipping the blocks pruned by the target chain node, their transactions are not going to be submitted",
+			zap.String("query", query),
+			zap.Int64("skipped_from_height", after+1),
+			zap.Int64("skipped_to_height", earliestHeight-1))
+		neutronmetrics.AddSkippedPrunedBlocks(s.connectionID, earliestHeight-1-after)
+		after = earliestHeight - 1
+	}
+	if until-after > maxScannedBlocks {
+		until = after + maxScannedBlocks
+	}
+
+	for height := after + 1; height <= until; height++ {
+		found, err := s.scanBlock(ctx, q, height)
+		if err != nil {
+			return fmt.Errorf("could not scan block %d: %w", height, err)
+		}
+
+		for _, tx := range found {
+			select {
+			case txs <- tx:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+
+		if height%checkpointInterval == 0 || height == until {
+			select {
+			case txs <- relay.Transaction{Height: uint64(height)}:
+			case <-ctx.Done():
+				return nil
+			}
+		}
+	}
+
+	return nil
+}
+
+// scanBlock returns the transactions of the block at the given height matching the query along with
+// their inclusion and delivery proofs.
+func (s *BlockScannerSrv) scanBlock(ctx context.Context, q *tmquery.Query, height int64) ([]relay.Transaction, error) {
+	block, err := s.chainClient.Block(ctx, &height)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch block: %w", err)
+	}
+	blockTxs := block.Block.Txs
+	if len(blockTxs) == 0 {
+		return nil, nil
+	}
+
+	results, err := s.blockResults.get(ctx, height)
+	if err != nil {
+		return nil, err
+	}
+	if len(results.txsResults) != len(blockTxs) {
+		return nil, fmt.Errorf("block has %d txs but %d tx results", len(blockTxs), len(results.txsResults))
+	}
+
+	found := make([]relay.Transaction, 0)
+	for i, tx := range blockTxs {
+		matches, err := q.Matches(txEvents(height, tx, results.txsResults[i]))
+		if err != nil {
+			return nil, fmt.Errorf("failed to match tx #%d: %w", i, err)
+		}
+		if !matches {
+			continue
+		}
+
+		found = append(found, relay.Transaction{
+			Tx: &neutrontypes.TxValue{
+				InclusionProof: cryptoProofFromMerkleProof(blockTxs.Proof(i).Proof),
+				DeliveryProof:  cryptoProofFromMerkleProof(results.abciResults.ProveResult(i)),
+				Response:       results.txsResults[i],
+				Data:           tx,
+			},
+			Height: uint64(height),
+		})
+	}
+
+	return found, nil
+}
+
+// txEvents composes the events of a transaction the same way the CometBFT tx indexer does, so the
+// queries match the same transactions as tx_search would: only the indexed attributes are matched.
+func txEvents(height int64, tx types.Tx, result *abci.ResponseDeliverTx) map[string][]string {
+	events := map[string][]string{
+		types.TxHeightKey: {strconv.FormatInt(height, 10)},
+		types.TxHashKey:   {fmt.Sprintf("%X", tx.Hash())},
+	}
+	for _, event := range result.Events {
+		if event.Type == "" {
+			continue
+		}
+		for _, attr := range event.Attributes {
+			if attr.Key == "" || !attr.Index {
+				continue
+			}
+			key := event.Type + "." + attr.Key
+			events[key] = append(events[key], attr.Value)
+		}
+	}
+	return events
+}
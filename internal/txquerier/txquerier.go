@@ -3,11 +3,16 @@ package txquerier
 import (
 	"context"
 	"fmt"
+	"math"
+	"math/big"
+	"sync"
 
 	abci "github.com/cometbft/cometbft/abci/types"
 	"github.com/cometbft/cometbft/crypto/merkle"
+	tmquery "github.com/cometbft/cometbft/libs/pubsub/query"
 	"github.com/cometbft/cometbft/proto/tendermint/crypto"
-	"github.com/cometbft/cometbft/types"
+	coretypes "github.com/cometbft/cometbft/rpc/core/types"
+	"golang.org/x/sync/errgroup"
 
 	"github.com/neutron-org/neutron-query-relayer/internal/relay"
 	neutrontypes "github.com/neutron-org/neutron/x/interchainqueries/types"
@@ -19,81 +24,277 @@ var perPage = 100
 
 const orderBy = "asc"
 
-func NewTXQuerySrv(chainClient relay.ChainClient) *TXQuerierSrv {
+var (
+	// initialWindowSize is the number of blocks covered by the first tx_search request of a search
+	initialWindowSize int64 = 1000
+	// maxWindowSize limits the growth of a window over the ranges with few matching transactions
+	maxWindowSize int64 = 100000
+	// maxWindowTxs is the number of transactions in a window above which the window is shrunk
+	// instead of being paged through
+	maxWindowTxs = 10 * perPage
+)
+
+// NewTXQuerySrv returns a TXQuerierSrv which keeps the block results of blockResultsCacheSize latest
+// heights (0 disables the cache) and builds up to proofWorkers proofs at the same time.
+func NewTXQuerySrv(chainClient relay.ChainClient, blockResultsCacheSize int, proofWorkers int) *TXQuerierSrv {
+	if proofWorkers < 1 {
+		proofWorkers = 1
+	}
+
 	return &TXQuerierSrv{
-		chainClient: chainClient,
+		chainClient:  chainClient,
+		blockResults: newBlockResultsCache(chainClient, blockResultsCacheSize),
+		proofWorkers: proofWorkers,
 	}
 }
 
 // TXQuerierSrv implementation of relay.TXQuerier interface
 type TXQuerierSrv struct {
-	chainClient relay.ChainClient
+	chainClient  relay.ChainClient
+	blockResults *blockResultsCache
+	proofWorkers int
 }
 
 // SearchTransactions gets txs with proofs for query type = 'tx'
 // (NOTE: there is no such query function in cosmos-sdk)
+//
+// The indexed blocks matching the tx.height conditions of the query are scanned in bounded height
+// windows (tx.height>a AND tx.height<=b) from the oldest to the newest one. The result set of such a
+// window can't change, so the pages of a window never shift, and every window is sent completely
+// before the next one is requested, i.e. the transactions of a block are never split between windows.
+// The window size adapts to the number of matching transactions. A checkpoint is sent after every window.
 func (t *TXQuerierSrv) SearchTransactions(ctx context.Context, query string) (<-chan relay.Transaction, <-chan error) {
 	errs := make(chan error, 1)
 	txs := make(chan relay.Transaction, TxsChanSize)
-	page := 1 // NOTE: page index starts from 1
 
 	go func() {
 		defer close(txs)
 		defer close(errs)
+		if err := t.searchTransactions(ctx, query, txs); err != nil {
+			errs <- err
+		}
+	}()
+
+	return txs, errs
+}
+
+func (t *TXQuerierSrv) searchTransactions(ctx context.Context, query string, txs chan<- relay.Transaction) error {
+	after, until, err := heightRange(query)
+	if err != nil {
+		return fmt.Errorf("invalid tx query %s: %w", query, err)
+	}
+
+	status, err := t.chainClient.Status(ctx)
+	if err != nil {
+		return fmt.Errorf("could not get latest height: %w", err)
+	}
+	// only the blocks indexed by now are scanned, the later ones are left for the next search. The node
+	// indexes the txs of a block asynchronously after committing it, so the latest block may be not
+	// indexed yet, and a checkpoint at its height would make its txs be skipped forever.
+	if indexedHeight := status.SyncInfo.LatestBlockHeight - 1; indexedHeight < until {
+		until = indexedHeight
+	}
+
+	windowSize := initialWindowSize
+	for after < until {
+		windowEnd := after + windowSize
+		if windowEnd > until {
+			windowEnd = until
+		}
+		windowQuery := fmt.Sprintf("%s AND tx.height>%d AND tx.height<=%d", query, after, windowEnd)
+
+		page := 1 // NOTE: page index starts from 1
+		searchResult, err := t.chainClient.TxSearch(ctx, windowQuery, true, &page, &perPage, orderBy)
+		if err != nil {
+			return fmt.Errorf("could not query new transactions to proof: %w", err)
+		}
+
+		// a too dense window makes the node collect a huge result set on every page request,
+		// so it's worth narrowing the window unless it's a single block already
+		if searchResult.TotalCount > maxWindowTxs && windowEnd-after > 1 {
+			windowSize = shrinkWindow(windowEnd-after, searchResult.TotalCount)
+			continue
+		}
+
 		for {
-			searchResult, err := t.chainClient.TxSearch(ctx, query, true, &page, &perPage, orderBy)
+			if len(searchResult.Txs) == 0 {
+				break
+			}
+			if err := t.sendTxs(ctx, searchResult.Txs, txs); err != nil {
+				return err
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+			if page*perPage >= searchResult.TotalCount {
+				break
+			}
+
+			page += 1
+			searchResult, err = t.chainClient.TxSearch(ctx, windowQuery, true, &page, &perPage, orderBy)
 			if err != nil {
-				errs <- fmt.Errorf("could not query new transactions to proof: %w", err)
-				return
+				return fmt.Errorf("could not query new transactions to proof: %w", err)
 			}
+		}
 
-			if len(searchResult.Txs) == 0 {
-				return
+		select {
+		case txs <- relay.Transaction{Height: uint64(windowEnd)}:
+		case <-ctx.Done():
+			return nil
+		}
+
+		if searchResult.TotalCount < perPage && windowSize < maxWindowSize {
+			windowSize *= 2
+			if windowSize > maxWindowSize {
+				windowSize = maxWindowSize
 			}
+		}
+		after = windowEnd
+	}
 
-			for _, tx := range searchResult.Txs {
-				deliveryProof, deliveryResult, err := t.proofDelivery(ctx, tx.Height, tx.Index)
-				if err != nil {
-					errs <- fmt.Errorf("could not proof transaction with hash=%s: %w", tx.Tx.String(), err)
-					return
-				}
+	return nil
+}
 
-				txProof := neutrontypes.TxValue{
+// sendTxs proves the found transactions and sends them to the txs channel in the same order. It stops
+// without an error once ctx is done.
+func (t *TXQuerierSrv) sendTxs(ctx context.Context, found []*coretypes.ResultTx, txs chan<- relay.Transaction) error {
+	proven, err := t.proveTxs(ctx, found)
+	if err != nil {
+		return err
+	}
+
+	for _, tx := range proven {
+		select {
+		case txs <- tx:
+		case <-ctx.Done():
+			return nil
+		}
+	}
+	return nil
+}
+
+// proveTxs builds the proofs of the found transactions concurrently. The block results of every height
+// are fetched once before any of the proofs of the height is built.
+func (t *TXQuerierSrv) proveTxs(ctx context.Context, found []*coretypes.ResultTx) ([]relay.Transaction, error) {
+	heights := make([]int64, 0)
+	for i, tx := range found {
+		if i == 0 || tx.Height != found[i-1].Height {
+			heights = append(heights, tx.Height)
+		}
+	}
+
+	results := make(map[int64]*heightResults, len(heights))
+	var resultsMutex sync.Mutex
+	g, gctx := errgroup.WithContext(ctx)
+	g.SetLimit(t.proofWorkers)
+	for _, height := range heights {
+		height := height
+		g.Go(func() error {
+			res, err := t.blockResults.get(gctx, height)
+			if err != nil {
+				return err
+			}
+			resultsMutex.Lock()
+			results[height] = res
+			resultsMutex.Unlock()
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
+
+	proven := make([]relay.Transaction, len(found))
+	g, _ = errgroup.WithContext(ctx)
+	g.SetLimit(t.proofWorkers)
+	for i, tx := range found {
+		i, tx := i, tx
+		g.Go(func() error {
+			deliveryProof, deliveryResult, err := proofDelivery(results[tx.Height], tx.Index)
+			if err != nil {
+				return fmt.Errorf("could not proof transaction with hash=%s: %w", tx.Tx.String(), err)
+			}
+
+			proven[i] = relay.Transaction{
+				Tx: &neutrontypes.TxValue{
 					InclusionProof: cryptoProofFromMerkleProof(tx.Proof.Proof),
 					DeliveryProof:  deliveryProof,
 					Response:       deliveryResult,
 					Data:           tx.Tx,
-				}
-				select {
-				case txs <- relay.Transaction{Tx: &txProof, Height: uint64(tx.Height)}:
-				case <-ctx.Done():
-					return
-				}
+				},
+				Height: uint64(tx.Height),
 			}
+			return nil
+		})
+	}
+	if err := g.Wait(); err != nil {
+		return nil, err
+	}
 
-			if page*perPage >= searchResult.TotalCount {
-				return
-			}
+	return proven, nil
+}
 
-			page += 1
+// shrinkWindow estimates the window size which is going to contain about a page of transactions.
+func shrinkWindow(windowSize int64, totalCount int) int64 {
+	size := windowSize * int64(perPage) / int64(totalCount)
+	if size >= windowSize {
+		size = windowSize / 2
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// heightRange returns the height range (after, until] implied by the tx.height conditions of the query.
+// until is math.MaxInt64 if the range is not limited from above.
+func heightRange(query string) (int64, int64, error) {
+	q, err := tmquery.New(query)
+	if err != nil {
+		return 0, 0, err
+	}
+	conditions, err := q.Conditions()
+	if err != nil {
+		return 0, 0, err
+	}
+
+	after, until := int64(0), int64(math.MaxInt64)
+	for _, condition := range conditions {
+		if condition.CompositeKey != relay.TxHeight {
+			continue
 		}
-	}()
+		operand, ok := condition.Operand.(*big.Int)
+		if !ok || !operand.IsInt64() {
+			continue
+		}
+		height := operand.Int64()
 
-	return txs, errs
-}
+		switch condition.Op {
+		case tmquery.OpGreater:
+			after = max(after, height)
+		case tmquery.OpGreaterEqual:
+			after = max(after, height-1)
+		case tmquery.OpLess:
+			until = min(until, height-1)
+		case tmquery.OpLessEqual:
+			until = min(until, height)
+		case tmquery.OpEqual:
+			after = max(after, height-1)
+			until = min(until, height)
+		}
+	}
 
-// proofDelivery returns (deliveryProof, deliveryResult, error) for transaction in block 'blockHeight' with index 'txIndexInBlock'
-func (t *TXQuerierSrv) proofDelivery(ctx context.Context, blockHeight int64, txIndexInBlock uint32) (*crypto.Proof, *abci.ResponseDeliverTx, error) {
-	results, err := t.chainClient.BlockResults(ctx, &blockHeight)
+	return after, until, nil
+}
 
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to fetch block results for height = %d: %w", blockHeight, err)
+// proofDelivery returns (deliveryProof, deliveryResult, error) for transaction with index 'txIndexInBlock' in block with 'results'
+func proofDelivery(results *heightResults, txIndexInBlock uint32) (*crypto.Proof, *abci.ResponseDeliverTx, error) {
+	if int(txIndexInBlock) >= len(results.txsResults) {
+		return nil, nil, fmt.Errorf("block has %d tx results, no result for tx #%d", len(results.txsResults), txIndexInBlock)
 	}
 
-	txsResults := results.TxsResults
-	abciResults := types.NewResults(txsResults)
-	txProof := abciResults.ProveResult(int(txIndexInBlock))
-	txResult := txsResults[txIndexInBlock]
+	txProof := results.abciResults.ProveResult(int(txIndexInBlock))
+	txResult := results.txsResults[txIndexInBlock]
 
 	return cryptoProofFromMerkleProof(txProof), txResult, nil
 }
@@ -0,0 +1,293 @@
+package txquerier_test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+
+	abci "github.com/cometbft/cometbft/abci/types"
+	tmquery "github.com/cometbft/cometbft/libs/pubsub/query"
+	ctypes "github.com/cometbft/cometbft/rpc/core/types"
+	"github.com/cometbft/cometbft/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/neutron-org/neutron-query-relayer/internal/txquerier"
+)
+
+// chainClientMock serves tx_search over an in-memory set of transactions like the CometBFT kv indexer does.
+type chainClientMock struct {
+	latestHeight int64
+	// indexedHeight is the latest block which txs are indexed, the txs of all the blocks are indexed if it's 0
+	indexedHeight int64
+	// earliestHeight is the earliest block the node hasn't pruned
+	earliestHeight int64
+	// txs is the number of matching transactions in every block
+	txs map[int64]int
+	// searches is the number of tx_search requests
+	searches int
+
+	mutex sync.Mutex
+	// blockResults is the number of block_results requests for every height
+	blockResults map[int64]int
+}
+
+func (c *chainClientMock) Status(_ context.Context) (*ctypes.ResultStatus, error) {
+	return &ctypes.ResultStatus{SyncInfo: ctypes.SyncInfo{
+		LatestBlockHeight:   c.latestHeight,
+		EarliestBlockHeight: c.earliestHeight,
+	}}, nil
+}
+
+func (c *chainClientMock) Block(_ context.Context, height *int64) (*ctypes.ResultBlock, error) {
+	if *height < c.earliestHeight {
+		return nil, fmt.Errorf("height %d is not available, lowest height is %d", *height, c.earliestHeight)
+	}
+	txs := make(types.Txs, c.txs[*height])
+	for i := range txs {
+		txs[i] = types.Tx(strconv.FormatInt(*height, 10) + "/" + strconv.Itoa(i))
+	}
+	return &ctypes.ResultBlock{Block: &types.Block{Data: types.Data{Txs: txs}}}, nil
+}
+
+func (c *chainClientMock) BlockResults(_ context.Context, height *int64) (*ctypes.ResultBlockResults, error) {
+	c.mutex.Lock()
+	if c.blockResults == nil {
+		c.blockResults = make(map[int64]int)
+	}
+	c.blockResults[*height]++
+	c.mutex.Unlock()
+
+	results := make([]*abci.ResponseDeliverTx, c.txs[*height])
+	for i := range results {
+		// only every second transaction of a block is sent to cosmos1
+		recipient := "cosmos1"
+		if i%2 == 1 {
+			recipient = "cosmos2"
+		}
+		results[i] = &abci.ResponseDeliverTx{Events: []abci.Event{{
+			Type: "transfer",
+			Attributes: []abci.EventAttribute{
+				{Key: "recipient", Value: recipient, Index: true},
+				{Key: "memo", Value: "unindexed", Index: false},
+			},
+		}}}
+	}
+	return &ctypes.ResultBlockResults{Height: *height, TxsResults: results}, nil
+}
+
+func (c *chainClientMock) TxSearch(_ context.Context, query string, _ bool, page, perPage *int, _ string) (*ctypes.ResultTxSearch, error) {
+	c.searches++
+	q, err := tmquery.New(query)
+	if err != nil {
+		return nil, err
+	}
+
+	heights := make([]int64, 0, len(c.txs))
+	for height := range c.txs {
+		heights = append(heights, height)
+	}
+	sort.Slice(heights, func(i, j int) bool { return heights[i] < heights[j] })
+
+	found := make([]*ctypes.ResultTx, 0)
+	for _, height := range heights {
+		if c.indexedHeight != 0 && height > c.indexedHeight {
+			continue
+		}
+		for i := 0; i < c.txs[height]; i++ {
+			matches, err := q.Matches(map[string][]string{
+				"tx.height":          {strconv.FormatInt(height, 10)},
+				"transfer.recipient": {"cosmos1"},
+			})
+			if err != nil {
+				return nil, err
+			}
+			if matches {
+				found = append(found, &ctypes.ResultTx{Height: height, Index: uint32(i), Tx: types.Tx(strconv.FormatInt(height, 10) + "/" + strconv.Itoa(i))})
+			}
+		}
+	}
+
+	start := (*page - 1) * *perPage
+	end := start + *perPage
+	if start > len(found) {
+		start = len(found)
+	}
+	if end > len(found) {
+		end = len(found)
+	}
+	return &ctypes.ResultTxSearch{Txs: found[start:end], TotalCount: len(found)}, nil
+}
+
+func TestSearchTransactions(t *testing.T) {
+	chainClient := &chainClientMock{
+		latestHeight: 300001,
+		txs: map[int64]int{
+			1:      1,
+			100:    3,
+			2500:   150,
+			2501:   1200, // more transactions than a window may have
+			2502:   99,
+			150000: 1,
+			300000: 2,
+			300001: 5, // the latest block, may be not indexed yet
+			300002: 4, // not committed yet
+		},
+	}
+	querier := txquerier.NewTXQuerySrv(chainClient, 100, 4)
+
+	txs, errs := querier.SearchTransactions(context.Background(), "transfer.recipient='cosmos1' AND tx.height>1")
+	received := make(map[string]struct{})
+	lastHeight, lastIndex := int64(0), -1
+	checkpoint := uint64(0)
+	for tx := range txs {
+		if tx.Tx == nil {
+			require.Greater(t, tx.Height, checkpoint)
+			checkpoint = tx.Height
+			continue
+		}
+		require.Greater(t, tx.Height, checkpoint, "tx received after a checkpoint of its height")
+
+		data := string(tx.Tx.Data)
+		_, duplicated := received[data]
+		require.False(t, duplicated, "tx %s received twice", data)
+		received[data] = struct{}{}
+
+		height := int64(tx.Height)
+		index := int(tx.Tx.DeliveryProof.Index)
+		require.True(t, height > lastHeight || (height == lastHeight && index == lastIndex+1),
+			"tx %d/%d received after %d/%d", height, index, lastHeight, lastIndex)
+		lastHeight, lastIndex = height, index
+	}
+	require.NoError(t, <-errs)
+
+	assert.Len(t, received, 3+150+1200+99+1+2)
+	assert.Equal(t, int64(300000), lastHeight)
+	assert.Equal(t, uint64(300000), checkpoint)
+	// the block results are fetched once per height even if the transactions of the height span many pages
+	assert.Equal(t, map[int64]int{100: 1, 2500: 1, 2501: 1, 2502: 1, 150000: 1, 300000: 1}, chainClient.blockResults)
+}
+
+func TestSearchTransactionsIndexerLag(t *testing.T) {
+	chainClient := &chainClientMock{
+		latestHeight:  100,
+		indexedHeight: 99,
+		txs:           map[int64]int{50: 1, 99: 1, 100: 1},
+	}
+	querier := txquerier.NewTXQuerySrv(chainClient, 100, 4)
+
+	search := func(query string) ([]string, uint64) {
+		txs, errs := querier.SearchTransactions(context.Background(), query)
+		received := make([]string, 0)
+		checkpoint := uint64(0)
+		for tx := range txs {
+			if tx.Tx == nil {
+				checkpoint = tx.Height
+				continue
+			}
+			received = append(received, string(tx.Tx.Data))
+		}
+		require.NoError(t, <-errs)
+		return received, checkpoint
+	}
+
+	// the latest block is not indexed yet, so it's not checkpointed
+	received, checkpoint := search("transfer.recipient='cosmos1' AND tx.height>0")
+	assert.Equal(t, []string{"50/0", "99/0"}, received)
+	assert.Equal(t, uint64(99), checkpoint)
+
+	// the txs of the block are found by the next search once the block is indexed
+	chainClient.latestHeight, chainClient.indexedHeight = 101, 100
+	received, checkpoint = search(fmt.Sprintf("transfer.recipient='cosmos1' AND tx.height>%d", checkpoint))
+	assert.Equal(t, []string{"100/0"}, received)
+	assert.Equal(t, uint64(100), checkpoint)
+}
+
+func TestSearchTransactionsHeightRange(t *testing.T) {
+	chainClient := &chainClientMock{
+		latestHeight: 1000,
+		txs:          map[int64]int{9: 1, 10: 2, 15: 3, 20: 4, 21: 5},
+	}
+	querier := txquerier.NewTXQuerySrv(chainClient, 100, 4)
+
+	txs, errs := querier.SearchTransactions(context.Background(), "transfer.recipient='cosmos1' AND tx.height>9 AND tx.height<=20")
+	heights := make(map[uint64]int)
+	for tx := range txs {
+		if tx.Tx != nil {
+			heights[tx.Height]++
+		}
+	}
+	require.NoError(t, <-errs)
+
+	assert.Equal(t, map[uint64]int{10: 2, 15: 3, 20: 4}, heights)
+	// the whole range fits into a single window
+	assert.Equal(t, 1, chainClient.searches)
+}
+
+func TestBlockScannerSearchTransactions(t *testing.T) {
+	chainClient := &chainClientMock{
+		latestHeight: 250,
+		txs:          map[int64]int{5: 1, 10: 2, 120: 5, 250: 3, 251: 1},
+	}
+	scanner := txquerier.NewBlockScannerSrv(chainClient, 100, "connection-0", zap.NewNop())
+
+	txs, errs := scanner.SearchTransactions(context.Background(), "transfer.recipient='cosmos1' AND tx.height>5")
+	received := make([]string, 0)
+	checkpoints := make([]uint64, 0)
+	for tx := range txs {
+		if tx.Tx == nil {
+			checkpoints = append(checkpoints, tx.Height)
+			continue
+		}
+		received = append(received, string(tx.Tx.Data))
+		assert.Equal(t, tx.Tx.InclusionProof.Index, tx.Tx.DeliveryProof.Index)
+	}
+	require.NoError(t, <-errs)
+
+	assert.Equal(t, []string{"10/0", "120/0", "120/2", "120/4", "250/0", "250/2"}, received)
+	assert.Equal(t, []uint64{100, 200, 250}, checkpoints)
+	assert.Equal(t, 0, chainClient.searches)
+}
+
+func TestBlockScannerSkipsPrunedBlocksAndReusesBlockResults(t *testing.T) {
+	chainClient := &chainClientMock{
+		latestHeight:   250,
+		earliestHeight: 100,
+		txs:            map[int64]int{5: 1, 120: 2, 250: 1},
+	}
+	core, logs := observer.New(zapcore.WarnLevel)
+	scanner := txquerier.NewBlockScannerSrv(chainClient, 100, "connection-0", zap.New(core))
+
+	search := func(query string) []string {
+		txs, errs := scanner.SearchTransactions(context.Background(), query)
+		received := make([]string, 0)
+		for tx := range txs {
+			if tx.Tx != nil {
+				received = append(received, string(tx.Tx.Data))
+			}
+		}
+		require.NoError(t, <-errs)
+		return received
+	}
+
+	// the scan starts at the earliest available block instead of failing on the pruned ones
+	assert.Equal(t, []string{"120/0", "250/0"}, search("transfer.recipient='cosmos1' AND tx.height>0"))
+	// the skipped range is reported
+	require.Equal(t, 1, logs.Len())
+	fields := logs.All()[0].ContextMap()
+	assert.Equal(t, int64(1), fields["skipped_from_height"])
+	assert.Equal(t, int64(99), fields["skipped_to_height"])
+	// the attributes which are not indexed are not matched, same as tx_search does
+	assert.Empty(t, search("transfer.memo='unindexed' AND tx.height>0"))
+
+	for height, requests := range chainClient.blockResults {
+		assert.GreaterOrEqual(t, height, int64(100))
+		assert.Equal(t, 1, requests, "block results of height %d must be cached", height)
+	}
+}
@@ -25,20 +25,23 @@ var (
 )
 
 type TxSubmitChecker struct {
-	storage   relay.Storage
-	rpcClient rpcclient.Client
-	logger    *zap.Logger
+	connectionID string
+	storage      relay.Storage
+	rpcClient    rpcclient.Client
+	logger       *zap.Logger
 }
 
 func NewTxSubmitChecker(
+	connectionID string,
 	storage relay.Storage,
 	rpcClient rpcclient.Client,
 	logger *zap.Logger,
 ) *TxSubmitChecker {
 	return &TxSubmitChecker{
-		storage:   storage,
-		rpcClient: rpcClient,
-		logger:    logger,
+		connectionID: connectionID,
+		storage:      storage,
+		rpcClient:    rpcClient,
+		logger:       logger,
 	}
 }
 
@@ -66,7 +69,12 @@ func (tc *TxSubmitChecker) Run(ctx context.Context, submittedTxsTasksQueue <-cha
 					zap.String("tx_submitted_hash", tx.SubmittedTxHash))
 			}
 		case <-ctx.Done():
-			tc.logger.Info("Context cancelled, shutting down TxSubmitChecker...")
+			// the pending txs are kept in the storage and are going to be checked on the next start
+			pending, err := tc.storage.GetAllPendingTxs()
+			if err != nil {
+				tc.logger.Error("failed to read pending txs from storage on shutdown", zap.Error(err))
+			}
+			tc.logger.Info("Context cancelled, shutting down TxSubmitChecker...", zap.Int("unchecked_pending_txs", len(pending)))
 			return nil
 		}
 	}
@@ -84,12 +92,12 @@ func (tc *TxSubmitChecker) processSubmittedTx(ctx context.Context, tx *relay.Pen
 	}
 
 	if txResponse.TxResult.Code == abci.CodeTypeOK {
-		instrumenters.IncSuccessTxSubmit()
+		instrumenters.IncSuccessTxSubmit(tc.connectionID)
 		tc.updateTxStatus(tx, relay.SubmittedTxInfo{
 			Status: relay.Committed,
 		})
 	} else {
-		instrumenters.IncFailedTxSubmit()
+		instrumenters.IncFailedTxSubmit(tc.connectionID)
 		tc.updateTxStatus(tx, relay.SubmittedTxInfo{
 			Status:  relay.ErrorOnCommit,
 			Message: fmt.Sprintf("Code: %d, Log: %s", txResponse.TxResult.Code, txResponse.TxResult.Log),
@@ -70,6 +70,20 @@ func (mr *MockRpcHttpClientMockRecorder) Status(ctx any) *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Status", reflect.TypeOf((*MockRpcHttpClient)(nil).Status), ctx)
 }
 
+// Stop mocks base method.
+func (m *MockRpcHttpClient) Stop() error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stop")
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Stop indicates an expected call of Stop.
+func (mr *MockRpcHttpClientMockRecorder) Stop() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stop", reflect.TypeOf((*MockRpcHttpClient)(nil).Stop))
+}
+
 // Subscribe mocks base method.
 func (m *MockRpcHttpClient) Subscribe(ctx context.Context, subscriber, query string, outCapacity ...int) (<-chan coretypes.ResultEvent, error) {
 	m.ctrl.T.Helper()